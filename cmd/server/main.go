@@ -4,11 +4,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
-	config "github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/app"
 	"github.com/BBaCode/pocketwise-server/internal/app/handlers"
 	"github.com/BBaCode/pocketwise-server/internal/app/middleware"
 	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/simplefin"
+	"github.com/BBaCode/pocketwise-server/internal/storage"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 )
@@ -24,7 +27,7 @@ func main() {
 		}
 	}
 
-	cfg := config.LoadConfig()
+	cfg := app.LoadConfig()
 
 	// Connect to the database
 	pool, err := db.Connect(db.DBConfig(cfg))
@@ -33,6 +36,15 @@ func main() {
 	}
 	defer pool.Close() // Ensure the connection is closed when you're done
 
+	// Where transaction attachments are stored
+	store, err := storage.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to set up attachment storage: %v\n", err)
+	}
+
+	// Reads bank connections from SimpleFIN
+	sf := simplefin.NewClientFromEnv()
+
 	r := mux.NewRouter()
 
 	// Public Routes
@@ -44,6 +56,11 @@ func main() {
 		handlers.HandleUserLogin(w, r, pool)
 	}).Methods("POST", "OPTIONS")
 
+	// calendar apps can't send a JWT, the secret token in the URL identifies the user instead
+	r.HandleFunc("/bills/calendar/{token:[0-9a-f]+}.ics", func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetCalendarFeed(w, r, pool)
+	}).Methods("GET")
+
 	// Protected routes (With JWT validation)
 	// Gets existing accounts from the database (no transactions)
 	r.Handle("/accounts", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -53,14 +70,19 @@ func main() {
 	// this works for any NEW account but not for updating the same accounts.
 	// currently this is done from an api, we dont have UI for this
 	r.Handle("/new-accounts", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handlers.HandleAddAccounts(w, r, pool)
+		handlers.HandleAddAccounts(w, r, sf, pool)
 	}))).Methods("GET")
 
 	// Updates all accounts AND transactions: this would be the function to run every morning/ twice a day
 	r.Handle("/account-data", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handlers.HandleGetUpdatedAccountData(w, r, pool)
+		handlers.HandleGetUpdatedAccountData(w, r, sf, pool)
 	}))).Methods("GET", "OPTIONS")
 
+	// the user's own SimpleFIN bridge, claimed from a setup token. DELETE revokes the server's access
+	r.Handle("/simplefin/connection", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSimpleFINConnection(w, r, sf, pool)
+	}))).Methods("POST", "DELETE", "OPTIONS")
+
 	r.Handle("/all-transactions", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handlers.HandleGetAllTransactions(w, r, pool)
 	}))).Methods("GET", "POST", "OPTIONS")
@@ -68,12 +90,16 @@ func main() {
 	// this currently lets us load more data from simplefin into the transactions table by passing an account
 	// not used at all as we moved away from account level updates
 	r.Handle("/transactions", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handlers.HandleGetTransactions(w, r, pool)
+		handlers.HandleGetTransactions(w, r, sf, pool)
 	}))).Methods("POST", "OPTIONS")
 
+	r.Handle("/transactions", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSearchTransactions(w, r, pool)
+	}))).Methods("GET")
+
 	r.Handle("/update-transactions", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handlers.HandleUpdateTransactions(w, r, pool)
-	}))).Methods("PUT", "OPTIONS")
+	}))).Methods("PUT", "PATCH", "OPTIONS")
 
 	r.Handle("/budget", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handlers.HandleGetBudget(w, r, pool)
@@ -95,6 +121,301 @@ func main() {
 		handlers.HandleUpdateBudget(w, r, pool)
 	}))).Methods("PUT", "OPTIONS")
 
+	// resolves which budgets apply on ?date=YYYY-MM-DD (monthly, weekly, biweekly, quarterly, yearly or custom)
+	r.Handle("/budgets/active", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetActiveBudgets(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/budgets/{budgetId}/progress", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetBudgetProgress(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/budgets/{year}/{month}/copy-from", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleCopyBudget(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/budgets/{year}/{month}/suggest", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSuggestBudget(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/budgets/{year}/{month}/from-template/{templateId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleApplyBudgetTemplate(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/budget-templates", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetBudgetTemplates(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/budget-templates", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleAddBudgetTemplate(w, r, pool)
+	}))).Methods("POST")
+
+	r.Handle("/budget-templates/{templateId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteBudgetTemplate(w, r, pool)
+	}))).Methods("DELETE", "OPTIONS")
+
+	// opt-in for creating each month's budget automatically at the start of the month
+	r.Handle("/budget-settings/auto-create", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleBudgetAutoCreateSettings(w, r, pool)
+	}))).Methods("GET", "PUT", "OPTIONS")
+
+	// e.g. {"thresholds": [{"field": "groceries", "percent": 80}, {"field": "total", "percent": 100}]}
+	r.Handle("/budgets/{budgetId}/alert-thresholds", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleBudgetAlertThresholds(w, r, pool)
+	}))).Methods("GET", "PUT", "OPTIONS")
+
+	r.Handle("/notifications", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetNotifications(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/notifications/{notificationId}/read", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleMarkNotificationRead(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/notification-preferences", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleNotificationPreferences(w, r, pool)
+	}))).Methods("GET", "PUT", "OPTIONS")
+
+	r.Handle("/goals", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetGoals(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/goals", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleAddGoal(w, r, pool)
+	}))).Methods("POST")
+
+	r.Handle("/goals/{goalId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetGoal(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/goals/{goalId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleUpdateGoal(w, r, pool)
+	}))).Methods("PUT")
+
+	r.Handle("/goals/{goalId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteGoal(w, r, pool)
+	}))).Methods("DELETE")
+
+	r.Handle("/recurring", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetRecurring(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/recurring/{seriesId}/{action:confirm|dismiss}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleReviewRecurring(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/bills/upcoming", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetUpcomingBills(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/bills/manual", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetManualBills(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/bills/manual", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSaveManualBill(w, r, pool)
+	}))).Methods("POST")
+
+	r.Handle("/bills/manual/{billId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSaveManualBill(w, r, pool)
+	}))).Methods("PUT", "OPTIONS")
+
+	r.Handle("/bills/manual/{billId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteManualBill(w, r, pool)
+	}))).Methods("DELETE")
+
+	r.Handle("/bills/calendar-feed", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleCreateCalendarFeed(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/forecast", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetForecast(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/transfers", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetTransfers(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/transfers", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleLinkTransfer(w, r, pool)
+	}))).Methods("POST")
+
+	r.Handle("/transfers/{groupId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleUnlinkTransfer(w, r, pool)
+	}))).Methods("DELETE", "OPTIONS")
+
+	r.Handle("/transactions/{transactionId}/splits", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleTransactionSplits(w, r, pool)
+	}))).Methods("GET", "PUT", "DELETE", "OPTIONS")
+
+	r.Handle("/tags", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetTags(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/transactions/{transactionId}/attachments", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleTransactionAttachments(w, r, store, pool)
+	}))).Methods("GET", "POST", "OPTIONS")
+
+	r.Handle("/attachments/{attachmentId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetAttachment(w, r, store, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/attachments/{attachmentId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteAttachment(w, r, store, pool)
+	}))).Methods("DELETE")
+
+	r.Handle("/accounts/manual", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSaveManualAccount(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/accounts/manual/{accountId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSaveManualAccount(w, r, pool)
+	}))).Methods("PUT", "OPTIONS")
+
+	r.Handle("/accounts/manual/{accountId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteManualAccount(w, r, pool)
+	}))).Methods("DELETE")
+
+	r.Handle("/accounts/manual/{accountId}/transactions", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSaveManualTransaction(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/accounts/manual/{accountId}/transactions/{transactionId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleSaveManualTransaction(w, r, pool)
+	}))).Methods("PUT", "OPTIONS")
+
+	r.Handle("/accounts/manual/{accountId}/transactions/{transactionId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteManualTransaction(w, r, pool)
+	}))).Methods("DELETE")
+
+	r.Handle("/net-worth", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetNetWorth(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/merchants", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetMerchants(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/merchants/{merchantId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleUpdateMerchant(w, r, pool)
+	}))).Methods("PUT", "OPTIONS")
+
+	r.Handle("/merchants/{merchantId}/aliases", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleMerchantAlias(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/merchants/{merchantId}/aliases/{alias}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleMerchantAlias(w, r, pool)
+	}))).Methods("DELETE", "OPTIONS")
+
+	r.Handle("/reports/spending", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetSpendingReport(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/reports/cash-flow", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetCashFlow(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/alerts", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetAlerts(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/alerts/{alertId}/dismiss", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDismissAlert(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/duplicates", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetDuplicates(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/duplicates/merge", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleMergeDuplicates(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/duplicates/dismiss", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDismissDuplicate(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/ask", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleAsk(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/digests", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetDigests(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/digests/{month}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetDigest(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/import", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleImport(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/import/profiles", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleImportProfiles(w, r, pool)
+	}))).Methods("GET", "POST", "OPTIONS")
+
+	r.Handle("/import/profiles/{profileId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteImportProfile(w, r, pool)
+	}))).Methods("DELETE", "OPTIONS")
+
+	r.Handle("/import/{importId}/commit", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleCommitImport(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/import/{importId}/undo", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleUndoImport(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/imports", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetImports(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/migrations/{source}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleMigrate(w, r, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/migrations/{source}/categories", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleCategoryMappings(w, r, pool)
+	}))).Methods("GET", "PUT", "OPTIONS")
+
+	r.Handle("/export", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleExport(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/me", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDeleteAccount(w, r, pool)
+	}))).Methods("DELETE", "OPTIONS")
+
+	r.Handle("/me/deletion", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleAccountDeletion(w, r, pool)
+	}))).Methods("GET", "DELETE", "OPTIONS")
+
+	r.Handle("/me/export", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleRequestDataExport(w, r, store, pool)
+	}))).Methods("POST", "OPTIONS")
+
+	r.Handle("/me/exports", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetDataExports(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/me/export/{exportId}", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleGetDataExport(w, r, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	r.Handle("/me/export/{exportId}/download", middleware.ValidateJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleDownloadDataExport(w, r, store, pool)
+	}))).Methods("GET", "OPTIONS")
+
+	// Background jobs
+	go app.RunBudgetAutoCreate(time.Hour, pool)
+	go app.RunAttachmentCleanup(time.Hour, store, pool)
+	go app.RunMonthlyDigests(time.Hour, pool)
+	go app.RunDeferredNotifications(5*time.Minute, pool)
+	go app.RunDataExports(time.Minute, store, pool)
+	go app.RunAccountDeletions(time.Hour, store, pool)
+
 	log.Println("Server starting on :80")
 
 	port := os.Getenv("PORT")
@@ -0,0 +1,331 @@
+package app
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/storage"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// How long a finished data export can be downloaded for
+	DataExportRetention = 7 * 24 * time.Hour
+	// Time between asking for the account to be deleted and the deletion, during which it can be
+	// cancelled
+	AccountDeletionGracePeriod = 30 * 24 * time.Hour
+	// Builds running longer than this are assumed to have died with their server and are restarted
+	dataExportStaleAfter = time.Hour
+)
+
+// Where the contents of a data export come from. Postgres and the blob store in the server, memory
+// in tests
+type DataExportSource interface {
+	Tables() []string
+	// Hands each of the user's rows in the table to fn as a JSON object
+	StreamTable(ctx context.Context, userId string, table string, fn func(row []byte) error) error
+	Attachments(userId string) ([]models.Attachment, error)
+	OpenBlob(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+type PostgresDataExportSource struct {
+	Pool  *pgxpool.Pool
+	Store storage.BlobStore
+}
+
+func (s PostgresDataExportSource) Tables() []string {
+	return db.UserTables()
+}
+
+func (s PostgresDataExportSource) StreamTable(ctx context.Context, userId string, table string, fn func(row []byte) error) error {
+	return db.StreamUserTable(ctx, userId, table, fn, s.Pool)
+}
+
+func (s PostgresDataExportSource) Attachments(userId string) ([]models.Attachment, error) {
+	return db.FetchUserAttachments(userId, s.Pool)
+}
+
+func (s PostgresDataExportSource) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.Store.Get(ctx, key)
+}
+
+// Name of an attachment's file inside the zip. The attachment ID keeps files with the same name apart
+func dataExportAttachmentPath(attachment models.Attachment) string {
+	name := path.Base(strings.ReplaceAll(attachment.Filename, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		name = "file"
+	}
+	return "attachments/" + attachment.ID + "-" + name
+}
+
+// Writes a zip of everything stored for the user: one JSON array per table, the attachment files and
+// a manifest.json describing the rest. Attachments whose file is missing from the blob store are
+// left out of the zip
+func WriteDataExport(ctx context.Context, w io.Writer, userId string, now time.Time, src DataExportSource) (models.DataExportManifest, error) {
+	manifest := models.DataExportManifest{UserId: userId, GeneratedAt: now.UTC().Format(time.RFC3339), Tables: map[string]int{}}
+	zw := zip.NewWriter(w)
+
+	for _, table := range src.Tables() {
+		file, err := zw.Create(table + ".json")
+		if err != nil {
+			return manifest, err
+		}
+		if _, err := io.WriteString(file, "["); err != nil {
+			return manifest, err
+		}
+		count := 0
+		err = src.StreamTable(ctx, userId, table, func(row []byte) error {
+			separator := "\n"
+			if count > 0 {
+				separator = ",\n"
+			}
+			count++
+			if _, err := io.WriteString(file, separator); err != nil {
+				return err
+			}
+			_, err := file.Write(row)
+			return err
+		})
+		if err != nil {
+			return manifest, err
+		}
+		if _, err := io.WriteString(file, "\n]\n"); err != nil {
+			return manifest, err
+		}
+		manifest.Tables[table] = count
+	}
+
+	attachments, err := src.Attachments(userId)
+	if err != nil {
+		return manifest, err
+	}
+	for _, attachment := range attachments {
+		blob, err := src.OpenBlob(ctx, attachment.StorageKey)
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Attachment %s is missing from the blob store, leaving it out of the export\n", attachment.ID)
+			continue
+		} else if err != nil {
+			return manifest, fmt.Errorf("failed to read attachment %s: %w", attachment.ID, err)
+		}
+		file, err := zw.Create(dataExportAttachmentPath(attachment))
+		if err == nil {
+			_, err = io.Copy(file, blob)
+		}
+		blob.Close()
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Attachments++
+	}
+
+	file, err := zw.Create("manifest.json")
+	if err != nil {
+		return manifest, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return manifest, err
+	}
+	return manifest, zw.Close()
+}
+
+func dataExportKey(export models.DataExport) string {
+	return fmt.Sprintf("exports/%s/%s.zip", export.UserId, export.ID)
+}
+
+// Queues a data export and starts building it. Asking again while one is queued or being built
+// returns that one
+func RequestDataExport(userId string, store storage.BlobStore, pool *pgxpool.Pool) (models.DataExport, error) {
+	export, err := db.InsertDataExport(userId, pool)
+	if err != nil {
+		return export, err
+	}
+	if export.Status == models.DataExportStatusPending {
+		go BuildDataExport(export, store, pool)
+	}
+	return export, nil
+}
+
+// Builds the zip of a queued export and stores it for download. Does nothing when another worker
+// has already taken the export on
+func BuildDataExport(export models.DataExport, store storage.BlobStore, pool *pgxpool.Pool) {
+	claimed, err := db.ClaimDataExport(export.ID, dataExportStaleAfter, pool)
+	if err != nil {
+		log.Printf("Failed to claim data export %s: %v\n", export.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	// the zip is built on disk, exports with many attachments don't fit in memory
+	file, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		log.Printf("Failed to create data export %s: %v\n", export.ID, err)
+		if err := db.FailDataExport(export.ID, "Failed to build the export", pool); err != nil {
+			log.Printf("Failed to mark data export %s as failed: %v\n", export.ID, err)
+		}
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := WriteDataExport(ctx, file, export.UserId, now, PostgresDataExportSource{Pool: pool, Store: store}); err != nil {
+		log.Printf("Failed to build data export %s: %v\n", export.ID, err)
+		if err := db.FailDataExport(export.ID, "Failed to build the export", pool); err != nil {
+			log.Printf("Failed to mark data export %s as failed: %v\n", export.ID, err)
+		}
+		return
+	}
+
+	key := dataExportKey(export)
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		err = store.PutStream(ctx, key, file, size, "application/zip")
+	}
+	if err != nil {
+		log.Printf("Failed to store data export %s: %v\n", export.ID, err)
+		if err := db.FailDataExport(export.ID, "Failed to store the export", pool); err != nil {
+			log.Printf("Failed to mark data export %s as failed: %v\n", export.ID, err)
+		}
+		return
+	}
+	err = db.CompleteDataExport(export.ID, key, size, now.Add(DataExportRetention), pool)
+	if errors.Is(err, db.ErrDataExportNotFound) {
+		// the account was deleted while the export was being built
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete data export %s: %v\n", export.ID, err)
+		}
+	} else if err != nil {
+		log.Printf("Failed to complete data export %s: %v\n", export.ID, err)
+	}
+}
+
+// Builds exports left queued, e.g. by a restart, and removes the ones past their retention
+func ProcessDataExports(store storage.BlobStore, pool *pgxpool.Pool) {
+	queued, err := db.FetchQueuedDataExports(dataExportStaleAfter, 10, pool)
+	if err != nil {
+		log.Printf("Failed to fetch queued data exports: %v\n", err)
+	}
+	for _, export := range queued {
+		BuildDataExport(export, store, pool)
+	}
+
+	expired, err := db.FetchExpiredDataExports(time.Now(), 100, pool)
+	if err != nil {
+		log.Printf("Failed to fetch expired data exports: %v\n", err)
+		return
+	}
+	for _, export := range expired {
+		if err := store.Delete(context.Background(), export.StorageKey); err != nil {
+			log.Printf("Failed to delete data export %s: %v\n", export.ID, err)
+			continue
+		}
+		if err := db.DeleteDataExport(export.ID, pool); err != nil {
+			log.Printf("Failed to delete data export %s: %v\n", export.ID, err)
+		}
+	}
+}
+
+func RunDataExports(interval time.Duration, store storage.BlobStore, pool *pgxpool.Pool) {
+	ProcessDataExports(store, pool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ProcessDataExports(store, pool)
+	}
+}
+
+// Schedules the account to be deleted once the grace period is over
+func RequestAccountDeletion(userId string, now time.Time, pool *pgxpool.Pool) (models.AccountDeletion, error) {
+	return db.ScheduleAccountDeletion(userId, now.Add(AccountDeletionGracePeriod), pool)
+}
+
+// Blob store keys of the user's attachment files and data export zips
+func userBlobKeys(attachments []models.Attachment, exports []models.DataExport) []string {
+	var keys []string
+	for _, attachment := range attachments {
+		keys = append(keys, attachment.StorageKey)
+		if attachment.ThumbnailKey != "" {
+			keys = append(keys, attachment.ThumbnailKey)
+		}
+	}
+	for _, export := range exports {
+		if export.StorageKey != "" {
+			keys = append(keys, export.StorageKey)
+		}
+	}
+	return keys
+}
+
+// Hard deletes the account of a user whose grace period is over, see db.DeleteUserData. Files go first, so a failure
+// part way leaves the rows that point at them for the next run to retry. The user's SimpleFIN
+// connection goes with their data, which revokes the server's access to it
+func DeleteAccount(ctx context.Context, userId string, now time.Time, store storage.BlobStore, pool *pgxpool.Pool) error {
+	// the deletion may have been cancelled since it was picked up
+	if _, err := db.FetchAccountDeletion(userId, pool); err != nil {
+		return err
+	}
+
+	attachments, err := db.FetchUserAttachments(userId, pool)
+	if err != nil {
+		return err
+	}
+	exports, err := db.FetchDataExports(userId, pool)
+	if err != nil {
+		return err
+	}
+	for _, key := range userBlobKeys(attachments, exports) {
+		if err := store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+
+	deleted, err := db.DeleteUserData(userId, now, pool)
+	if err != nil {
+		return err
+	}
+	log.Printf("Deleted account %s (%d transactions)\n", userId, deleted["transactions"])
+	return nil
+}
+
+// Carries out the deletions whose grace period is over
+func ProcessAccountDeletions(store storage.BlobStore, pool *pgxpool.Pool) {
+	now := time.Now()
+	userIds, err := db.FetchDueAccountDeletions(now, pool)
+	if err != nil {
+		log.Printf("Failed to fetch account deletions: %v\n", err)
+		return
+	}
+	for _, userId := range userIds {
+		if err := DeleteAccount(context.Background(), userId, now, store, pool); err != nil && !errors.Is(err, db.ErrDeletionNotFound) {
+			log.Printf("Failed to delete account %s: %v\n", userId, err)
+		}
+	}
+}
+
+func RunAccountDeletions(interval time.Duration, store storage.BlobStore, pool *pgxpool.Pool) {
+	ProcessAccountDeletions(store, pool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ProcessAccountDeletions(store, pool)
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/storage"
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+type memoryDataExportSource struct {
+	tables      map[string][]string
+	order       []string
+	attachments []models.Attachment
+	blobs       map[string]string
+}
+
+func (s memoryDataExportSource) Tables() []string {
+	return s.order
+}
+
+func (s memoryDataExportSource) StreamTable(ctx context.Context, userId string, table string, fn func(row []byte) error) error {
+	for _, row := range s.tables[table] {
+		if err := fn([]byte(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s memoryDataExportSource) Attachments(userId string) ([]models.Attachment, error) {
+	return s.attachments, nil
+}
+
+func (s memoryDataExportSource) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	blob, ok := s.blobs[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewBufferString(blob)), nil
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Expected a valid zip, got %v", err)
+	}
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(f)
+		f.Close()
+		files[file.Name] = content
+	}
+	return files
+}
+
+func TestWriteDataExport(t *testing.T) {
+	src := memoryDataExportSource{
+		order: []string{"users", "transactions", "budgets"},
+		tables: map[string][]string{
+			"users":        {`{"id":"u1","email":"sam@example.com"}`},
+			"transactions": {`{"id":"TRN-1","amount":-4.5}`, `{"id":"TRN-2","amount":100}`},
+		},
+		attachments: []models.Attachment{
+			{ID: "a1", Filename: "receipt.jpg", StorageKey: "u1/a1/original"},
+			{ID: "a2", Filename: "gone.pdf", StorageKey: "u1/a2/original"},
+		},
+		blobs: map[string]string{"u1/a1/original": "jpeg bytes"},
+	}
+
+	var buf bytes.Buffer
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	manifest, err := WriteDataExport(context.Background(), &buf, "u1", now, src)
+	if err != nil {
+		t.Fatalf("Expected the export to be written, got %v", err)
+	}
+	files := readZip(t, buf.Bytes())
+
+	var transactions []map[string]any
+	if err := json.Unmarshal(files["transactions.json"], &transactions); err != nil || len(transactions) != 2 {
+		t.Errorf("Expected transactions.json to hold both rows, got %s (%v)", files["transactions.json"], err)
+	}
+	var budgets []map[string]any
+	if err := json.Unmarshal(files["budgets.json"], &budgets); err != nil || len(budgets) != 0 {
+		t.Errorf("Expected an empty array for a table without rows, got %s (%v)", files["budgets.json"], err)
+	}
+	if string(files["attachments/a1-receipt.jpg"]) != "jpeg bytes" {
+		t.Errorf("Expected the attachment file in the zip, got %v", files)
+	}
+	if _, ok := files["attachments/a2-gone.pdf"]; ok {
+		t.Errorf("Expected the attachment missing from the blob store to be left out")
+	}
+
+	var written models.DataExportManifest
+	if err := json.Unmarshal(files["manifest.json"], &written); err != nil {
+		t.Fatalf("Expected a manifest, got %v", err)
+	}
+	if written.UserId != "u1" || written.GeneratedAt != "2026-10-19T12:00:00Z" || written.Attachments != 1 {
+		t.Errorf("Unexpected manifest %+v", written)
+	}
+	if fmt.Sprint(written.Tables) != fmt.Sprint(manifest.Tables) || written.Tables["transactions"] != 2 || written.Tables["budgets"] != 0 {
+		t.Errorf("Unexpected table counts %v", written.Tables)
+	}
+}
+
+func TestDataExportAttachmentPath(t *testing.T) {
+	cases := map[string]string{
+		"receipt.jpg":        "attachments/a1-receipt.jpg",
+		"../../etc/passwd":   "attachments/a1-passwd",
+		`C:\Users\sam\a.pdf`: "attachments/a1-a.pdf",
+		"..":                 "attachments/a1-file",
+	}
+	for filename, expected := range cases {
+		if got := dataExportAttachmentPath(models.Attachment{ID: "a1", Filename: filename}); got != expected {
+			t.Errorf("Expected %q to be stored as %q, got %q", filename, expected, got)
+		}
+	}
+}
+
+func TestUserBlobKeys(t *testing.T) {
+	attachments := []models.Attachment{
+		{StorageKey: "u1/a1/original", ThumbnailKey: "u1/a1/thumbnail"},
+		{StorageKey: "u1/a2/original"},
+	}
+	exports := []models.DataExport{{StorageKey: "exports/u1/e1.zip"}, {Status: models.DataExportStatusPending}}
+	keys := userBlobKeys(attachments, exports)
+	if fmt.Sprint(keys) != "[u1/a1/original u1/a1/thumbnail u1/a2/original exports/u1/e1.zip]" {
+		t.Errorf("Unexpected blob keys %v", keys)
+	}
+}
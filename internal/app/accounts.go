@@ -0,0 +1,151 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Account types a manual account can have, and whether they are owed rather than owned
+var manualAccountTypes = map[string]bool{
+	"cash":       false,
+	"checking":   false,
+	"savings":    false,
+	"investment": false,
+	"property":   false,
+	"vehicle":    false,
+	"credit":     true,
+	"loan":       true,
+	"mortgage":   true,
+	"other":      false,
+}
+
+func IsLiability(accountType string) bool {
+	return manualAccountTypes[strings.ToLower(accountType)]
+}
+
+// Validates a manual account request and turns it into the stored account. Liabilities are
+// stored with a negative balance, the same way SimpleFIN reports credit cards
+func ManualAccountFromRequest(userId uuid.UUID, accountId string, req models.ManualAccountRequest, now time.Time) (models.StoredAccount, error) {
+	accountType := strings.ToLower(req.AccountType)
+	if strings.TrimSpace(req.Name) == "" {
+		return models.StoredAccount{}, errors.New("name is required")
+	}
+	if _, ok := manualAccountTypes[accountType]; !ok {
+		return models.StoredAccount{}, fmt.Errorf("unknown account type %q", req.AccountType)
+	}
+	balance, err := strconv.ParseFloat(req.Balance, 64)
+	if err != nil {
+		return models.StoredAccount{}, errors.New("balance must be a number")
+	}
+	if IsLiability(accountType) {
+		balance = -math.Abs(balance)
+	}
+	if req.Currency == "" {
+		req.Currency = "USD"
+	}
+	if accountId == "" {
+		accountId = "MAN-" + uuid.NewString()
+	}
+
+	return models.StoredAccount{
+		ID:               accountId,
+		UserId:           userId,
+		Name:             strings.TrimSpace(req.Name),
+		AccountType:      accountType,
+		Currency:         req.Currency,
+		Balance:          fmt.Sprintf("%.2f", balance),
+		AvailableBalance: fmt.Sprintf("%.2f", balance),
+		Org:              models.Org{Name: req.OrgName},
+		BalanceDate:      now.Unix(),
+		Manual:           true,
+	}, nil
+}
+
+// Uses the category set on the transaction's merchant, then the category of an earlier transaction
+// from the same merchant or payee, and asks the categorizer otherwise. Synced and manually entered
+// transactions both go through this
+func CategorizeWithPayeeHistory(userId string, txn models.Transaction, pool *pgxpool.Pool) (models.Transaction, error) {
+	merchantId, category, err := db.FetchMerchantIdByKey(userId, MerchantKey(transactionMerchantName(txn)), pool)
+	if err != nil {
+		return txn, err
+	}
+	if merchantId != "" && category == "" {
+		if category, err = db.FetchCategoryByMerchant(merchantId, pool); err != nil {
+			return txn, err
+		}
+	}
+	if category != "" {
+		txn.Category = category
+		return txn, nil
+	}
+	// no earlier transaction from the payee comes back as an error, so only the category matters here
+	if category, _ := db.FetchCategoryByPayee(txn, pool); len(category) > 0 {
+		txn.Category = category
+		return txn, nil
+	}
+	categorized, err := CategorizeTransaction(&txn)
+	if err != nil {
+		return txn, err
+	}
+	categorized.AccountID = txn.AccountID
+	return categorized, nil
+}
+
+// Validates a manually entered transaction and fills in its defaults
+func NormalizeManualTransaction(txn *models.Transaction, now time.Time) error {
+	if _, err := strconv.ParseFloat(txn.Amount, 64); err != nil {
+		return errors.New("amount must be a number")
+	}
+	if strings.TrimSpace(txn.Payee) == "" && strings.TrimSpace(txn.Description) == "" {
+		return errors.New("a payee or description is required")
+	}
+	if txn.Payee == "" {
+		txn.Payee = txn.Description
+	}
+	if txn.Description == "" {
+		txn.Description = txn.Payee
+	}
+	if txn.TransactedAt == 0 {
+		txn.TransactedAt = now.Unix()
+	}
+	txn.Posted = txn.TransactedAt
+	return nil
+}
+
+// Sums the balances of all accounts, synced and manual. Negative balances count as liabilities
+func CalculateNetWorth(accounts []models.StoredAccount) models.NetWorth {
+	netWorth := models.NetWorth{Accounts: []models.NetWorthAccount{}}
+	for _, account := range accounts {
+		balance, err := strconv.ParseFloat(account.Balance, 64)
+		if err != nil {
+			continue
+		}
+		if balance < 0 {
+			netWorth.Liabilities += -balance
+		} else {
+			netWorth.Assets += balance
+		}
+		netWorth.Accounts = append(netWorth.Accounts, models.NetWorthAccount{
+			ID:          account.ID,
+			Name:        account.Name,
+			AccountType: account.AccountType,
+			Manual:      account.Manual,
+			Balance:     balance,
+		})
+	}
+	sort.Slice(netWorth.Accounts, func(i, j int) bool { return netWorth.Accounts[i].Balance > netWorth.Accounts[j].Balance })
+	netWorth.Assets = roundCents(netWorth.Assets)
+	netWorth.Liabilities = roundCents(netWorth.Liabilities)
+	netWorth.NetWorth = roundCents(netWorth.Assets - netWorth.Liabilities)
+	return netWorth
+}
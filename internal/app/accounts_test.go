@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+)
+
+func TestManualAccountFromRequest(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	loan, err := ManualAccountFromRequest(uuid.New(), "", models.ManualAccountRequest{Name: "Car loan", AccountType: "Loan", Balance: "12500"}, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loan.Balance != "-12500.00" || loan.AccountType != "loan" || !loan.Manual || loan.Currency != "USD" || loan.ID == "" {
+		t.Errorf("Unexpected loan account: %+v", loan)
+	}
+
+	if _, err := ManualAccountFromRequest(uuid.New(), "", models.ManualAccountRequest{Name: "Boat", AccountType: "yacht", Balance: "1"}, now); err == nil {
+		t.Error("Expected an unknown account type to be rejected")
+	}
+}
+
+func TestCalculateNetWorth(t *testing.T) {
+	netWorth := CalculateNetWorth([]models.StoredAccount{
+		{ID: "checking", Balance: "2500.00"},
+		{ID: "card", Balance: "-430.25"},
+		{ID: "house", Balance: "350000.00", Manual: true},
+		{ID: "mortgage", Balance: "-280000.00", Manual: true},
+	})
+	if netWorth.Assets != 352500 || netWorth.Liabilities != 280430.25 || netWorth.NetWorth != 72069.75 {
+		t.Errorf("Unexpected net worth: %+v", netWorth)
+	}
+	if netWorth.Accounts[0].ID != "house" {
+		t.Errorf("Expected accounts sorted by balance, got %+v", netWorth.Accounts)
+	}
+}
@@ -0,0 +1,291 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/notify"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// Transactions older than this were already checked by an earlier sync
+	anomalyCheckDays = 7
+	// How far back a merchant's usual amounts are taken from
+	anomalyHistoryDays = 180
+	// A merchant needs this many earlier charges before an amount can be unusual for it
+	anomalyMinMerchantHistory = 5
+	// Standard deviations above the merchant's mean that count as unusual
+	anomalyAmountDeviations = 3
+	// Ignore unusual amounts that are only this much above the mean, e.g. $3 at a usual $1
+	anomalyMinAmountIncrease = 20
+	// Identical charges this close together look like the merchant charged twice
+	anomalyDuplicateWindow = 10 * time.Minute
+	// First charges from a merchant at or above this are flagged
+	anomalyNewMerchantThreshold = 200
+	// Month to date spending in a category above this multiple of its trailing average is a spike
+	anomalySpikeRatio = 1.5
+	// and it has to be at least this much above the average
+	anomalySpikeMinIncrease = 100
+	// Full months before the current one the category average is taken over
+	anomalySpikeTrailingMonths = 3
+)
+
+// Fees and amounts in other currencies that banks add to the description of foreign charges
+var foreignCharge = regexp.MustCompile(`(?i)\b(foreign|international|intl)\s+(transaction|txn|purchase|fee)|\bFX\s+(fee|rate)|\b(EUR|GBP|CAD|MXN|JPY|AUD|CHF|INR|CNY|SEK|NOK|DKK)\s?\d`)
+
+type AnomalyInput struct {
+	UserId       string
+	Accounts     []models.StoredAccount
+	Transactions []models.Transaction
+	Now          time.Time
+}
+
+func transactionMerchantKey(txn models.Transaction) string {
+	if txn.MerchantId != "" {
+		return txn.MerchantId
+	}
+	return MerchantKey(transactionMerchantName(txn))
+}
+
+// The spending amount of a transaction (positive) and whether it is spending at all. Transfers
+// aren't spending
+func spendingAmount(txn models.Transaction) (float64, bool) {
+	amount, err := strconv.ParseFloat(txn.Amount, 64)
+	if err != nil || amount >= 0 || txn.TransferGroupId != "" {
+		return 0, false
+	}
+	return -amount, true
+}
+
+// The currency most of the user's accounts are in
+func primaryCurrency(accounts []models.StoredAccount) string {
+	counts := map[string]int{}
+	primary := "USD"
+	for _, account := range accounts {
+		if account.Currency == "" {
+			continue
+		}
+		counts[account.Currency]++
+		if counts[account.Currency] > counts[primary] || (counts[account.Currency] == counts[primary] && account.Currency < primary) {
+			primary = account.Currency
+		}
+	}
+	return primary
+}
+
+// Flags the recent transactions that look unusual, and categories whose spending this month spikes
+// above their trailing average. Each anomaly's Key makes it safe to detect the same thing again on
+// the next sync
+func DetectAnomalies(in AnomalyInput) []models.Anomaly {
+	txns := append([]models.Transaction(nil), in.Transactions...)
+	sort.SliceStable(txns, func(i, j int) bool {
+		if txns[i].TransactedAt != txns[j].TransactedAt {
+			return txns[i].TransactedAt < txns[j].TransactedAt
+		}
+		return txns[i].ID < txns[j].ID
+	})
+
+	accountCurrency := map[string]string{}
+	for _, account := range in.Accounts {
+		accountCurrency[account.ID] = account.Currency
+	}
+	primary := primaryCurrency(in.Accounts)
+
+	checkFrom := in.Now.AddDate(0, 0, -anomalyCheckDays).Unix()
+	historyFrom := in.Now.AddDate(0, 0, -anomalyHistoryDays).Unix()
+	anomalies := []models.Anomaly{}
+	flag := func(kind string, txn models.Transaction, amount float64, reason string) {
+		anomalies = append(anomalies, models.Anomaly{
+			UserId:        in.UserId,
+			Kind:          kind,
+			Key:           txn.ID,
+			TransactionId: txn.ID,
+			Amount:        roundCents(amount),
+			Reason:        reason,
+		})
+	}
+
+	// merchant key -> spending amounts seen so far, in the history window
+	history := map[string][]float64{}
+	seen := map[string]bool{}
+	for i, txn := range txns {
+		key := transactionMerchantKey(txn)
+		spend, isSpending := spendingAmount(txn)
+		name := transactionMerchantName(txn)
+
+		if txn.TransactedAt >= checkFrom && isSpending {
+			if amounts := history[key]; len(amounts) >= anomalyMinMerchantHistory {
+				mean, deviation := meanAndDeviation(amounts)
+				// a merchant that always charges the same still needs some leeway
+				spread := math.Max(deviation, mean*0.1)
+				if spend > mean+anomalyAmountDeviations*spread && spend-mean >= anomalyMinAmountIncrease {
+					flag(models.AnomalyUnusualAmount, txn, spend, fmt.Sprintf("$%.2f at %s is well above the usual $%.2f", spend, name, mean))
+				}
+			}
+
+			if !seen[key] && spend >= anomalyNewMerchantThreshold {
+				flag(models.AnomalyNewMerchant, txn, spend, fmt.Sprintf("First charge from %s is $%.2f", name, spend))
+			}
+
+			for j := i - 1; j >= 0 && txn.TransactedAt-txns[j].TransactedAt <= int64(anomalyDuplicateWindow.Seconds()); j-- {
+				earlier := txns[j]
+				if earlier.AccountID == txn.AccountID && transactionMerchantKey(earlier) == key {
+					if earlierSpend, ok := spendingAmount(earlier); ok && earlierSpend == spend {
+						flag(models.AnomalyDuplicate, txn, spend, fmt.Sprintf("%s charged $%.2f twice within %d minutes", name, spend, int(anomalyDuplicateWindow.Minutes())))
+						break
+					}
+				}
+			}
+
+			if currency := accountCurrency[txn.AccountID]; currency != "" && currency != primary {
+				flag(models.AnomalyForeign, txn, spend, fmt.Sprintf("$%.2f at %s was charged in %s", spend, name, currency))
+			} else if foreignCharge.MatchString(txn.Description) || foreignCharge.MatchString(txn.Payee) {
+				flag(models.AnomalyForeign, txn, spend, fmt.Sprintf("$%.2f at %s looks like a foreign currency charge", spend, name))
+			}
+		}
+
+		seen[key] = true
+		if isSpending && txn.TransactedAt >= historyFrom {
+			history[key] = append(history[key], spend)
+		}
+	}
+
+	return append(anomalies, detectCategorySpikes(in.UserId, txns, in.Now)...)
+}
+
+func meanAndDeviation(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)))
+}
+
+// Compares each category's spending this month so far with its average over the trailing full
+// months. Users whose history doesn't reach back into the first of those months are skipped,
+// their average would be too low. txns must be sorted oldest first
+func detectCategorySpikes(userId string, txns []models.Transaction, now time.Time) []models.Anomaly {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	trailingStart := monthStart.AddDate(0, -anomalySpikeTrailingMonths, 0)
+	if len(txns) == 0 || txns[0].TransactedAt >= trailingStart.AddDate(0, 1, 0).Unix() {
+		return nil
+	}
+
+	current, trailing := map[string]float64{}, map[string]float64{}
+	for _, txn := range txns {
+		if txn.TransactedAt < trailingStart.Unix() {
+			continue
+		}
+		for _, line := range SplitLines(txn) {
+			spend, ok := spendingAmount(line)
+			if !ok || line.Category == models.IncomeCategory {
+				continue
+			}
+			category := line.Category
+			if category == "" {
+				category = "Unknown"
+			}
+			if txn.TransactedAt >= monthStart.Unix() {
+				current[category] += spend
+			} else {
+				trailing[category] += spend
+			}
+		}
+	}
+
+	month := monthStart.Format("2006-01")
+	spikes := []models.Anomaly{}
+	for category, spent := range current {
+		average := trailing[category] / anomalySpikeTrailingMonths
+		if average <= 0 || spent <= average*anomalySpikeRatio || spent-average < anomalySpikeMinIncrease {
+			continue
+		}
+		spikes = append(spikes, models.Anomaly{
+			UserId:   userId,
+			Kind:     models.AnomalyCategorySpike,
+			Key:      category + ":" + month,
+			Category: category,
+			Amount:   roundCents(spent),
+			Reason:   fmt.Sprintf("$%.2f spent on %s so far this month, %.1fx the %d month average of $%.2f", spent, category, spent/average, anomalySpikeTrailingMonths, average),
+		})
+	}
+	sort.Slice(spikes, func(i, j int) bool { return spikes[i].Key < spikes[j].Key })
+	return spikes
+}
+
+var anomalyTitles = map[string]string{
+	models.AnomalyUnusualAmount: "Unusually large charge",
+	models.AnomalyDuplicate:     "Possible duplicate charge",
+	models.AnomalyNewMerchant:   "Large charge from a new merchant",
+	models.AnomalyForeign:       "Foreign currency charge",
+	models.AnomalyCategorySpike: "Spending spike",
+}
+
+func anomalyNotification(anomaly models.Anomaly) models.Notification {
+	return models.Notification{
+		UserId: anomaly.UserId,
+		Kind:   "anomaly",
+		Title:  anomalyTitles[anomaly.Kind],
+		Body:   anomaly.Reason + ".",
+		Data: map[string]string{
+			"anomaly_id":     anomaly.ID,
+			"kind":           anomaly.Kind,
+			"transaction_id": anomaly.TransactionId,
+			"category":       anomaly.Category,
+		},
+	}
+}
+
+// Flags anomalies in the user's transactions and stores the new ones. When the user enabled
+// anomaly alerts, new anomalies are also sent as notifications. Meant to run after a sync.
+func EvaluateAnomalies(userId string, now time.Time, dispatcher *notify.Dispatcher, pool *pgxpool.Pool) ([]models.Anomaly, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return nil, err
+	}
+	txns, err := db.FetchAllTransactions(accounts, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := db.InsertAnomalies(DetectAnomalies(AnomalyInput{UserId: userId, Accounts: accounts, Transactions: txns, Now: now}), pool)
+	if err != nil {
+		return inserted, err
+	}
+	if len(inserted) == 0 {
+		return inserted, nil
+	}
+
+	prefs, err := db.FetchNotificationPreferences(userId, pool)
+	if err != nil {
+		return inserted, err
+	}
+	if !prefs.AnomalyAlertsEnabled {
+		return inserted, nil
+	}
+	for _, anomaly := range inserted {
+		if err := dispatcher.Send(context.Background(), anomalyNotification(anomaly)); err != nil {
+			log.Printf("Failed to send anomaly alert to user %s: %v\n", userId, err)
+		}
+	}
+	return inserted, nil
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func anomalyKinds(anomalies []models.Anomaly) map[string]string {
+	kinds := map[string]string{}
+	for _, a := range anomalies {
+		kinds[a.Kind+" "+a.Key] = a.Reason
+	}
+	return kinds
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	now := date("2026-10-20")
+	txns := []models.Transaction{
+		txnOn("2026-05-02", "SHELL OIL 5744", "-42.10"),
+		txnOn("2026-06-01", "SHELL OIL 5744", "-38.75"),
+		txnOn("2026-07-04", "SHELL OIL 5744", "-45.00"),
+		txnOn("2026-08-03", "SHELL OIL 5744", "-40.20"),
+		txnOn("2026-09-05", "SHELL OIL 5744", "-44.30"),
+		txnOn("2026-10-18", "SHELL OIL 5744", "-310.00"),
+		// within the usual range
+		txnOn("2026-10-19", "SHELL OIL 5744", "-43.00"),
+
+		txnOn("2026-10-17", "BEST BUY 00123", "-899.99"),
+		txnOn("2026-10-17", "CORNER DELI", "-12.00"),
+		txnOn("2026-10-18", "CAFE DE FLORE EUR 12,50", "-14.20"),
+		txnOn("2026-10-19", "PAYROLL", "1500.00"),
+	}
+	// charged twice a few minutes apart
+	first := txnOn("2026-10-19", "SPOTIFY", "-11.99")
+	second := first
+	second.ID, second.TransactedAt = "SPOTIFY-again", first.TransactedAt+180
+	txns = append(txns, first, second)
+	// a big transfer isn't spending
+	transfer := txnOn("2026-10-18", "TRANSFER TO SAVINGS", "-5000.00")
+	transfer.TransferGroupId = "group"
+	txns = append(txns, transfer)
+
+	kinds := anomalyKinds(DetectAnomalies(AnomalyInput{UserId: "user", Transactions: txns, Now: now}))
+
+	for _, want := range []string{
+		models.AnomalyUnusualAmount + " SHELL OIL 5744-2026-10-18",
+		models.AnomalyNewMerchant + " BEST BUY 00123-2026-10-17",
+		models.AnomalyDuplicate + " SPOTIFY-again",
+		models.AnomalyForeign + " CAFE DE FLORE EUR 12,50-2026-10-18",
+		// none of these have a category, and the gas charge alone is way over the usual
+		models.AnomalyCategorySpike + " Unknown:2026-10",
+	} {
+		if _, ok := kinds[want]; !ok {
+			t.Errorf("expected %s, got %v", want, kinds)
+		}
+	}
+	if len(kinds) != 5 {
+		t.Errorf("expected 5 anomalies, got %v", kinds)
+	}
+}
+
+func TestDetectAnomaliesForeignAccount(t *testing.T) {
+	accounts := []models.StoredAccount{{ID: "acc", Currency: "USD"}, {ID: "savings", Currency: "USD"}, {ID: "travel", Currency: "EUR"}}
+	txn := txnOn("2026-10-19", "Boulangerie", "-8.40")
+	txn.AccountID = "travel"
+
+	anomalies := DetectAnomalies(AnomalyInput{UserId: "user", Accounts: accounts, Transactions: []models.Transaction{txn}, Now: date("2026-10-20")})
+	if len(anomalies) != 1 || anomalies[0].Kind != models.AnomalyForeign || anomalies[0].Reason != "$8.40 at Boulangerie was charged in EUR" {
+		t.Errorf("unexpected anomalies %+v", anomalies)
+	}
+}
+
+func TestDetectCategorySpikes(t *testing.T) {
+	categorized := func(day string, payee string, amount string, category string) models.Transaction {
+		txn := txnOn(day, payee, amount)
+		txn.Category = category
+		return txn
+	}
+	txns := []models.Transaction{
+		categorized("2026-07-05", "Trader Joe's", "-300", "Groceries"),
+		categorized("2026-08-05", "Trader Joe's", "-300", "Groceries"),
+		categorized("2026-09-05", "Trader Joe's", "-300", "Groceries"),
+		categorized("2026-07-10", "Cinema", "-20", "Entertainment"),
+		categorized("2026-10-02", "Trader Joe's", "-320", "Groceries"),
+		categorized("2026-10-03", "Concert", "-90", "Entertainment"),
+		categorized("2026-10-04", "Whole Foods", "-180", "Groceries"),
+	}
+
+	spikes := detectCategorySpikes("user", txns, date("2026-10-20"))
+	if len(spikes) != 1 || spikes[0].Key != "Groceries:2026-10" || spikes[0].Amount != 500 {
+		t.Fatalf("unexpected spikes %+v", spikes)
+	}
+
+	// not enough history to know what's usual
+	if spikes := detectCategorySpikes("user", txns[4:], date("2026-10-20")); len(spikes) != 0 {
+		t.Errorf("expected no spikes without history, got %+v", spikes)
+	}
+}
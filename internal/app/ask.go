@@ -0,0 +1,325 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+const (
+	maxAskQuestionLength = 500
+	maxQueryPlanSteps    = 4
+	// Longest period a single step can cover
+	maxQueryStepYears = 5
+	maxQueryStepLimit = 50
+	// Groups and transactions returned when the plan doesn't say
+	defaultQueryStepLimit = 10
+	// Page size used to read through a step's transactions
+	queryStepPageSize = 200
+)
+
+var ErrInvalidQueryPlan = errors.New("invalid query plan")
+
+var askGroupings = map[string]bool{models.ReportGroupByCategory: true, models.ReportGroupByMerchant: true, models.ReportGroupByTag: true}
+
+func askSystemPrompt(now time.Time) string {
+	return fmt.Sprintf(`You translate questions about a user's own bank transactions into a JSON query plan. Today is %s.
+Respond with only a JSON object of the form {"steps": [step, ...]} with 1 to %d steps, and nothing else. Each step is:
+{"label": short name for the step, e.g. "March 2026",
+ "metric": one of "spending", "income", "count", "transactions",
+ "from": first day "YYYY-MM-DD", "to": last day "YYYY-MM-DD" (inclusive),
+ "group_by": optional, one of "category", "merchant", "tag",
+ "categories": optional list, only from %v,
+ "tags": optional list of tag names,
+ "merchant": optional merchant or payee name,
+ "limit": optional number of transactions or groups, at most %d}
+Use one step per period or filter being compared. Months without a year are the most recent one that has started. If the question can't be answered from transactions, respond with {"steps": []}.`,
+		now.Format(time.DateOnly), maxQueryPlanSteps, TransactionCategories, maxQueryStepLimit)
+}
+
+// Reads the plan out of the model's reply, which sometimes wraps the JSON in a code block
+func ParseQueryPlan(reply string) (models.QueryPlan, error) {
+	reply = strings.TrimSpace(reply)
+	if start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}"); start >= 0 && end > start {
+		reply = reply[start : end+1]
+	}
+	var plan models.QueryPlan
+	decoder := json.NewDecoder(strings.NewReader(reply))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&plan); err != nil {
+		return models.QueryPlan{}, fmt.Errorf("%w: %v", ErrInvalidQueryPlan, err)
+	}
+	return plan, nil
+}
+
+// Checks that every step of the plan only uses known metrics, groupings and categories over a
+// bounded period, and fills in defaults. Nothing from the plan reaches the database without
+// passing through here
+func ValidateQueryPlan(plan models.QueryPlan) (models.QueryPlan, error) {
+	invalid := func(step int, format string, args ...any) error {
+		return fmt.Errorf("%w: step %d: %s", ErrInvalidQueryPlan, step+1, fmt.Sprintf(format, args...))
+	}
+	if len(plan.Steps) == 0 {
+		return plan, fmt.Errorf("%w: the question can't be answered from transactions", ErrInvalidQueryPlan)
+	}
+	if len(plan.Steps) > maxQueryPlanSteps {
+		return plan, fmt.Errorf("%w: at most %d steps", ErrInvalidQueryPlan, maxQueryPlanSteps)
+	}
+
+	steps := make([]models.QueryStep, len(plan.Steps))
+	for i, step := range plan.Steps {
+		switch step.Metric {
+		case models.AskMetricSpending, models.AskMetricIncome, models.AskMetricCount, models.AskMetricTransactions:
+		default:
+			return plan, invalid(i, "unknown metric %q", step.Metric)
+		}
+		from, err := time.Parse(time.DateOnly, step.From)
+		if err != nil {
+			return plan, invalid(i, "from must be formatted as YYYY-MM-DD")
+		}
+		to, err := time.Parse(time.DateOnly, step.To)
+		if err != nil {
+			return plan, invalid(i, "to must be formatted as YYYY-MM-DD")
+		}
+		if to.Before(from) || to.After(from.AddDate(maxQueryStepYears, 0, 0)) {
+			return plan, invalid(i, "the period must end after it starts and cover at most %d years", maxQueryStepYears)
+		}
+		if step.GroupBy != "" {
+			if !askGroupings[step.GroupBy] {
+				return plan, invalid(i, "unknown group_by %q", step.GroupBy)
+			}
+			if step.Metric != models.AskMetricSpending && step.Metric != models.AskMetricIncome {
+				return plan, invalid(i, "only spending and income can be grouped")
+			}
+		}
+		for _, category := range step.Categories {
+			if !containsString(TransactionCategories, category) {
+				return plan, invalid(i, "unknown category %q", category)
+			}
+		}
+		for j, tag := range step.Tags {
+			if step.Tags[j] = NormalizeTag(tag); step.Tags[j] == "" {
+				return plan, invalid(i, "empty tag")
+			}
+		}
+		step.Merchant = strings.TrimSpace(step.Merchant)
+		if len(step.Merchant) > 100 {
+			return plan, invalid(i, "merchant is too long")
+		}
+		if step.Limit < 0 || step.Limit > maxQueryStepLimit {
+			return plan, invalid(i, "limit must be between 0 and %d", maxQueryStepLimit)
+		}
+		if step.Limit == 0 {
+			step.Limit = defaultQueryStepLimit
+		}
+		if step.Label == "" {
+			step.Label = step.From + " - " + step.To
+		}
+		steps[i] = step
+	}
+	return models.QueryPlan{Steps: steps}, nil
+}
+
+func askGroupKey(txn models.Transaction, groupBy string) []string {
+	switch groupBy {
+	case models.ReportGroupByCategory:
+		return []string{txn.Category}
+	case models.ReportGroupByMerchant:
+		return []string{transactionMerchantName(txn)}
+	}
+	if len(txn.Tags) == 0 {
+		return []string{"untagged"}
+	}
+	return txn.Tags
+}
+
+// Runs one validated step over the user's transactions. Spending and income count split lines
+// under their own categories and leave transfers out, like the reports
+func ExecuteQueryStep(repo TransactionRepository, userId string, step models.QueryStep) (models.QueryStepResult, error) {
+	from, _ := time.Parse(time.DateOnly, step.From)
+	to, _ := time.Parse(time.DateOnly, step.To)
+	q := models.TransactionQuery{
+		From:   from.Unix(),
+		To:     to.AddDate(0, 0, 1).Unix(),
+		Tags:   step.Tags,
+		Search: step.Merchant,
+		Sort:   models.TransactionSortDateDesc,
+		Limit:  queryStepPageSize,
+	}
+	if step.Metric == models.AskMetricTransactions {
+		// the biggest charges are the interesting ones
+		q.Sort = models.TransactionSortAmountAsc
+	}
+
+	result := models.QueryStepResult{Label: step.Label, Metric: step.Metric, From: step.From, To: step.To}
+	groups := map[string]*models.SpendingGroup{}
+	for {
+		ranked, _, err := repo.SearchTransactions(userId, q)
+		if err != nil {
+			return result, err
+		}
+		page := ranked
+		if len(page) > q.Limit {
+			page = page[:q.Limit]
+		}
+
+		for _, r := range page {
+			txn := r.Transaction
+			counted := false
+			for _, line := range SplitLines(txn) {
+				if len(step.Categories) > 0 && !containsString(step.Categories, line.Category) {
+					continue
+				}
+				counted = true
+				var amount float64
+				switch step.Metric {
+				case models.AskMetricSpending:
+					spend, ok := spendingAmount(line)
+					if !ok || line.Category == models.IncomeCategory {
+						continue
+					}
+					amount = spend
+				case models.AskMetricIncome:
+					cents, ok := amountCents(line.Amount)
+					if !ok || cents <= 0 || line.TransferGroupId != "" || line.Category != models.IncomeCategory {
+						continue
+					}
+					amount = float64(cents) / 100
+				default:
+					continue
+				}
+				result.Total += amount
+				if step.GroupBy != "" {
+					for _, key := range askGroupKey(line, step.GroupBy) {
+						if groups[key] == nil {
+							groups[key] = &models.SpendingGroup{Key: key}
+						}
+						groups[key].Total += amount
+						groups[key].TransactionCount++
+					}
+				}
+			}
+			if !counted {
+				continue
+			}
+			result.Count++
+			if step.Metric == models.AskMetricTransactions && len(result.Transactions) < step.Limit {
+				result.Transactions = append(result.Transactions, txn)
+			}
+		}
+
+		if len(ranked) <= q.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		q.After = &models.TransactionCursor{Sort: q.Sort, Value: strconv.FormatInt(last.TransactedAt, 10), ID: last.ID}
+		if q.Sort == models.TransactionSortAmountAsc {
+			q.After.Value = last.Amount
+		}
+	}
+
+	for _, group := range groups {
+		group.Total = roundCents(group.Total)
+		result.Groups = append(result.Groups, *group)
+	}
+	sort.Slice(result.Groups, func(i, j int) bool {
+		if result.Groups[i].Total != result.Groups[j].Total {
+			return result.Groups[i].Total > result.Groups[j].Total
+		}
+		return result.Groups[i].Key < result.Groups[j].Key
+	})
+	if len(result.Groups) > step.Limit {
+		result.Groups = result.Groups[:step.Limit]
+	}
+	result.Total = roundCents(result.Total)
+	return result, nil
+}
+
+func describeQueryStep(step models.QueryStep, result models.QueryStepResult) string {
+	subject := "Spending"
+	switch step.Metric {
+	case models.AskMetricIncome:
+		subject = "Income"
+	case models.AskMetricCount, models.AskMetricTransactions:
+		subject = "Transactions"
+	}
+	var filters []string
+	if len(step.Categories) > 0 {
+		filters = append(filters, strings.Join(step.Categories, ", "))
+	}
+	if step.Merchant != "" {
+		filters = append(filters, step.Merchant)
+	}
+	if len(step.Tags) > 0 {
+		filters = append(filters, "#"+strings.Join(step.Tags, " #"))
+	}
+	if len(filters) > 0 {
+		subject += " on " + strings.Join(filters, ", ")
+	}
+
+	value := fmt.Sprintf("$%.2f", result.Total)
+	if step.Metric == models.AskMetricCount || step.Metric == models.AskMetricTransactions {
+		value = fmt.Sprintf("%d", result.Count)
+	}
+	return fmt.Sprintf("%s in %s: %s.", subject, step.Label, value)
+}
+
+// Writes the answer from the results rather than asking the model again, so every number in it
+// comes straight from the user's data
+func DescribeQueryResults(plan models.QueryPlan, results []models.QueryStepResult) string {
+	sentences := make([]string, len(results))
+	for i, result := range results {
+		sentences[i] = describeQueryStep(plan.Steps[i], result)
+	}
+	// a comparison of two amounts gets the difference spelled out
+	if len(results) == 2 && results[0].Metric == results[1].Metric && (results[0].Metric == models.AskMetricSpending || results[0].Metric == models.AskMetricIncome) {
+		a, b := results[0], results[1]
+		difference := fmt.Sprintf("%s is $%.2f %s than %s", a.Label, math.Abs(a.Total-b.Total), map[bool]string{true: "more", false: "less"}[a.Total >= b.Total], b.Label)
+		if percent := changePercent(b.Total, a.Total); percent != nil {
+			difference += fmt.Sprintf(" (%+.1f%%)", *percent)
+		}
+		sentences = append(sentences, difference+".")
+	}
+	return strings.Join(sentences, " ")
+}
+
+// Has the model turn the question into a query plan, validates it and runs it over the user's
+// transactions
+func AskQuestion(ctx context.Context, model ChatModel, repo TransactionRepository, userId string, question string, now time.Time) (models.AskResponse, error) {
+	question = strings.TrimSpace(question)
+	response := models.AskResponse{Question: question, Results: []models.QueryStepResult{}}
+	if question == "" || len(question) > maxAskQuestionLength {
+		return response, fmt.Errorf("%w: the question must be between 1 and %d characters", ErrInvalidQueryPlan, maxAskQuestionLength)
+	}
+
+	reply, err := model.Complete(ctx, askSystemPrompt(now), question)
+	if err != nil {
+		return response, fmt.Errorf("failed to plan question: %w", err)
+	}
+	plan, err := ParseQueryPlan(reply)
+	if err != nil {
+		return response, err
+	}
+	if plan, err = ValidateQueryPlan(plan); err != nil {
+		return response, err
+	}
+	response.Plan = plan
+
+	for _, step := range plan.Steps {
+		result, err := ExecuteQueryStep(repo, userId, step)
+		if err != nil {
+			return response, err
+		}
+		response.Results = append(response.Results, result)
+	}
+	response.Answer = DescribeQueryResults(plan, response.Results)
+	return response, nil
+}
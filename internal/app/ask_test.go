@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// Stands in for the language model with a canned reply
+type stubChatModel struct {
+	reply  string
+	system string
+	user   string
+}
+
+func (m *stubChatModel) Complete(ctx context.Context, system string, user string) (string, error) {
+	m.system, m.user = system, user
+	return m.reply, nil
+}
+
+func askRepository() MemoryTransactionRepository {
+	repo := MemoryTransactionRepository{AccountOwners: map[string]string{"checking": "user-1", "other": "user-2"}}
+	add := func(day string, payee string, amount string, category string, account string) {
+		txn := txnOn(day, payee, amount)
+		txn.AccountID, txn.Category = account, category
+		repo.Transactions = append(repo.Transactions, txn)
+	}
+	add("2026-02-03", "Chipotle", "-14.20", "Food & Dining", "checking")
+	add("2026-02-17", "Olive Garden", "-62.80", "Food & Dining", "checking")
+	add("2026-02-20", "Safeway", "-120.00", "Groceries", "checking")
+	add("2026-03-05", "Chipotle", "-15.10", "Food & Dining", "checking")
+	add("2026-03-12", "Nobu", "-210.00", "Food & Dining", "checking")
+	add("2026-03-14", "Acme Payroll", "3000.00", "Income", "checking")
+	// someone else's dinner never shows up
+	add("2026-03-20", "Nobu", "-500.00", "Food & Dining", "other")
+
+	// a split grocery run with a restaurant line
+	split := txnOn("2026-03-22", "Costco", "-100.00")
+	split.AccountID, split.Category = "checking", "Groceries"
+	split.Splits = []models.TransactionSplit{{Amount: "-70.00", Category: "Groceries"}, {Amount: "-30.00", Category: "Food & Dining"}}
+	repo.Transactions = append(repo.Transactions, split)
+	return repo
+}
+
+func TestAskQuestion(t *testing.T) {
+	model := &stubChatModel{reply: "```json\n" + `{"steps": [
+		{"label": "March", "metric": "spending", "from": "2026-03-01", "to": "2026-03-31", "categories": ["Food & Dining"]},
+		{"label": "February", "metric": "spending", "from": "2026-02-01", "to": "2026-02-28", "categories": ["Food & Dining"]}
+	]}` + "\n```"}
+
+	response, err := AskQuestion(context.Background(), model, askRepository(), "user-1", "how much did I spend on restaurants in March vs February?", date("2026-04-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(model.system, "Today is 2026-04-02") || model.user != "how much did I spend on restaurants in March vs February?" {
+		t.Errorf("unexpected prompt %q / %q", model.system, model.user)
+	}
+	if len(response.Results) != 2 || response.Results[0].Total != 255.10 || response.Results[0].Count != 3 || response.Results[1].Total != 77 {
+		t.Fatalf("unexpected results %+v", response.Results)
+	}
+	want := "Spending on Food & Dining in March: $255.10. Spending on Food & Dining in February: $77.00. March is $178.10 more than February (+231.3%)."
+	if response.Answer != want {
+		t.Errorf("answer = %q", response.Answer)
+	}
+}
+
+func TestExecuteQueryStepGroupsAndTransactions(t *testing.T) {
+	repo := askRepository()
+	plan, err := ValidateQueryPlan(models.QueryPlan{Steps: []models.QueryStep{
+		{Metric: models.AskMetricSpending, From: "2026-03-01", To: "2026-03-31", GroupBy: models.ReportGroupByCategory},
+		{Metric: models.AskMetricTransactions, From: "2026-02-01", To: "2026-03-31", Merchant: "chipotle", Limit: 1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grouped, err := ExecuteQueryStep(repo, "user-1", plan.Steps[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grouped.Total != 325.10 || len(grouped.Groups) != 2 || grouped.Groups[0].Key != "Food & Dining" || grouped.Groups[1].Total != 70 {
+		t.Errorf("unexpected grouped result %+v", grouped)
+	}
+
+	listed, err := ExecuteQueryStep(repo, "user-1", plan.Steps[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listed.Count != 2 || len(listed.Transactions) != 1 || listed.Transactions[0].Amount != "-15.10" {
+		t.Errorf("unexpected transactions result %+v", listed)
+	}
+}
+
+func TestValidateQueryPlan(t *testing.T) {
+	valid := models.QueryStep{Metric: models.AskMetricSpending, From: "2026-03-01", To: "2026-03-31"}
+	cases := map[string]func(step *models.QueryStep){
+		"unknown metric":     func(step *models.QueryStep) { step.Metric = "sql" },
+		"bad date":           func(step *models.QueryStep) { step.From = "March" },
+		"backwards":          func(step *models.QueryStep) { step.To = "2026-02-01" },
+		"too long":           func(step *models.QueryStep) { step.From = "2010-01-01" },
+		"unknown category":   func(step *models.QueryStep) { step.Categories = []string{"Restaurants"} },
+		"unknown group":      func(step *models.QueryStep) { step.GroupBy = "payee; DROP TABLE" },
+		"grouped count":      func(step *models.QueryStep) { step.Metric, step.GroupBy = models.AskMetricCount, "category" },
+		"limit out of range": func(step *models.QueryStep) { step.Limit = 1000 },
+	}
+	for name, change := range cases {
+		step := valid
+		change(&step)
+		if _, err := ValidateQueryPlan(models.QueryPlan{Steps: []models.QueryStep{step}}); !errors.Is(err, ErrInvalidQueryPlan) {
+			t.Errorf("%s: expected an invalid plan, got %v", name, err)
+		}
+	}
+
+	if _, err := ValidateQueryPlan(models.QueryPlan{}); !errors.Is(err, ErrInvalidQueryPlan) {
+		t.Errorf("expected an empty plan to be rejected, got %v", err)
+	}
+	if _, err := ParseQueryPlan(`{"steps": [], "sql": "SELECT 1"}`); !errors.Is(err, ErrInvalidQueryPlan) {
+		t.Errorf("expected unknown fields to be rejected, got %v", err)
+	}
+
+	plan, err := ValidateQueryPlan(models.QueryPlan{Steps: []models.QueryStep{valid}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step := plan.Steps[0]; step.Limit != defaultQueryStepLimit || step.Label != fmt.Sprintf("%s - %s", valid.From, valid.To) {
+		t.Errorf("expected defaults to be filled in, got %+v", step)
+	}
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/storage"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const MaxAttachmentSize = 10 << 20
+
+// Longest side of a thumbnail in pixels
+const thumbnailSize = 256
+
+// Largest image, in pixels, a thumbnail is made for. A small file can claim huge dimensions and
+// decoding allocates for all of them, so the size is checked from the header before decoding
+const maxThumbnailPixels = 40_000_000
+
+var ErrImageTooLarge = fmt.Errorf("images over %d megapixels don't get thumbnails", maxThumbnailPixels/1_000_000)
+
+// Content types accepted for attachments, detected from the file contents rather than trusting
+// the upload. Only the ones the standard library can decode get thumbnails
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      false,
+	"application/pdf": false,
+}
+
+var ErrAttachmentTooLarge = fmt.Errorf("attachments can be at most %d MB", MaxAttachmentSize>>20)
+var ErrAttachmentType = errors.New("attachments must be a JPEG, PNG, GIF or WebP image or a PDF")
+
+// Returns the content type of an upload and whether a thumbnail can be made for it
+func DetectAttachmentType(data []byte) (string, bool, error) {
+	if len(data) > MaxAttachmentSize {
+		return "", false, ErrAttachmentTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	thumbnail, ok := attachmentTypes[contentType]
+	if !ok {
+		return "", false, ErrAttachmentType
+	}
+	return contentType, thumbnail, nil
+}
+
+// Scales an image down so its longest side is at most thumbnailSize and encodes it as a JPEG.
+// Each thumbnail pixel averages the block of source pixels it covers
+func MakeThumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxThumbnailPixels {
+		return nil, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := float64(thumbnailSize) / float64(max(width, height))
+	if scale > 1 {
+		scale = 1
+	}
+	thumbWidth, thumbHeight := max(int(float64(width)*scale), 1), max(int(float64(height)*scale), 1)
+
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0, y1 := bounds.Min.Y+y*height/thumbHeight, bounds.Min.Y+max((y+1)*height/thumbHeight, y*height/thumbHeight+1)
+		for x := 0; x < thumbWidth; x++ {
+			x0, x1 := bounds.Min.X+x*width/thumbWidth, bounds.Min.X+max((x+1)*width/thumbWidth, x*width/thumbWidth+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			offset := thumb.PixOffset(x, y)
+			thumb.Pix[offset] = uint8(r / n >> 8)
+			thumb.Pix[offset+1] = uint8(g / n >> 8)
+			thumb.Pix[offset+2] = uint8(b / n >> 8)
+			thumb.Pix[offset+3] = uint8(a / n >> 8)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Stores an uploaded file (and its thumbnail) for a transaction. The caller has already checked
+// that the transaction belongs to the user
+func SaveAttachment(ctx context.Context, userId string, transactionId string, filename string, data []byte, store storage.BlobStore, pool *pgxpool.Pool) (models.Attachment, error) {
+	contentType, canThumbnail, err := DetectAttachmentType(data)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	prefix := path.Join("attachments", userId, uuid.NewString())
+	attachment := models.Attachment{
+		TransactionId: transactionId,
+		UserId:        userId,
+		Filename:      path.Base(filename),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		StorageKey:    prefix + "/original",
+	}
+	if err := store.Put(ctx, attachment.StorageKey, data, contentType); err != nil {
+		return models.Attachment{}, err
+	}
+	if canThumbnail {
+		// a broken image is still kept, it just doesn't get a thumbnail
+		if thumb, err := MakeThumbnail(data); err != nil {
+			log.Printf("Failed to create thumbnail for %s: %v\n", attachment.StorageKey, err)
+		} else if err := store.Put(ctx, prefix+"/thumbnail.jpg", thumb, "image/jpeg"); err == nil {
+			attachment.ThumbnailKey = prefix + "/thumbnail.jpg"
+			attachment.HasThumbnail = true
+		}
+	}
+
+	attachment.ID, err = db.InsertAttachment(attachment, pool)
+	if err != nil {
+		removeAttachmentBlobs(ctx, attachment, store)
+		return models.Attachment{}, fmt.Errorf("failed to save attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+func removeAttachmentBlobs(ctx context.Context, attachment models.Attachment, store storage.BlobStore) error {
+	if err := store.Delete(ctx, attachment.StorageKey); err != nil {
+		return err
+	}
+	if attachment.ThumbnailKey != "" {
+		return store.Delete(ctx, attachment.ThumbnailKey)
+	}
+	return nil
+}
+
+// Removes the attachment's files and then its row
+func DeleteAttachment(ctx context.Context, attachment models.Attachment, store storage.BlobStore, pool *pgxpool.Pool) error {
+	if err := removeAttachmentBlobs(ctx, attachment, store); err != nil {
+		return err
+	}
+	return db.DeleteAttachment(attachment.ID, pool)
+}
+
+// Deletes the files of attachments whose transaction has been deleted
+func CleanUpAttachments(store storage.BlobStore, pool *pgxpool.Pool) {
+	orphaned, err := db.FetchOrphanedAttachments(500, pool)
+	if err != nil {
+		log.Printf("Failed to fetch orphaned attachments: %v\n", err)
+		return
+	}
+	for _, attachment := range orphaned {
+		if err := DeleteAttachment(context.Background(), attachment, store, pool); err != nil {
+			log.Printf("Failed to delete orphaned attachment %s: %v\n", attachment.ID, err)
+		}
+	}
+}
+
+func RunAttachmentCleanup(interval time.Duration, store storage.BlobStore, pool *pgxpool.Pool) {
+	CleanUpAttachments(store, pool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		CleanUpAttachments(store, pool)
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestDetectAttachmentType(t *testing.T) {
+	if contentType, thumbnail, err := DetectAttachmentType([]byte("%PDF-1.7\n...")); err != nil || contentType != "application/pdf" || thumbnail {
+		t.Errorf("Expected a PDF without thumbnail, got %q %v %v", contentType, thumbnail, err)
+	}
+	if _, _, err := DetectAttachmentType([]byte("#!/bin/sh\nrm -rf /")); !errors.Is(err, ErrAttachmentType) {
+		t.Errorf("Expected a script to be rejected, got %v", err)
+	}
+	if _, _, err := DetectAttachmentType(make([]byte, MaxAttachmentSize+1)); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("Expected an oversized file to be rejected, got %v", err)
+	}
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	receipt := image.NewRGBA(image.Rect(0, 0, 600, 1200))
+	for y := 0; y < 1200; y++ {
+		for x := 0; x < 600; x++ {
+			receipt.Set(x, y, color.RGBA{200, 100, 50, 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, receipt)
+
+	if _, thumbnail, err := DetectAttachmentType(buf.Bytes()); err != nil || !thumbnail {
+		t.Fatalf("Expected a PNG to get a thumbnail, got %v %v", thumbnail, err)
+	}
+	data, err := MakeThumbnail(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Thumbnail is not a JPEG: %v", err)
+	}
+	if size := thumb.Bounds().Size(); size.X != 128 || size.Y != 256 {
+		t.Errorf("Expected a 128x256 thumbnail, got %v", size)
+	}
+	if r, _, _, _ := thumb.At(64, 128).RGBA(); r>>8 < 190 || r>>8 > 210 {
+		t.Errorf("Expected the thumbnail to keep the image colour, got red %d", r>>8)
+	}
+}
+
+func TestMakeThumbnailRejectsDecompressionBombs(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+
+	// claim 100000x100000 pixels in the IHDR chunk (after the 8 byte signature, length and type)
+	// and fix up its checksum, the way a crafted upload would
+	binary.BigEndian.PutUint32(data[16:20], 100000)
+	binary.BigEndian.PutUint32(data[20:24], 100000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, err := MakeThumbnail(data); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected the image to be rejected before decoding, got %v", err)
+	}
+}
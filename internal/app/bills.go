@@ -0,0 +1,193 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns the dates in [from, until) a recurring series is expected on. A series that is slightly
+// overdue (but not yet considered cancelled) is expected on from, since it most likely just posts late.
+func ProjectSeriesDates(s models.RecurringSeries, from time.Time, until time.Time) []time.Time {
+	due, err := time.Parse(time.DateOnly, s.NextExpectedDate)
+	if err != nil {
+		return nil
+	}
+	var dates []time.Time
+	if due.Before(from) {
+		dates = append(dates, from)
+		due = AddCadence(due, s.Cadence)
+	}
+	for ; due.Before(until); due = AddCadence(due, s.Cadence) {
+		if !due.Before(from) {
+			dates = append(dates, due)
+		}
+	}
+	return dates
+}
+
+// Lists every bill expected in [from, until), from both detected recurring expenses and manual bills
+func ProjectBills(series []models.RecurringSeries, manual []models.ManualBill, from time.Time, until time.Time) []models.UpcomingBill {
+	bills := []models.UpcomingBill{}
+
+	for _, s := range series {
+		if s.IsIncome || s.Status != models.RecurringStatusActive || s.Review == models.RecurringReviewDismissed {
+			continue
+		}
+		for _, due := range ProjectSeriesDates(s, from, until) {
+			bills = append(bills, models.UpcomingBill{
+				Source:    "detected",
+				SourceId:  s.ID,
+				Name:      s.Payee,
+				Amount:    math.Abs(s.NextExpectedAmount),
+				DueDate:   due.Format(time.DateOnly),
+				AccountId: s.AccountId,
+				Category:  s.Category,
+			})
+		}
+	}
+
+	for _, m := range manual {
+		due, err := time.Parse(time.DateOnly, m.NextDueDate)
+		if err != nil {
+			continue
+		}
+		bill := models.UpcomingBill{
+			Source:    "manual",
+			SourceId:  m.ID,
+			Name:      m.Name,
+			Amount:    m.Amount,
+			AccountId: m.AccountId,
+			Category:  m.Category,
+		}
+		if m.Cadence == "once" {
+			if !due.Before(from) && due.Before(until) {
+				bill.DueDate = due.Format(time.DateOnly)
+				bills = append(bills, bill)
+			}
+			continue
+		}
+		// users don't move the due date forward after paying, so older dates just roll over
+		for ; due.Before(until); due = AddCadence(due, m.Cadence) {
+			if !due.Before(from) {
+				bill.DueDate = due.Format(time.DateOnly)
+				bills = append(bills, bill)
+			}
+		}
+	}
+
+	sort.SliceStable(bills, func(i, j int) bool { return bills[i].DueDate < bills[j].DueDate })
+	return bills
+}
+
+// Walks each account's balance through its upcoming bills (in due date order) to find how low it
+// will get. Bills that aren't tied to an account don't affect any balance.
+func ProjectAccountBalances(accounts []models.StoredAccount, bills []models.UpcomingBill) []models.ProjectedAccountBalance {
+	projections := []models.ProjectedAccountBalance{}
+	for _, account := range accounts {
+		balance, _ := strconv.ParseFloat(account.Balance, 64)
+		projection := models.ProjectedAccountBalance{
+			AccountId:      account.ID,
+			Name:           account.Name,
+			CurrentBalance: balance,
+			LowestBalance:  balance,
+		}
+		for _, bill := range bills {
+			if bill.AccountId != account.ID {
+				continue
+			}
+			balance -= bill.Amount
+			if balance < projection.LowestBalance {
+				projection.LowestBalance = roundCents(balance)
+				projection.LowestBalanceDate = bill.DueDate
+			}
+		}
+		projection.ProjectedBalance = roundCents(balance)
+		projection.OverdraftWarning = projection.LowestBalance < 0
+		projections = append(projections, projection)
+	}
+	return projections
+}
+
+func FetchUpcomingBills(userId string, now time.Time, days int, pool *pgxpool.Pool) (models.UpcomingBillsResponse, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return models.UpcomingBillsResponse{}, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return models.UpcomingBillsResponse{}, err
+	}
+	series, err := FetchRecurringSeries(userId, now, false, pool)
+	if err != nil {
+		return models.UpcomingBillsResponse{}, err
+	}
+	manual, err := db.FetchManualBills(userId, pool)
+	if err != nil {
+		return models.UpcomingBillsResponse{}, err
+	}
+
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	bills := ProjectBills(series, manual, from, from.AddDate(0, 0, days))
+	return models.UpcomingBillsResponse{
+		Bills:    bills,
+		Accounts: ProjectAccountBalances(accounts, bills),
+	}, nil
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+// Builds an iCalendar (RFC 5545) document with an all day event per bill
+func BuildICalendar(bills []models.UpcomingBill, now time.Time) string {
+	var b strings.Builder
+	writeLine := func(line string) {
+		// lines longer than 75 octets are folded onto continuation lines starting with a space
+		for len(line) > 75 {
+			cut := 75
+			for cut > 0 && !utf8RuneStart(line[cut]) {
+				cut--
+			}
+			b.WriteString(line[:cut] + "\r\n")
+			line = " " + line[cut:]
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//Pocketwise//Bills//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("X-WR-CALNAME:Pocketwise bills")
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, bill := range bills {
+		due, err := time.Parse(time.DateOnly, bill.DueDate)
+		if err != nil {
+			continue
+		}
+		writeLine("BEGIN:VEVENT")
+		writeLine(fmt.Sprintf("UID:%s-%s-%s@pocketwise", bill.Source, bill.SourceId, due.Format("20060102")))
+		writeLine("DTSTAMP:" + stamp)
+		writeLine("DTSTART;VALUE=DATE:" + due.Format("20060102"))
+		writeLine("DTEND;VALUE=DATE:" + due.AddDate(0, 0, 1).Format("20060102"))
+		writeLine("SUMMARY:" + icalEscaper.Replace(fmt.Sprintf("%s $%.2f", bill.Name, bill.Amount)))
+		if bill.Category != "" {
+			writeLine("CATEGORIES:" + icalEscaper.Replace(bill.Category))
+		}
+		writeLine("TRANSP:TRANSPARENT")
+		writeLine("END:VEVENT")
+	}
+	writeLine("END:VCALENDAR")
+	return b.String()
+}
+
+func utf8RuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestProjectBillsAndBalances(t *testing.T) {
+	from := date("2026-10-19")
+	series := []models.RecurringSeries{
+		{ID: "netflix", Payee: "Netflix", AccountId: "checking", Cadence: "monthly", NextExpectedDate: "2026-11-03", NextExpectedAmount: -17.99, Status: models.RecurringStatusActive},
+		{ID: "payroll", Payee: "Acme", AccountId: "checking", Cadence: "biweekly", NextExpectedDate: "2026-10-30", NextExpectedAmount: 2150, IsIncome: true, Status: models.RecurringStatusActive},
+		{ID: "gym", Payee: "City Gym", AccountId: "checking", Cadence: "monthly", NextExpectedDate: "2026-07-10", NextExpectedAmount: -40, Status: models.RecurringStatusCancelled},
+	}
+	manual := []models.ManualBill{
+		{ID: "rent", Name: "Rent", Amount: 1500, AccountId: "checking", Cadence: "monthly", NextDueDate: "2026-09-01"},
+	}
+
+	bills := ProjectBills(series, manual, from, from.AddDate(0, 0, 30))
+	if len(bills) != 2 {
+		t.Fatalf("Expected rent and netflix, got %+v", bills)
+	}
+	if bills[0].SourceId != "rent" || bills[0].DueDate != "2026-11-01" {
+		t.Errorf("Expected rent to roll over to 2026-11-01, got %+v", bills[0])
+	}
+
+	accounts := []models.StoredAccount{{ID: "checking", Name: "Checking", Balance: "1510.00"}}
+	projected := ProjectAccountBalances(accounts, bills)
+	if projected[0].ProjectedBalance != -7.99 || !projected[0].OverdraftWarning || projected[0].LowestBalanceDate != "2026-11-03" {
+		t.Errorf("Expected an overdraft warning on 2026-11-03, got %+v", projected[0])
+	}
+}
+
+func TestBuildICalendar(t *testing.T) {
+	bills := []models.UpcomingBill{{Source: "manual", SourceId: "rent", Name: "Rent, downtown; apt 4", Amount: 1500, DueDate: "2026-11-01"}}
+	ics := BuildICalendar(bills, time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:manual-rent-20261101@pocketwise\r\n",
+		"DTSTART;VALUE=DATE:20261101\r\n",
+		`SUMMARY:Rent\, downtown\; apt 4 $1500.00` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, expected) {
+			t.Errorf("Expected calendar to contain %q, got:\n%s", expected, ics)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/notify"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns the thresholds that the budget's current spending has reached
+func CrossedThresholds(progress models.BudgetProgress, thresholds []models.BudgetAlertThreshold) []models.BudgetAlertThreshold {
+	lines := map[string]models.BudgetLineProgress{"total": progress.Total}
+	for _, line := range progress.Lines {
+		lines[line.Field] = line
+	}
+
+	var crossed []models.BudgetAlertThreshold
+	for _, threshold := range thresholds {
+		line, ok := lines[threshold.Field]
+		// lines without a budgeted amount have nothing to overspend against
+		if !ok || line.Budgeted <= 0 {
+			continue
+		}
+		if line.PercentUsed >= threshold.Percent {
+			crossed = append(crossed, threshold)
+		}
+	}
+	return crossed
+}
+
+func budgetAlertNotification(userId string, progress models.BudgetProgress, threshold models.BudgetAlertThreshold) models.Notification {
+	var line models.BudgetLineProgress
+	if threshold.Field == "total" {
+		line = progress.Total
+	}
+	for _, l := range progress.Lines {
+		if l.Field == threshold.Field {
+			line = l
+		}
+	}
+
+	title := fmt.Sprintf("You've used %.0f%% of your %s budget", line.PercentUsed, threshold.Field)
+	if line.PercentUsed >= 100 {
+		title = fmt.Sprintf("You're over your %s budget", threshold.Field)
+	}
+	return models.Notification{
+		UserId: userId,
+		Kind:   "budget_alert",
+		Title:  title,
+		Body:   fmt.Sprintf("$%.2f spent of $%.2f budgeted for %s - %s.", line.Spent, line.Budgeted, progress.PeriodStart, progress.PeriodEnd),
+		Data: map[string]string{
+			"budget_id":    progress.BudgetId,
+			"field":        threshold.Field,
+			"threshold":    fmt.Sprintf("%.0f", threshold.Percent),
+			"period_start": progress.PeriodStart,
+		},
+	}
+}
+
+// Checks every budget active right now against its alert thresholds and notifies the user about
+// newly crossed ones. Each threshold fires at most once per period window. Meant to run after a sync.
+func EvaluateBudgetAlerts(userId string, now time.Time, dispatcher *notify.Dispatcher, pool *pgxpool.Pool) error {
+	activeBudgets, err := FetchActiveBudgets(userId, now, pool)
+	if err != nil {
+		return err
+	}
+
+	for _, active := range activeBudgets {
+		thresholds, err := db.FetchBudgetAlertThresholds(userId, active.Budget.ID, pool)
+		if err != nil {
+			return err
+		}
+		if len(thresholds) == 0 {
+			continue
+		}
+
+		start, _ := time.Parse(time.DateOnly, active.PeriodStart)
+		end, _ := time.Parse(time.DateOnly, active.PeriodEnd)
+		spending, err := db.FetchSpendingByCategory(userId, start.Unix(), end.Unix(), pool)
+		if err != nil {
+			return err
+		}
+		progress := CalculateBudgetProgress(active.Budget, start, end, now, 1, spending)
+
+		for _, threshold := range CrossedThresholds(progress, thresholds) {
+			isNew, err := db.InsertBudgetAlertEvent(userId, active.Budget.ID, active.PeriodStart, threshold, pool)
+			if err != nil {
+				return err
+			}
+			if !isNew {
+				continue
+			}
+			if err := dispatcher.Send(context.Background(), budgetAlertNotification(userId, progress, threshold)); err != nil {
+				log.Printf("Failed to send budget alert to user %s: %v\n", userId, err)
+			}
+		}
+	}
+	return nil
+}
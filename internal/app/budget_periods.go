@@ -0,0 +1,247 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrBudgetNotActive = errors.New("budget is not active")
+
+// Validates a budget's period and fills in the dates that can be derived. Monthly budgets take their
+// dates from year/month, every other period takes year/month from its start date. New budgets
+// without a period are monthly, see NormalizeBudgetUpdatePeriod for edits.
+func NormalizeBudgetPeriod(period *models.BudgetPeriod, year *int, month *int) error {
+	if period.Period == "" {
+		period.Period = models.BudgetPeriodMonthly
+	}
+
+	if period.Period == models.BudgetPeriodMonthly {
+		if *month < 1 || *month > 12 {
+			return fmt.Errorf("invalid month: %d", *month)
+		}
+		*period = models.MonthlyPeriod(*year, *month)
+		return nil
+	}
+
+	start, err := time.Parse(time.DateOnly, period.StartDate)
+	if err != nil {
+		return fmt.Errorf("start_date must be formatted as YYYY-MM-DD")
+	}
+	*year, *month = start.Year(), int(start.Month())
+
+	switch period.Period {
+	case models.BudgetPeriodWeekly, models.BudgetPeriodBiweekly, models.BudgetPeriodQuarterly, models.BudgetPeriodYearly:
+		if period.EndDate == "" {
+			return nil
+		}
+	case models.BudgetPeriodCustom:
+		if period.EndDate == "" {
+			return fmt.Errorf("custom budgets require an end_date")
+		}
+	default:
+		return fmt.Errorf("unknown budget period: %s", period.Period)
+	}
+
+	end, err := time.Parse(time.DateOnly, period.EndDate)
+	if err != nil {
+		return fmt.Errorf("end_date must be formatted as YYYY-MM-DD")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end_date must be after start_date")
+	}
+	return nil
+}
+
+// Validates the period of an edit to the stored budget. Clients that don't send a period keep the
+// budget's own period and dates, so editing the amounts of a weekly budget doesn't turn it monthly.
+func NormalizeBudgetUpdatePeriod(update *models.UpdateBudgetRequest, stored models.StoredBudget) error {
+	if update.Period == "" {
+		update.BudgetPeriod = stored.BudgetPeriod
+	}
+	return NormalizeBudgetPeriod(&update.BudgetPeriod, &update.Year, &update.Month)
+}
+
+// Returns the window [start, end) of a budget's period that contains the date. ok is false if the
+// budget isn't active on that date.
+func PeriodWindow(period models.BudgetPeriod, date time.Time) (start time.Time, end time.Time, ok bool) {
+	anchor, err := time.Parse(time.DateOnly, period.StartDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	var until time.Time
+	if period.EndDate != "" {
+		if until, err = time.Parse(time.DateOnly, period.EndDate); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(anchor) || (!until.IsZero() && !day.Before(until)) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	switch period.Period {
+	case models.BudgetPeriodMonthly, models.BudgetPeriodCustom:
+		return anchor, until, true
+	case models.BudgetPeriodWeekly, models.BudgetPeriodBiweekly:
+		length := 7
+		if period.Period == models.BudgetPeriodBiweekly {
+			length = 14
+		}
+		elapsedDays := int(day.Sub(anchor).Hours() / 24)
+		start = anchor.AddDate(0, 0, elapsedDays/length*length)
+		end = start.AddDate(0, 0, length)
+	case models.BudgetPeriodQuarterly, models.BudgetPeriodYearly:
+		length := 3
+		if period.Period == models.BudgetPeriodYearly {
+			length = 12
+		}
+		elapsedMonths := (day.Year()-anchor.Year())*12 + int(day.Month()) - int(anchor.Month())
+		n := elapsedMonths / length
+		start = anchor.AddDate(0, n*length, 0)
+		if start.After(day) {
+			start = anchor.AddDate(0, (n-1)*length, 0)
+		}
+		end = start.AddDate(0, length, 0)
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+
+	// a recurring budget that was ended part way through a window stops at its end date
+	if !until.IsZero() && end.After(until) {
+		end = until
+	}
+	return start, end, true
+}
+
+// Picks the budgets that are active on the date. Several budgets can be active at once (e.g. a
+// monthly budget and a yearly one for insurance), but for each recurring period only the
+// definition with the latest start date applies since it replaced the earlier ones.
+func ResolveActiveBudgets(budgets []models.StoredBudget, date time.Time) []models.ActiveBudget {
+	active := []models.ActiveBudget{}
+	latestRecurring := map[string]int{}
+	for _, budget := range budgets {
+		start, end, ok := PeriodWindow(budget.BudgetPeriod, date)
+		if !ok {
+			continue
+		}
+		activeBudget := models.ActiveBudget{
+			Budget:      budget,
+			PeriodStart: start.Format(time.DateOnly),
+			PeriodEnd:   end.Format(time.DateOnly),
+		}
+		if budget.Period == models.BudgetPeriodMonthly || budget.Period == models.BudgetPeriodCustom {
+			active = append(active, activeBudget)
+			continue
+		}
+		if i, seen := latestRecurring[budget.Period]; seen {
+			if budget.StartDate > active[i].Budget.StartDate {
+				active[i] = activeBudget
+			}
+			continue
+		}
+		latestRecurring[budget.Period] = len(active)
+		active = append(active, activeBudget)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].PeriodStart < active[j].PeriodStart })
+	return active
+}
+
+func FetchActiveBudgets(userId string, date time.Time, pool *pgxpool.Pool) ([]models.ActiveBudget, error) {
+	budgets, err := db.FetchAllExistingBudgets(userId, pool)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveActiveBudgets(budgets, date), nil
+}
+
+// Compares the spending in a window against the budget lines. scale is applied to every budgeted
+// amount, which is how a longer budget is prorated into a shorter view (e.g. 1/12th of a yearly
+// budget for a single month).
+func CalculateBudgetProgress(budget models.StoredBudget, start time.Time, end time.Time, date time.Time, scale float64, spending map[string]float64) models.BudgetProgress {
+	elapsed := date.Sub(start).Hours()/24 + 1 // today counts as elapsed
+	elapsedFraction := math.Min(math.Max(elapsed/(end.Sub(start).Hours()/24), 0), 1)
+
+	spentByField := map[string]float64{}
+	for category, amount := range spending {
+		if category == "Income" {
+			continue
+		}
+		spentByField[models.BudgetFieldForCategory(category)] += amount
+	}
+
+	progress := models.BudgetProgress{
+		BudgetId:        budget.ID,
+		Period:          budget.Period,
+		PeriodStart:     start.Format(time.DateOnly),
+		PeriodEnd:       end.Format(time.DateOnly),
+		ElapsedFraction: roundCents(elapsedFraction),
+		Total:           models.BudgetLineProgress{Field: "total"},
+	}
+	for field, amount := range budget.Lines() {
+		line := lineProgress(field, amount*scale, spentByField[field], elapsedFraction)
+		progress.Lines = append(progress.Lines, line)
+		progress.Total.Budgeted += line.Budgeted
+		progress.Total.Spent += line.Spent
+	}
+	sort.Slice(progress.Lines, func(i, j int) bool { return progress.Lines[i].Field < progress.Lines[j].Field })
+	progress.Total = lineProgress("total", progress.Total.Budgeted, progress.Total.Spent, elapsedFraction)
+	return progress
+}
+
+func lineProgress(field string, budgeted float64, spent float64, elapsedFraction float64) models.BudgetLineProgress {
+	line := models.BudgetLineProgress{
+		Field:     field,
+		Budgeted:  roundCents(budgeted),
+		Prorated:  roundCents(budgeted * elapsedFraction),
+		Spent:     roundCents(spent),
+		Remaining: roundCents(budgeted - spent),
+	}
+	if budgeted > 0 {
+		line.PercentUsed = roundCents(spent / budgeted * 100)
+	}
+	return line
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Calculates a budget's progress for the window containing the date. With monthView set, budgets
+// whose period is longer than a month are prorated down to the calendar month containing the date.
+func FetchBudgetProgress(userId string, budgetId string, date time.Time, monthView bool, pool *pgxpool.Pool) (models.BudgetProgress, error) {
+	budget, err := db.FetchBudgetById(userId, budgetId, pool)
+	if err != nil {
+		return models.BudgetProgress{}, err
+	}
+
+	start, end, ok := PeriodWindow(budget.BudgetPeriod, date)
+	if !ok {
+		return models.BudgetProgress{}, fmt.Errorf("%w on %s", ErrBudgetNotActive, date.Format(time.DateOnly))
+	}
+
+	scale := 1.0
+	if monthView && (budget.Period == models.BudgetPeriodQuarterly || budget.Period == models.BudgetPeriodYearly || budget.Period == models.BudgetPeriodCustom) {
+		monthStart := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		monthEnd := monthStart.AddDate(0, 1, 0)
+		scale = monthEnd.Sub(monthStart).Hours() / end.Sub(start).Hours()
+		if scale < 1 {
+			start, end = monthStart, monthEnd
+		} else {
+			scale = 1
+		}
+	}
+
+	spending, err := db.FetchSpendingByCategory(userId, start.Unix(), end.Unix(), pool)
+	if err != nil {
+		return models.BudgetProgress{}, err
+	}
+	return CalculateBudgetProgress(budget, start, end, date, scale, spending), nil
+}
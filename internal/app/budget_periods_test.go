@@ -0,0 +1,129 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse(time.DateOnly, s)
+	return d
+}
+
+func TestPeriodWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		period models.BudgetPeriod
+		date   string
+		start  string
+		end    string
+		ok     bool
+	}{
+		{"monthly", models.MonthlyPeriod(2026, 10), "2026-10-19", "2026-10-01", "2026-11-01", true},
+		{"monthly outside", models.MonthlyPeriod(2026, 10), "2026-11-01", "", "", false},
+		{"biweekly anchored to pay date", models.BudgetPeriod{Period: "biweekly", StartDate: "2026-01-02"}, "2026-01-30", "2026-01-30", "2026-02-13", true},
+		{"biweekly before anchor", models.BudgetPeriod{Period: "biweekly", StartDate: "2026-01-02"}, "2026-01-01", "", "", false},
+		{"weekly", models.BudgetPeriod{Period: "weekly", StartDate: "2026-10-05"}, "2026-10-19", "2026-10-19", "2026-10-26", true},
+		{"quarterly", models.BudgetPeriod{Period: "quarterly", StartDate: "2026-01-15"}, "2026-07-14", "2026-04-15", "2026-07-15", true},
+		{"yearly", models.BudgetPeriod{Period: "yearly", StartDate: "2025-03-01"}, "2026-10-19", "2026-03-01", "2027-03-01", true},
+		{"yearly ended early", models.BudgetPeriod{Period: "yearly", StartDate: "2025-03-01", EndDate: "2026-12-01"}, "2026-10-19", "2026-03-01", "2026-12-01", true},
+		{"custom", models.BudgetPeriod{Period: "custom", StartDate: "2026-06-10", EndDate: "2026-06-24"}, "2026-06-23", "2026-06-10", "2026-06-24", true},
+	}
+
+	for _, tt := range tests {
+		start, end, ok := PeriodWindow(tt.period, date(tt.date))
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok to be %v", tt.name, tt.ok)
+			continue
+		}
+		if ok && (start.Format(time.DateOnly) != tt.start || end.Format(time.DateOnly) != tt.end) {
+			t.Errorf("%s: expected %s - %s, got %s - %s", tt.name, tt.start, tt.end, start.Format(time.DateOnly), end.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestResolveActiveBudgetsUsesLatestRecurringDefinition(t *testing.T) {
+	budgets := []models.StoredBudget{
+		{ID: "old-weekly", BudgetPeriod: models.BudgetPeriod{Period: "weekly", StartDate: "2026-01-05"}},
+		{ID: "new-weekly", BudgetPeriod: models.BudgetPeriod{Period: "weekly", StartDate: "2026-09-07"}},
+		{ID: "october", BudgetPeriod: models.MonthlyPeriod(2026, 10)},
+		{ID: "september", BudgetPeriod: models.MonthlyPeriod(2026, 9)},
+	}
+
+	active := ResolveActiveBudgets(budgets, date("2026-10-19"))
+	if len(active) != 2 {
+		t.Fatalf("Expected 2 active budgets, got %d: %+v", len(active), active)
+	}
+	ids := map[string]bool{active[0].Budget.ID: true, active[1].Budget.ID: true}
+	if !ids["new-weekly"] || !ids["october"] {
+		t.Errorf("Expected new-weekly and october to be active, got %+v", ids)
+	}
+}
+
+func TestNormalizeBudgetUpdatePeriodKeepsStoredPeriod(t *testing.T) {
+	stored := models.StoredBudget{ID: "groceries", Year: 2026, Month: 10, BudgetPeriod: models.BudgetPeriod{Period: "weekly", StartDate: "2026-10-05", EndDate: "2026-12-28"}}
+
+	// today's clients only send the amounts along with the year and month
+	update := models.UpdateBudgetRequest{Id: "groceries", Year: 2026, Month: 10, Groceries: 150}
+	if err := NormalizeBudgetUpdatePeriod(&update, stored); err != nil {
+		t.Fatalf("Expected the edit to be valid, got %v", err)
+	}
+	if update.BudgetPeriod != stored.BudgetPeriod {
+		t.Errorf("Expected the budget to stay %+v, got %+v", stored.BudgetPeriod, update.BudgetPeriod)
+	}
+
+	update = models.UpdateBudgetRequest{Id: "groceries", Year: 2026, Month: 11, BudgetPeriod: models.BudgetPeriod{Period: "monthly"}}
+	if err := NormalizeBudgetUpdatePeriod(&update, stored); err != nil {
+		t.Fatalf("Expected the edit to be valid, got %v", err)
+	}
+	if update.BudgetPeriod != models.MonthlyPeriod(2026, 11) {
+		t.Errorf("Expected an explicit period to replace the stored one, got %+v", update.BudgetPeriod)
+	}
+}
+
+func TestCalculateBudgetProgressProratesYearlyBudget(t *testing.T) {
+	budget := models.StoredBudget{ID: "insurance", Insurance: 1200, BudgetPeriod: models.BudgetPeriod{Period: "yearly", StartDate: "2026-01-01"}}
+
+	// prorated down to a 30 day month of a 365 day year
+	scale := 30.0 / 365.0
+	progress := CalculateBudgetProgress(budget, date("2026-09-01"), date("2026-10-01"), date("2026-09-15"), scale, map[string]float64{"Insurance": 50})
+
+	var insurance models.BudgetLineProgress
+	for _, line := range progress.Lines {
+		if line.Field == "insurance" {
+			insurance = line
+		}
+	}
+	if insurance.Budgeted != 98.63 {
+		t.Errorf("Expected prorated budget of 98.63, got %.2f", insurance.Budgeted)
+	}
+	if insurance.Prorated != 49.32 {
+		t.Errorf("Expected 49.32 to be available so far, got %.2f", insurance.Prorated)
+	}
+	if progress.Total.Spent != 50 {
+		t.Errorf("Expected total spent of 50, got %.2f", progress.Total.Spent)
+	}
+}
+
+func TestCrossedThresholds(t *testing.T) {
+	progress := models.BudgetProgress{
+		Lines: []models.BudgetLineProgress{
+			{Field: "groceries", Budgeted: 400, Spent: 330, PercentUsed: 82.5},
+			{Field: "travel", Budgeted: 0, Spent: 50},
+		},
+		Total: models.BudgetLineProgress{Field: "total", Budgeted: 400, Spent: 380, PercentUsed: 95},
+	}
+	thresholds := []models.BudgetAlertThreshold{
+		{Field: "groceries", Percent: 80},
+		{Field: "groceries", Percent: 100},
+		{Field: "travel", Percent: 80},
+		{Field: "total", Percent: 90},
+	}
+
+	crossed := CrossedThresholds(progress, thresholds)
+	if len(crossed) != 2 || crossed[0] != thresholds[0] || crossed[1] != thresholds[3] {
+		t.Errorf("Expected groceries 80%% and total 90%% to be crossed, got %+v", crossed)
+	}
+}
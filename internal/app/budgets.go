@@ -0,0 +1,128 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns the unix timestamps for the start of the month and the start of the following month
+func MonthRange(year int, month int) (int64, int64) {
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return start.Unix(), start.AddDate(0, 1, 0).Unix()
+}
+
+func PreviousMonth(year int, month int) (int, int) {
+	prev := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	return prev.Year(), int(prev.Month())
+}
+
+// Averages a list of monthly category spending totals into budget lines. Months without any
+// spending are skipped so a new user's empty history doesn't drag the averages down.
+// Amounts are rounded up to the nearest dollar.
+func SuggestBudgetLines(monthlySpending []map[string]float64) models.BudgetLines {
+	sums := models.BudgetLines{}
+	monthsWithData := 0
+	for _, spending := range monthlySpending {
+		if len(spending) == 0 {
+			continue
+		}
+		monthsWithData++
+		for category, amount := range spending {
+			if category == "Income" {
+				continue
+			}
+			sums[models.BudgetFieldForCategory(category)] += amount
+		}
+	}
+
+	lines := models.BudgetLines{}
+	if monthsWithData == 0 {
+		return lines
+	}
+	for field, sum := range sums {
+		lines[field] = math.Ceil(sum / float64(monthsWithData))
+	}
+	return lines
+}
+
+// Proposes a budget for the given month from the average spending of the N months before it
+func SuggestBudget(userId string, year int, month int, months int, pool *pgxpool.Pool) (models.BudgetRequest, error) {
+	var monthlySpending []map[string]float64
+	y, m := year, month
+	for i := 0; i < months; i++ {
+		y, m = PreviousMonth(y, m)
+		start, end := MonthRange(y, m)
+		spending, err := db.FetchSpendingByCategory(userId, start, end, pool)
+		if err != nil {
+			return models.BudgetRequest{}, err
+		}
+		monthlySpending = append(monthlySpending, spending)
+	}
+	return SuggestBudgetLines(monthlySpending).ToBudgetRequest(userId, year, month), nil
+}
+
+// Builds the budget a user's auto create settings describe for the given month
+func BudgetFromAutoCreateSettings(settings models.BudgetAutoCreateSettings, year int, month int, pool *pgxpool.Pool) (models.BudgetRequest, error) {
+	switch settings.Source {
+	case "template":
+		template, err := db.FetchBudgetTemplate(settings.UserId, settings.TemplateId, pool)
+		if err != nil {
+			return models.BudgetRequest{}, fmt.Errorf("failed to fetch template %s: %w", settings.TemplateId, err)
+		}
+		return template.Lines().ToBudgetRequest(settings.UserId, year, month), nil
+	default:
+		prevYear, prevMonth := PreviousMonth(year, month)
+		previous, err := db.FetchExistingBudget(models.BudgetRequest{UserId: settings.UserId, Year: prevYear, Month: prevMonth}, pool)
+		if err != nil {
+			return models.BudgetRequest{}, fmt.Errorf("failed to fetch budget for %d/%d: %w", prevMonth, prevYear, err)
+		}
+		return previous.Lines().ToBudgetRequest(settings.UserId, year, month), nil
+	}
+}
+
+// Creates the current month's budget for every user that opted in and doesn't have one yet
+func AutoCreateBudgets(now time.Time, pool *pgxpool.Pool) {
+	allSettings, err := db.FetchEnabledBudgetAutoCreateSettings(pool)
+	if err != nil {
+		log.Printf("Failed to fetch budget auto create settings: %v\n", err)
+		return
+	}
+
+	year, month := now.Year(), int(now.Month())
+	for _, settings := range allSettings {
+		_, err := db.FetchExistingBudget(models.BudgetRequest{UserId: settings.UserId, Year: year, Month: month}, pool)
+		if err == nil {
+			continue // already has a budget for this month
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Skipping budget auto creation for user %s, couldn't check for an existing budget: %v\n", settings.UserId, err)
+			continue
+		}
+		budget, err := BudgetFromAutoCreateSettings(settings, year, month, pool)
+		if err != nil {
+			log.Printf("Skipping budget auto creation for user %s: %v\n", settings.UserId, err)
+			continue
+		}
+		if err := db.InsertNewBudget(budget, pool); err != nil {
+			log.Printf("Failed to auto create budget for user %s: %v\n", settings.UserId, err)
+		}
+	}
+}
+
+// Runs AutoCreateBudgets once on startup and then on every tick of the interval. Meant to be started
+// in its own goroutine from main
+func RunBudgetAutoCreate(interval time.Duration, pool *pgxpool.Pool) {
+	AutoCreateBudgets(time.Now().UTC(), pool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		AutoCreateBudgets(now.UTC(), pool)
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import "testing"
+
+func TestSuggestBudgetLines(t *testing.T) {
+	monthlySpending := []map[string]float64{
+		{"Groceries": 400, "Food & Dining": 120.5, "Income": 3000},
+		{},
+		{"Groceries": 300, "Rent": 1500, "Something Custom": 10},
+	}
+
+	lines := SuggestBudgetLines(monthlySpending)
+
+	// the empty month is skipped, so everything is averaged over 2 months
+	expected := map[string]float64{"groceries": 350, "food": 61, "housing": 750, "other": 5}
+	for field, amount := range expected {
+		if lines[field] != amount {
+			t.Errorf("Expected %s to be %.2f, got %.2f", field, amount, lines[field])
+		}
+	}
+	if len(lines) != len(expected) {
+		t.Errorf("Expected %d lines, got %d: %+v", len(expected), len(lines), lines)
+	}
+}
+
+func TestPreviousMonth(t *testing.T) {
+	year, month := PreviousMonth(2026, 1)
+	if year != 2025 || month != 12 {
+		t.Errorf("Expected 12/2025, got %d/%d", month, year)
+	}
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func FetchCashFlowStatement(userId string, year int, pool *pgxpool.Pool) (models.CashFlowStatement, error) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	totals, err := db.FetchCashFlowTotals(userId, start.Unix(), start.AddDate(1, 0, 0).Unix(), pool)
+	if err != nil {
+		return models.CashFlowStatement{}, err
+	}
+	return BuildCashFlowStatement(totals, year), nil
+}
+
+// Sorts the lines biggest first and drops the ones that net out to nothing
+func cashFlowLines(amounts map[string]float64) []models.CashFlowLine {
+	lines := []models.CashFlowLine{}
+	for name, amount := range amounts {
+		if amount = roundCents(amount); amount != 0 {
+			lines = append(lines, models.CashFlowLine{Name: name, Amount: amount})
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Amount != lines[j].Amount {
+			return lines[i].Amount > lines[j].Amount
+		}
+		return lines[i].Name < lines[j].Name
+	})
+	return lines
+}
+
+// Builds the statement for all twelve months of the year, with the Sankey covering the whole year
+func BuildCashFlowStatement(totals []models.CashFlowTotal, year int) models.CashFlowStatement {
+	type monthAmounts struct{ income, expenses map[string]float64 }
+	months := map[string]monthAmounts{}
+	yearIncome, yearExpenses := map[string]float64{}, map[string]float64{}
+	for _, total := range totals {
+		amounts, ok := months[total.Month]
+		if !ok {
+			amounts = monthAmounts{income: map[string]float64{}, expenses: map[string]float64{}}
+			months[total.Month] = amounts
+		}
+		if total.IsIncome {
+			amounts.income[total.Name] += total.Amount
+			yearIncome[total.Name] += total.Amount
+		} else {
+			amounts.expenses[total.Name] += total.Amount
+			yearExpenses[total.Name] += total.Amount
+		}
+	}
+
+	statement := models.CashFlowStatement{Year: year, Months: []models.CashFlowMonth{}}
+	for month := time.January; month <= time.December; month++ {
+		key := fmt.Sprintf("%04d-%02d", year, int(month))
+		amounts := months[key]
+		cashFlowMonth := models.CashFlowMonth{
+			Month:         key,
+			IncomeSources: cashFlowLines(amounts.income),
+			Categories:    cashFlowLines(amounts.expenses),
+		}
+		for _, line := range cashFlowMonth.IncomeSources {
+			cashFlowMonth.Income += line.Amount
+		}
+		for _, line := range cashFlowMonth.Categories {
+			cashFlowMonth.Expenses += line.Amount
+		}
+		cashFlowMonth.Income = roundCents(cashFlowMonth.Income)
+		cashFlowMonth.Expenses = roundCents(cashFlowMonth.Expenses)
+		cashFlowMonth.NetSavings = roundCents(cashFlowMonth.Income - cashFlowMonth.Expenses)
+		cashFlowMonth.SavingsRate = savingsRate(cashFlowMonth.Income, cashFlowMonth.NetSavings)
+
+		statement.Income += cashFlowMonth.Income
+		statement.Expenses += cashFlowMonth.Expenses
+		statement.Months = append(statement.Months, cashFlowMonth)
+	}
+	statement.Income = roundCents(statement.Income)
+	statement.Expenses = roundCents(statement.Expenses)
+	statement.NetSavings = roundCents(statement.Income - statement.Expenses)
+	statement.SavingsRate = savingsRate(statement.Income, statement.NetSavings)
+	statement.Sankey = buildCashFlowSankey(cashFlowLines(yearIncome), cashFlowLines(yearExpenses), statement.NetSavings)
+	return statement
+}
+
+func savingsRate(income float64, net float64) *float64 {
+	if income <= 0 {
+		return nil
+	}
+	rate := roundCents(net / income * 100)
+	return &rate
+}
+
+func buildCashFlowSankey(sources []models.CashFlowLine, categories []models.CashFlowLine, net float64) models.Sankey {
+	sankey := models.Sankey{
+		Nodes: []models.SankeyNode{{ID: "income", Label: models.IncomeCategory, Kind: "income"}},
+		Links: []models.SankeyLink{},
+	}
+	for _, source := range sources {
+		// a source that paid back more than it paid out has nothing to flow
+		if source.Amount <= 0 {
+			continue
+		}
+		id := "source:" + source.Name
+		sankey.Nodes = append(sankey.Nodes, models.SankeyNode{ID: id, Label: source.Name, Kind: "source"})
+		sankey.Links = append(sankey.Links, models.SankeyLink{Source: id, Target: "income", Value: source.Amount})
+	}
+	if net < 0 {
+		sankey.Nodes = append(sankey.Nodes, models.SankeyNode{ID: "shortfall", Label: "Shortfall", Kind: "shortfall"})
+		sankey.Links = append(sankey.Links, models.SankeyLink{Source: "shortfall", Target: "income", Value: -net})
+	}
+	for _, category := range categories {
+		if category.Amount <= 0 {
+			continue
+		}
+		id := "category:" + category.Name
+		sankey.Nodes = append(sankey.Nodes, models.SankeyNode{ID: id, Label: category.Name, Kind: "category"})
+		sankey.Links = append(sankey.Links, models.SankeyLink{Source: "income", Target: id, Value: category.Amount})
+	}
+	if net > 0 {
+		sankey.Nodes = append(sankey.Nodes, models.SankeyNode{ID: "savings", Label: "Savings", Kind: "savings"})
+		sankey.Links = append(sankey.Links, models.SankeyLink{Source: "income", Target: "savings", Value: net})
+	}
+	return sankey
+}
+
+// Writes the statement as one row per month with the expense categories of the year as extra
+// columns, followed by a total row
+func WriteCashFlowCSV(w io.Writer, statement models.CashFlowStatement) error {
+	categorySet := map[string]bool{}
+	for _, month := range statement.Months {
+		for _, line := range month.Categories {
+			categorySet[line.Name] = true
+		}
+	}
+	categories := make([]string, 0, len(categorySet))
+	for category := range categorySet {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	money := func(amount float64) string {
+		return strconv.FormatFloat(amount, 'f', 2, 64)
+	}
+	rate := func(rate *float64) string {
+		if rate == nil {
+			return ""
+		}
+		return money(*rate)
+	}
+
+	writer := csv.NewWriter(w)
+	header := append([]string{"Month", "Income", "Expenses", "Net Savings", "Savings Rate %"}, categories...)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	totals := make([]float64, len(categories))
+	for _, month := range statement.Months {
+		row := []string{month.Month, money(month.Income), money(month.Expenses), money(month.NetSavings), rate(month.SavingsRate)}
+		amounts := map[string]float64{}
+		for _, line := range month.Categories {
+			amounts[line.Name] = line.Amount
+		}
+		for i, category := range categories {
+			row = append(row, money(amounts[category]))
+			totals[i] += amounts[category]
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	row := []string{"Total", money(statement.Income), money(statement.Expenses), money(statement.NetSavings), rate(statement.SavingsRate)}
+	for _, total := range totals {
+		row = append(row, money(total))
+	}
+	if err := writer.Write(row); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
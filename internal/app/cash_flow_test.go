@@ -0,0 +1,79 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestBuildCashFlowStatement(t *testing.T) {
+	totals := []models.CashFlowTotal{
+		{Month: "2024-01", IsIncome: true, Name: "Acme Payroll", Amount: 4000},
+		{Month: "2024-01", Name: "Rent", Amount: 1800},
+		{Month: "2024-01", Name: "Groceries", Amount: 450.5},
+		{Month: "2024-02", IsIncome: true, Name: "Acme Payroll", Amount: 4000},
+		{Month: "2024-02", IsIncome: true, Name: "Etsy", Amount: 250},
+		{Month: "2024-02", Name: "Rent", Amount: 1800},
+		{Month: "2024-02", Name: "Travel", Amount: 3000},
+		// a refund bigger than the month's spending in the category
+		{Month: "2024-03", Name: "Shopping", Amount: -40},
+	}
+	statement := BuildCashFlowStatement(totals, 2024)
+
+	if len(statement.Months) != 12 {
+		t.Fatalf("expected 12 months, got %d", len(statement.Months))
+	}
+	jan := statement.Months[0]
+	if jan.Income != 4000 || jan.Expenses != 2250.5 || jan.NetSavings != 1749.5 || *jan.SavingsRate != 43.74 {
+		t.Errorf("unexpected january %+v", jan)
+	}
+	feb := statement.Months[1]
+	if feb.NetSavings != -550 || feb.IncomeSources[0].Name != "Acme Payroll" || feb.Categories[0].Name != "Travel" {
+		t.Errorf("unexpected february %+v", feb)
+	}
+	if mar := statement.Months[2]; mar.Expenses != -40 || mar.SavingsRate != nil {
+		t.Errorf("unexpected march %+v", mar)
+	}
+
+	if statement.Income != 8250 || statement.Expenses != 7010.5 || statement.NetSavings != 1239.5 {
+		t.Errorf("unexpected totals %+v", statement)
+	}
+
+	links := map[string]float64{}
+	for _, link := range statement.Sankey.Links {
+		links[link.Source+">"+link.Target] = link.Value
+	}
+	if links["source:Acme Payroll>income"] != 8000 || links["income>category:Rent"] != 3600 || links["income>savings"] != 1239.5 {
+		t.Errorf("unexpected sankey links %v", links)
+	}
+	if _, ok := links["income>category:Shopping"]; ok {
+		t.Errorf("a net refund should not flow out of income")
+	}
+}
+
+func TestWriteCashFlowCSV(t *testing.T) {
+	statement := BuildCashFlowStatement([]models.CashFlowTotal{
+		{Month: "2024-01", IsIncome: true, Name: "Acme Payroll", Amount: 1000},
+		{Month: "2024-01", Name: "Rent", Amount: 600},
+		{Month: "2024-02", Name: "Groceries", Amount: 50.25},
+	}, 2024)
+
+	var out strings.Builder
+	if err := WriteCashFlowCSV(&out, statement); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 14 {
+		t.Fatalf("expected header, 12 months and a total, got %d lines", len(lines))
+	}
+	if lines[0] != "Month,Income,Expenses,Net Savings,Savings Rate %,Groceries,Rent" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if lines[1] != "2024-01,1000.00,600.00,400.00,40.00,0.00,600.00" {
+		t.Errorf("unexpected january row %q", lines[1])
+	}
+	if lines[13] != "Total,1000.00,650.25,349.75,34.98,50.25,600.00" {
+		t.Errorf("unexpected total row %q", lines[13])
+	}
+}
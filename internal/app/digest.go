@@ -0,0 +1,316 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/notify"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// A month without income or spending has nothing to summarize
+var ErrNoDigestActivity = errors.New("no activity in the month")
+
+// How many categories, changes and unusual charges a digest mentions
+const digestListLength = 3
+
+const digestSystemPrompt = `You write a short monthly money summary for the user of a budgeting app, addressed to them as "you".
+You get the month's numbers as JSON. Cover how much came in and went out, how the budgets did, the biggest changes in spending, new subscriptions and unusual charges, skipping anything that is empty.
+Use only the numbers given, don't calculate new ones or give financial advice. Write at most 150 words of plain text, no markdown.`
+
+// Recurring expenses that were first detected in the month: present when detecting up to the end
+// of the month but not up to its start
+func NewRecurringExpenses(txns []models.Transaction, monthStart time.Time, monthEnd time.Time) []models.RecurringSeries {
+	before := func(t time.Time) []models.Transaction {
+		var filtered []models.Transaction
+		for _, txn := range txns {
+			if TransactionTime(txn).Before(t) {
+				filtered = append(filtered, txn)
+			}
+		}
+		return filtered
+	}
+
+	known := map[string]bool{}
+	for _, s := range DetectRecurring(before(monthStart), monthStart) {
+		known[s.ID] = true
+	}
+	var added []models.RecurringSeries
+	for _, s := range DetectRecurring(before(monthEnd), monthEnd) {
+		if !s.IsIncome && !known[s.ID] {
+			added = append(added, s)
+		}
+	}
+	return added
+}
+
+// Gathers the numbers for the user's digest of the month starting at monthStart
+func FetchMonthlyDigestFacts(userId string, monthStart time.Time, pool *pgxpool.Pool) (models.MonthlyDigestFacts, error) {
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	lastDay := monthEnd.AddDate(0, 0, -1)
+	facts := models.MonthlyDigestFacts{
+		Month:            monthStart.Format("2006-01"),
+		TopCategories:    []models.CashFlowLine{},
+		Budgets:          []models.DigestBudget{},
+		BiggestChanges:   []models.SpendingMover{},
+		NewSubscriptions: []models.DigestSubscription{},
+		UnusualCharges:   []models.Anomaly{},
+	}
+
+	totals, err := db.FetchCashFlowTotals(userId, monthStart.Unix(), monthEnd.Unix(), pool)
+	if err != nil {
+		return facts, err
+	}
+	cashFlow := BuildCashFlowStatement(totals, monthStart.Year()).Months[monthStart.Month()-1]
+	facts.Income, facts.Expenses, facts.NetSavings, facts.SavingsRate = cashFlow.Income, cashFlow.Expenses, cashFlow.NetSavings, cashFlow.SavingsRate
+	for _, line := range cashFlow.Categories {
+		if len(facts.TopCategories) < digestListLength && line.Amount > 0 {
+			facts.TopCategories = append(facts.TopCategories, line)
+		}
+	}
+
+	activeBudgets, err := FetchActiveBudgets(userId, lastDay, pool)
+	if err != nil {
+		return facts, err
+	}
+	for _, active := range activeBudgets {
+		// budgets shorter than a month don't say much about the month as a whole
+		if active.Budget.Period == models.BudgetPeriodWeekly || active.Budget.Period == models.BudgetPeriodBiweekly {
+			continue
+		}
+		progress, err := FetchBudgetProgress(userId, active.Budget.ID, lastDay, true, pool)
+		if err != nil {
+			return facts, err
+		}
+		budget := models.DigestBudget{
+			BudgetId:    active.Budget.ID,
+			Period:      active.Budget.Period,
+			Budgeted:    progress.Total.Budgeted,
+			Spent:       progress.Total.Spent,
+			PercentUsed: progress.Total.PercentUsed,
+			OverBudget:  []models.BudgetLineProgress{},
+		}
+		for _, line := range progress.Lines {
+			if line.Budgeted > 0 && line.Spent > line.Budgeted {
+				budget.OverBudget = append(budget.OverBudget, line)
+			}
+		}
+		facts.Budgets = append(facts.Budgets, budget)
+	}
+
+	report, err := FetchSpendingReport(userId, monthStart, lastDay, models.ReportGroupByCategory, models.ReportIntervalMonth, pool)
+	if err != nil {
+		return facts, err
+	}
+	for _, mover := range report.TopMovers {
+		if len(facts.BiggestChanges) < digestListLength {
+			facts.BiggestChanges = append(facts.BiggestChanges, mover)
+		}
+	}
+
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return facts, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return facts, err
+	}
+	txns, err := db.FetchAllTransactions(accounts, pool)
+	if err != nil {
+		return facts, err
+	}
+	for _, s := range NewRecurringExpenses(txns, monthStart, monthEnd) {
+		facts.NewSubscriptions = append(facts.NewSubscriptions, models.DigestSubscription{Payee: s.Payee, Amount: roundCents(-s.LastAmount), Cadence: s.Cadence})
+	}
+
+	anomalies, err := db.FetchAnomalies(userId, true, pool)
+	if err != nil {
+		return facts, err
+	}
+	for _, anomaly := range anomalies {
+		if strings.HasPrefix(anomaly.CreatedAt, facts.Month) && len(facts.UnusualCharges) < digestListLength {
+			facts.UnusualCharges = append(facts.UnusualCharges, anomaly)
+		}
+	}
+	return facts, nil
+}
+
+func formatDigestMonth(month string) string {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+	return t.Format("January 2006")
+}
+
+// Writes the digest without a language model. The same facts always give the same text
+func DigestTemplate(facts models.MonthlyDigestFacts) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "In %s you brought in $%.2f and spent $%.2f", formatDigestMonth(facts.Month), facts.Income, facts.Expenses)
+	if facts.NetSavings >= 0 {
+		fmt.Fprintf(&b, ", saving $%.2f", facts.NetSavings)
+	} else {
+		fmt.Fprintf(&b, ", $%.2f more than you earned", -facts.NetSavings)
+	}
+	if facts.SavingsRate != nil && *facts.SavingsRate > 0 {
+		fmt.Fprintf(&b, " (%.1f%% of your income)", *facts.SavingsRate)
+	}
+	b.WriteString(".")
+
+	if len(facts.TopCategories) > 0 {
+		parts := make([]string, len(facts.TopCategories))
+		for i, line := range facts.TopCategories {
+			parts[i] = fmt.Sprintf("%s ($%.2f)", line.Name, line.Amount)
+		}
+		fmt.Fprintf(&b, " Most of it went to %s.", strings.Join(parts, ", "))
+	}
+
+	for _, budget := range facts.Budgets {
+		fmt.Fprintf(&b, " You used %.0f%% of your %s budget", budget.PercentUsed, budget.Period)
+		if len(budget.OverBudget) > 0 {
+			over := make([]string, len(budget.OverBudget))
+			for i, line := range budget.OverBudget {
+				over[i] = fmt.Sprintf("%s by $%.2f", line.Field, line.Spent-line.Budgeted)
+			}
+			fmt.Fprintf(&b, " and went over on %s", strings.Join(over, ", "))
+		}
+		b.WriteString(".")
+	}
+
+	if len(facts.BiggestChanges) > 0 {
+		parts := make([]string, len(facts.BiggestChanges))
+		for i, mover := range facts.BiggestChanges {
+			direction := "up"
+			if mover.Change < 0 {
+				direction = "down"
+			}
+			amount := mover.Change
+			if amount < 0 {
+				amount = -amount
+			}
+			parts[i] = fmt.Sprintf("%s %s $%.2f", mover.Key, direction, amount)
+		}
+		fmt.Fprintf(&b, " Compared to the month before: %s.", strings.Join(parts, ", "))
+	}
+
+	if len(facts.NewSubscriptions) > 0 {
+		parts := make([]string, len(facts.NewSubscriptions))
+		for i, s := range facts.NewSubscriptions {
+			parts[i] = fmt.Sprintf("%s ($%.2f %s)", s.Payee, s.Amount, s.Cadence)
+		}
+		fmt.Fprintf(&b, " New recurring charges: %s.", strings.Join(parts, ", "))
+	}
+
+	if len(facts.UnusualCharges) > 0 {
+		reasons := make([]string, len(facts.UnusualCharges))
+		for i, anomaly := range facts.UnusualCharges {
+			reasons[i] = anomaly.Reason
+		}
+		fmt.Fprintf(&b, " Worth a look: %s.", strings.Join(reasons, "; "))
+	}
+	return b.String()
+}
+
+// Has the model write the digest from the facts. Without a model, or when it fails, the template
+// writes it instead
+func WriteDigestSummary(ctx context.Context, model ChatModel, facts models.MonthlyDigestFacts) (string, string) {
+	if model != nil {
+		data, err := json.Marshal(facts)
+		if err == nil {
+			summary, err := model.Complete(ctx, digestSystemPrompt, string(data))
+			if summary = strings.TrimSpace(summary); err == nil && summary != "" {
+				return summary, models.DigestGeneratedByLLM
+			}
+			log.Printf("Failed to write digest for %s with the language model, using the template: %v\n", facts.Month, err)
+		}
+	}
+	return DigestTemplate(facts), models.DigestGeneratedByTemplate
+}
+
+// Writes and stores the user's digest for the month starting at monthStart, unless one is stored
+// already. The bool reports whether a new digest was written. Months without any activity return
+// ErrNoDigestActivity
+func GenerateMonthlyDigest(ctx context.Context, model ChatModel, userId string, monthStart time.Time, pool *pgxpool.Pool) (models.MonthlyDigest, bool, error) {
+	month := monthStart.Format("2006-01")
+	if existing, err := db.FetchMonthlyDigest(userId, month, pool); err == nil {
+		return existing, false, nil
+	}
+
+	facts, err := FetchMonthlyDigestFacts(userId, monthStart, pool)
+	if err != nil {
+		return models.MonthlyDigest{}, false, err
+	}
+	if facts.Income == 0 && facts.Expenses == 0 {
+		return models.MonthlyDigest{}, false, ErrNoDigestActivity
+	}
+	summary, generatedBy := WriteDigestSummary(ctx, model, facts)
+	return db.InsertMonthlyDigest(models.MonthlyDigest{UserId: userId, Month: month, Summary: summary, GeneratedBy: generatedBy, Facts: facts}, pool)
+}
+
+// Writes last month's digest for every user that doesn't have it yet and sends it to them
+func SendMonthlyDigests(now time.Time, dispatcher *notify.Dispatcher, pool *pgxpool.Pool) {
+	userIds, err := db.FetchUserIdsWithAccounts(pool)
+	if err != nil {
+		log.Printf("Failed to fetch users for monthly digests: %v\n", err)
+		return
+	}
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	model := NewChatModelFromEnv()
+
+	sort.Strings(userIds)
+	for _, userId := range userIds {
+		digest, isNew, err := GenerateMonthlyDigest(context.Background(), model, userId, monthStart, pool)
+		if errors.Is(err, ErrNoDigestActivity) {
+			continue
+		} else if err != nil {
+			log.Printf("Failed to generate monthly digest for user %s: %v\n", userId, err)
+			continue
+		}
+		if !isNew {
+			continue
+		}
+		err = dispatcher.Send(context.Background(), models.Notification{
+			UserId: userId,
+			Kind:   "monthly_digest",
+			Title:  "Your " + formatDigestMonth(digest.Month) + " summary",
+			Body:   digest.Summary,
+			Data:   map[string]string{"digest_id": digest.ID, "month": digest.Month},
+		})
+		if err != nil {
+			log.Printf("Failed to send monthly digest to user %s: %v\n", userId, err)
+		}
+	}
+}
+
+// Runs SendMonthlyDigests once on startup and then on every tick of the interval. Meant to be
+// started in its own goroutine from main
+func RunMonthlyDigests(interval time.Duration, pool *pgxpool.Pool) {
+	dispatcher := notify.NewDispatcher(pool)
+	SendMonthlyDigests(time.Now().UTC(), dispatcher, pool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		SendMonthlyDigests(now.UTC(), dispatcher, pool)
+	}
+}
+
+// Delivers the emails and webhooks held back by quiet hours every tick of the interval. Meant to be
+// started in its own goroutine from main
+func RunDeferredNotifications(interval time.Duration, pool *pgxpool.Pool) {
+	dispatcher := notify.NewDispatcher(pool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		dispatcher.SendDeferred(context.Background(), now.UTC())
+	}
+}
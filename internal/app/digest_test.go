@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+type failingChatModel struct{}
+
+func (failingChatModel) Complete(ctx context.Context, system string, user string) (string, error) {
+	return "", errors.New("model unavailable")
+}
+
+func digestFacts() models.MonthlyDigestFacts {
+	rate := 20.0
+	return models.MonthlyDigestFacts{
+		Month:         "2026-09",
+		Income:        5000,
+		Expenses:      4000,
+		NetSavings:    1000,
+		SavingsRate:   &rate,
+		TopCategories: []models.CashFlowLine{{Name: "Rent", Amount: 1800}, {Name: "Groceries", Amount: 650.4}},
+		Budgets: []models.DigestBudget{{
+			Period: "monthly", Budgeted: 3800, Spent: 4000, PercentUsed: 105.3,
+			OverBudget: []models.BudgetLineProgress{{Field: "food", Budgeted: 300, Spent: 412.5}},
+		}},
+		BiggestChanges:   []models.SpendingMover{{Key: "Travel", Change: -350}},
+		NewSubscriptions: []models.DigestSubscription{{Payee: "Netflix", Amount: 17.99, Cadence: "monthly"}},
+		UnusualCharges:   []models.Anomaly{{Reason: "Spotify charged $11.99 twice within 10 minutes"}},
+	}
+}
+
+func TestDigestTemplate(t *testing.T) {
+	want := "In September 2026 you brought in $5000.00 and spent $4000.00, saving $1000.00 (20.0% of your income)." +
+		" Most of it went to Rent ($1800.00), Groceries ($650.40)." +
+		" You used 105% of your monthly budget and went over on food by $112.50." +
+		" Compared to the month before: Travel down $350.00." +
+		" New recurring charges: Netflix ($17.99 monthly)." +
+		" Worth a look: Spotify charged $11.99 twice within 10 minutes."
+	if got := DigestTemplate(digestFacts()); got != want {
+		t.Errorf("template =\n%s\nwant\n%s", got, want)
+	}
+
+	quiet := models.MonthlyDigestFacts{Month: "2026-09", Income: 100, Expenses: 150, NetSavings: -50}
+	if got := DigestTemplate(quiet); got != "In September 2026 you brought in $100.00 and spent $150.00, $50.00 more than you earned." {
+		t.Errorf("unexpected quiet month template %q", got)
+	}
+}
+
+func TestWriteDigestSummary(t *testing.T) {
+	model := &stubChatModel{reply: "  You saved $1000.00 in September.  "}
+	summary, generatedBy := WriteDigestSummary(context.Background(), model, digestFacts())
+	if summary != "You saved $1000.00 in September." || generatedBy != models.DigestGeneratedByLLM {
+		t.Errorf("unexpected summary %q by %s", summary, generatedBy)
+	}
+	if model.user == "" || model.system != digestSystemPrompt {
+		t.Errorf("expected the facts to be sent to the model")
+	}
+
+	for name, model := range map[string]ChatModel{"no model": nil, "failing model": failingChatModel{}} {
+		summary, generatedBy := WriteDigestSummary(context.Background(), model, digestFacts())
+		if generatedBy != models.DigestGeneratedByTemplate || summary != DigestTemplate(digestFacts()) {
+			t.Errorf("%s: expected the template, got %q by %s", name, summary, generatedBy)
+		}
+	}
+}
+
+func TestNewRecurringExpenses(t *testing.T) {
+	var txns []models.Transaction
+	for month := 6; month <= 9; month++ {
+		txns = append(txns, txnOn(fmt.Sprintf("2026-%02d-03", month), "NETFLIX.COM", "-17.99"))
+	}
+	for month := 7; month <= 9; month++ {
+		txns = append(txns, txnOn(fmt.Sprintf("2026-%02d-12", month), "PLANET FITNESS", "-24.99"))
+	}
+
+	added := NewRecurringExpenses(txns, date("2026-09-01"), date("2026-10-01"))
+	if len(added) != 1 || added[0].NormalizedPayee != "planet fitness" {
+		t.Errorf("expected only the gym to be new, got %+v", added)
+	}
+}
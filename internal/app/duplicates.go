@@ -0,0 +1,192 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// How far apart two copies of the same charge can be dated. Re-issued transactions sometimes
+// switch from the transaction date to the posting date
+const duplicateWindow = 3 * 24 * time.Hour
+
+// How far back suspected duplicates are looked for
+const duplicateLookbackDays = 90
+
+// Accounts with the same name at the same institution are the same account synced through two
+// connections
+func linkedAccountKey(account models.StoredAccount) string {
+	return strings.ToLower(strings.TrimSpace(account.Name)) + "|" + strings.ToLower(strings.TrimSpace(account.Org.Name)) + "|" + account.Currency
+}
+
+// How alike the payees of two transactions are, from 0 to 1
+func payeeSimilarity(a models.Transaction, b models.Transaction) float64 {
+	if a.MerchantId != "" && a.MerchantId == b.MerchantId {
+		return 1
+	}
+	nameA, nameB := transactionMerchantName(a), transactionMerchantName(b)
+	if MerchantKey(nameA) == MerchantKey(nameB) {
+		return 1
+	}
+	return max(WordSimilarity(nameA, nameB), WordSimilarity(nameB, nameA))
+}
+
+func orderedPair(a string, b string) [2]string {
+	if a > b {
+		return [2]string{b, a}
+	}
+	return [2]string{a, b}
+}
+
+// Pairs transactions with the same amount on the same (or a linked) account, dated within the
+// duplicate window of each other and with similar payees. Each transaction is paired at most once,
+// with the closest match in time. Pairs in dismissed (lower id first) are skipped
+func FindDuplicates(txns []models.Transaction, accounts []models.StoredAccount, dismissed map[[2]string]bool) []models.DuplicatePair {
+	sorted := append([]models.Transaction(nil), txns...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := TransactionTime(sorted[i]), TransactionTime(sorted[j])
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	linked := map[string]string{}
+	for _, account := range accounts {
+		linked[account.ID] = linkedAccountKey(account)
+	}
+	sameAccount := func(a string, b string) bool {
+		if a == b {
+			return true
+		}
+		keyA, okA := linked[a]
+		keyB, okB := linked[b]
+		return okA && okB && keyA == keyB
+	}
+
+	byAmount := map[int64][]int{}
+	for i, txn := range sorted {
+		if cents, ok := amountCents(txn.Amount); ok && cents != 0 {
+			byAmount[cents] = append(byAmount[cents], i)
+		}
+	}
+
+	used := map[int]bool{}
+	pairs := []models.DuplicatePair{}
+	for i, txn := range sorted {
+		if used[i] {
+			continue
+		}
+		cents, ok := amountCents(txn.Amount)
+		if !ok {
+			continue
+		}
+		best, bestSimilarity := -1, 0.0
+		var bestGap time.Duration
+		for _, j := range byAmount[cents] {
+			other := sorted[j]
+			if j <= i || used[j] || !sameAccount(txn.AccountID, other.AccountID) || dismissed[orderedPair(txn.ID, other.ID)] {
+				continue
+			}
+			// the two sides of a transfer are never copies of each other
+			if txn.TransferGroupId != "" && txn.TransferGroupId == other.TransferGroupId {
+				continue
+			}
+			gap := TransactionTime(other).Sub(TransactionTime(txn))
+			if gap > duplicateWindow {
+				continue
+			}
+			similarity := payeeSimilarity(txn, other)
+			if similarity < wordSimilarityThreshold {
+				continue
+			}
+			if best == -1 || gap < bestGap {
+				best, bestSimilarity, bestGap = j, similarity, gap
+			}
+		}
+		if best == -1 {
+			continue
+		}
+		used[i], used[best] = true, true
+
+		duplicate := sorted[best]
+		reason := "Same amount and payee on the same account"
+		if txn.AccountID != duplicate.AccountID {
+			reason = "Same amount and payee on two connections of the same account"
+		}
+		if days := int(bestGap.Hours() / 24); days > 0 {
+			reason += fmt.Sprintf(", %d day(s) apart", days)
+		}
+		pairs = append(pairs, models.DuplicatePair{
+			Original:   txn,
+			Duplicate:  duplicate,
+			Similarity: roundCents(bestSimilarity),
+			Reason:     reason,
+		})
+	}
+	return pairs
+}
+
+// The category and notes the kept transaction ends up with. Its own category wins unless it was
+// never categorized, and notes from both are kept
+func MergeTransactionDetails(keep models.Transaction, remove models.Transaction) models.Transaction {
+	if keep.Category == "" || keep.Category == "Unknown" {
+		if remove.Category != "" {
+			keep.Category = remove.Category
+		}
+	}
+	switch {
+	case remove.Notes == "" || remove.Notes == keep.Notes:
+	case keep.Notes == "":
+		keep.Notes = remove.Notes
+	default:
+		keep.Notes = keep.Notes + "\n" + remove.Notes
+	}
+	return keep
+}
+
+func FetchDuplicates(userId string, now time.Time, pool *pgxpool.Pool) ([]models.DuplicatePair, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return nil, err
+	}
+	txns, err := db.FetchDuplicateCandidates(userId, now.AddDate(0, 0, -duplicateLookbackDays).Unix(), pool)
+	if err != nil {
+		return nil, err
+	}
+	dismissed, err := db.FetchDuplicateDismissals(userId, pool)
+	if err != nil {
+		return nil, err
+	}
+	return FindDuplicates(txns, accounts, dismissed), nil
+}
+
+// Merges one of the user's transactions into another. Returns db.ErrTransactionNotFound when
+// either isn't one of the user's
+func MergeDuplicates(userId string, req models.MergeDuplicateRequest, now time.Time, pool *pgxpool.Pool) error {
+	if req.KeepId == "" || req.RemoveId == "" || req.KeepId == req.RemoveId {
+		return fmt.Errorf("keep_id and remove_id must be two different transactions")
+	}
+	txns, err := db.FetchUserTransactionsByIds(userId, []string{req.KeepId, req.RemoveId}, pool)
+	if err != nil {
+		return err
+	}
+	if len(txns) != 2 {
+		return db.ErrTransactionNotFound
+	}
+	keep, remove := txns[0], txns[1]
+	if keep.ID != req.KeepId {
+		keep, remove = remove, keep
+	}
+	return db.MergeDuplicateTransactions(userId, MergeTransactionDetails(keep, remove), remove, now.Unix(), pool)
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	accounts := []models.StoredAccount{
+		{ID: "acc", Name: "Sapphire Card", Org: models.Org{Name: "Chase"}, Currency: "USD"},
+		// the same card through a second connection
+		{ID: "acc-2", Name: "Sapphire Card ", Org: models.Org{Name: "chase"}, Currency: "USD"},
+		{ID: "checking", Name: "Checking", Org: models.Org{Name: "Chase"}, Currency: "USD"},
+	}
+
+	reissued := txnOn("2026-10-03", "SQ *BLUE BOTTLE COF 0423", "-6.50")
+	reissued.ID = "TRN-new"
+	otherConnection := txnOn("2026-10-06", "AMAZON.COM*2K4", "-54.20")
+	otherConnection.AccountID = "acc-2"
+	wrongAccount := txnOn("2026-10-10", "Shell Oil", "-40.00")
+	wrongAccount.AccountID = "checking"
+
+	txns := []models.Transaction{
+		txnOn("2026-10-02", "SQ *BLUE BOTTLE COF 0423", "-6.50"),
+		reissued,
+		txnOn("2026-10-05", "AMAZON.COM*2K4 AMZN.COM/BILL", "-54.20"),
+		otherConnection,
+		// same amount and payee, but too far apart
+		txnOn("2026-10-20", "SQ *BLUE BOTTLE COF 0423", "-6.50"),
+		// same amount and day, different payee
+		txnOn("2026-10-05", "Trader Joe's", "-54.20"),
+		txnOn("2026-10-10", "Shell Oil", "-40.00"),
+		wrongAccount,
+	}
+
+	pairs := FindDuplicates(txns, accounts, nil)
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %+v", pairs)
+	}
+	if pairs[0].Original.ID != "SQ *BLUE BOTTLE COF 0423-2026-10-02" || pairs[0].Duplicate.ID != "TRN-new" {
+		t.Errorf("unexpected first pair %+v", pairs[0])
+	}
+	if pairs[1].Duplicate.AccountID != "acc-2" || pairs[1].Reason != "Same amount and payee on two connections of the same account, 1 day(s) apart" {
+		t.Errorf("unexpected second pair %+v", pairs[1])
+	}
+
+	dismissed := map[[2]string]bool{orderedPair("TRN-new", "SQ *BLUE BOTTLE COF 0423-2026-10-02"): true}
+	if pairs := FindDuplicates(txns, accounts, dismissed); len(pairs) != 1 {
+		t.Errorf("expected the dismissed pair to be skipped, got %+v", pairs)
+	}
+}
+
+func TestMergeTransactionDetails(t *testing.T) {
+	keep := models.Transaction{ID: "new", Category: "Unknown"}
+	remove := models.Transaction{ID: "old", Category: "Food & Dining", Notes: "team lunch"}
+	merged := MergeTransactionDetails(keep, remove)
+	if merged.ID != "new" || merged.Category != "Food & Dining" || merged.Notes != "team lunch" {
+		t.Errorf("unexpected merge %+v", merged)
+	}
+
+	keep = models.Transaction{ID: "old", Category: "Travel", Notes: "flight"}
+	remove = models.Transaction{ID: "new", Category: "Shopping", Notes: "refund pending"}
+	merged = MergeTransactionDetails(keep, remove)
+	if merged.Category != "Travel" || merged.Notes != "flight\nrefund pending" {
+		t.Errorf("unexpected merge %+v", merged)
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/exporter"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrUnknownExportFormat = errors.New("format must be one of csv, ofx, json, ledger or beancount")
+
+var exportExtensions = map[string]string{
+	models.ExportFormatCSV:       "csv",
+	models.ExportFormatOFX:       "ofx",
+	models.ExportFormatJSON:      "json",
+	models.ExportFormatLedger:    "ledger",
+	models.ExportFormatBeancount: "beancount",
+}
+
+// Name of the file an export is downloaded as
+func ExportFilename(format string) string {
+	return "pocketwise-transactions." + exportExtensions[format]
+}
+
+// Reads the format (csv by default), from, to and accounts parameters of GET /export. Dates are
+// formatted as YYYY-MM-DD and to is inclusive
+func ParseExportQuery(values url.Values) (string, models.TransactionQuery, error) {
+	format := values.Get("format")
+	if format == "" {
+		format = models.ExportFormatCSV
+	}
+	if _, ok := exportExtensions[format]; !ok {
+		return format, models.TransactionQuery{}, ErrUnknownExportFormat
+	}
+
+	q := models.TransactionQuery{AccountIds: listParam(values, "accounts")}
+	if param := values.Get("from"); param != "" {
+		from, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			return format, q, errors.New("from must be formatted as YYYY-MM-DD")
+		}
+		q.From = from.Unix()
+	}
+	if param := values.Get("to"); param != "" {
+		to, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			return format, q, errors.New("to must be formatted as YYYY-MM-DD")
+		}
+		q.To = to.AddDate(0, 0, 1).Unix()
+	}
+	if q.From != 0 && q.To != 0 && q.To <= q.From {
+		return format, q, errors.New("from must not be after to")
+	}
+	return format, q, nil
+}
+
+// The accounts an export covers. Accounts asked for by the query must all be the user's, otherwise
+// ErrAccountNotFound is returned
+func exportAccounts(accounts []models.StoredAccount, accountIds []string) ([]exporter.Account, error) {
+	exported := make([]exporter.Account, 0, len(accounts))
+	for _, account := range accounts {
+		exported = append(exported, exporter.Account{
+			ID:          account.ID,
+			Name:        account.Name,
+			Org:         account.Org.Name,
+			AccountType: account.AccountType,
+			Currency:    account.Currency,
+			Liability:   IsLiability(account.AccountType),
+			Balance:     account.Balance,
+			BalanceDate: account.BalanceDate,
+		})
+	}
+	for _, accountId := range accountIds {
+		found := false
+		for _, account := range accounts {
+			found = found || account.ID == accountId
+		}
+		if !found {
+			return nil, db.ErrAccountNotFound
+		}
+	}
+	return exported, nil
+}
+
+// Streams the user's transactions matching the query to w in the given format. Nothing is written
+// when the query names an account that isn't the user's; errors after that point leave w with a
+// partial export
+func ExportTransactions(ctx context.Context, w io.Writer, userId string, format string, q models.TransactionQuery, now time.Time, pool *pgxpool.Pool) error {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return err
+	}
+	opts := exporter.Options{Now: now}
+	if opts.Accounts, err = exportAccounts(accounts, q.AccountIds); err != nil {
+		return err
+	}
+	if q.From != 0 {
+		opts.From = time.Unix(q.From, 0).UTC()
+	}
+	if q.To != 0 {
+		opts.To = time.Unix(q.To, 0).UTC()
+	}
+
+	writer, err := exporter.NewWriter(format, w, opts)
+	if err != nil {
+		return err
+	}
+	if err := db.StreamTransactions(ctx, userId, q, exporter.GroupsByAccount(format), writer.Write, pool); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish export: %w", err)
+	}
+	return nil
+}
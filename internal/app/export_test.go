@@ -0,0 +1,58 @@
+package app
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestParseExportQuery(t *testing.T) {
+	values, _ := url.ParseQuery("from=2026-09-01&to=2026-09-30&accounts=ACT-1,ACT-2")
+	format, q, err := ParseExportQuery(values)
+	if err != nil {
+		t.Fatalf("Expected the query to parse, got %v", err)
+	}
+	if format != models.ExportFormatCSV {
+		t.Errorf("Expected csv by default, got %q", format)
+	}
+	if from := time.Unix(q.From, 0).UTC().Format(time.DateOnly); from != "2026-09-01" {
+		t.Errorf("Expected the export to start on 2026-09-01, got %s", from)
+	}
+	if to := time.Unix(q.To, 0).UTC().Format(time.DateOnly); to != "2026-10-01" {
+		t.Errorf("Expected the last day to be included, got an end of %s", to)
+	}
+	if len(q.AccountIds) != 2 || q.AccountIds[1] != "ACT-2" {
+		t.Errorf("Expected both accounts, got %v", q.AccountIds)
+	}
+
+	for _, query := range []string{"format=xlsx", "from=09/01/2026", "from=2026-10-01&to=2026-09-01"} {
+		values, _ := url.ParseQuery(query)
+		if _, _, err := ParseExportQuery(values); err == nil {
+			t.Errorf("Expected %q to be rejected", query)
+		}
+	}
+	if format, _, _ := ParseExportQuery(url.Values{"format": {"beancount"}}); ExportFilename(format) != "pocketwise-transactions.beancount" {
+		t.Errorf("Unexpected filename %q", ExportFilename(format))
+	}
+}
+
+func TestExportAccounts(t *testing.T) {
+	accounts := []models.StoredAccount{
+		{ID: "ACT-1", Name: "Checking", AccountType: "checking", Balance: "100.00", Org: models.Org{Name: "Chase"}},
+		{ID: "ACT-2", Name: "Sapphire", AccountType: "credit", Balance: "-40.00"},
+	}
+	exported, err := exportAccounts(accounts, []string{"ACT-2"})
+	if err != nil {
+		t.Fatalf("Expected the user's account to be exportable, got %v", err)
+	}
+	if len(exported) != 2 || exported[0].Org != "Chase" || exported[0].Liability || !exported[1].Liability {
+		t.Errorf("Unexpected accounts %+v", exported)
+	}
+	if _, err := exportAccounts(accounts, []string{"ACT-3"}); !errors.Is(err, db.ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound for someone else's account, got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// How much transaction history the discretionary spending averages are based on
+const forecastLookbackDays = 90
+
+// z-score of the confidence bands (80%)
+const forecastBandZ = 1.28
+
+type ForecastInput struct {
+	Accounts     []models.StoredAccount
+	Series       []models.RecurringSeries
+	ManualBills  []models.ManualBill
+	Transactions []models.Transaction
+	Budgets      []models.StoredBudget
+	Now          time.Time
+	Days         int
+}
+
+// Projects each account's balance day by day. Every day applies the account's average
+// discretionary (non recurring) spend, then whatever recurring income, recurring expenses and
+// manual bills are due. Where the user has a monthly budget for a field, the budgeted amount
+// (less the recurring charges already counted) replaces the historical average for that field.
+// The confidence bands widen with the square root of time, like a random walk of the daily
+// discretionary spend.
+func BuildForecast(in ForecastInput) models.ForecastResponse {
+	from := time.Date(in.Now.Year(), in.Now.Month(), in.Now.Day(), 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(0, 0, in.Days)
+
+	activeSeries := map[string]models.RecurringSeries{}
+	for _, s := range in.Series {
+		if s.Status == models.RecurringStatusActive && s.Review != models.RecurringReviewDismissed {
+			activeSeries[s.ID] = s
+		}
+	}
+
+	// historical discretionary spending per account and budget field
+	historyStart := from.AddDate(0, 0, -forecastLookbackDays)
+	earliest := from
+	fieldSpend := map[string]map[string]float64{}
+	dailySpend := map[string][]float64{}
+	for _, txn := range in.Transactions {
+		at := TransactionTime(txn)
+		if at.Before(earliest) {
+			earliest = at
+		}
+		amount, err := strconv.ParseFloat(txn.Amount, 64)
+		// transfers between the user's own accounts aren't spending
+		if err != nil || amount >= 0 || txn.TransferGroupId != "" || at.Before(historyStart) || !at.Before(from) {
+			continue
+		}
+		if id, ok := RecurringSeriesIdForTransaction(txn); ok {
+			if _, recurring := activeSeries[id]; recurring {
+				continue
+			}
+		}
+		if fieldSpend[txn.AccountID] == nil {
+			fieldSpend[txn.AccountID] = map[string]float64{}
+			dailySpend[txn.AccountID] = make([]float64, forecastLookbackDays)
+		}
+		for _, line := range SplitLines(txn) {
+			lineAmount, _ := strconv.ParseFloat(line.Amount, 64)
+			fieldSpend[txn.AccountID][models.BudgetFieldForCategory(line.Category)] += -lineAmount
+		}
+		dailySpend[txn.AccountID][int(at.Sub(historyStart).Hours()/24)] += -amount
+	}
+	historyDays := math.Max(math.Min(float64(forecastLookbackDays), from.Sub(earliest).Hours()/24), 1)
+
+	fieldTotals := map[string]float64{}
+	var largestAccount string
+	var largestSpend float64
+	for accountId, fields := range fieldSpend {
+		var accountTotal float64
+		for field, amount := range fields {
+			fieldTotals[field] += amount
+			accountTotal += amount
+		}
+		if accountTotal > largestSpend {
+			largestAccount, largestSpend = accountId, accountTotal
+		}
+	}
+	if largestAccount == "" && len(in.Accounts) > 0 {
+		largestAccount = in.Accounts[0].ID
+	}
+
+	// the share of a field's spending an account is responsible for, used to split budgeted amounts
+	share := func(accountId string, field string) float64 {
+		if fieldTotals[field] == 0 {
+			if accountId == largestAccount {
+				return 1
+			}
+			return 0
+		}
+		return fieldSpend[accountId][field] / fieldTotals[field]
+	}
+
+	// monthly recurring expense per field, so budgeted amounts aren't counted twice
+	recurringPerField := map[string]float64{}
+	for _, s := range activeSeries {
+		if !s.IsIncome && s.IntervalDays > 0 {
+			recurringPerField[models.BudgetFieldForCategory(s.Category)] += math.Abs(s.NextExpectedAmount) * averageDaysPerMonth / float64(s.IntervalDays)
+		}
+	}
+
+	monthlyBudgets := map[string]models.StoredBudget{}
+	for _, b := range in.Budgets {
+		if b.Period == models.BudgetPeriodMonthly || b.Period == "" {
+			monthlyBudgets[fmt.Sprintf("%d-%d", b.Year, b.Month)] = b
+		}
+	}
+
+	dailyDiscretionary := func(accountId string, day time.Time) float64 {
+		rates := map[string]float64{}
+		for field, amount := range fieldSpend[accountId] {
+			rates[field] = amount / historyDays
+		}
+		if budget, ok := monthlyBudgets[fmt.Sprintf("%d-%d", day.Year(), int(day.Month()))]; ok {
+			daysInMonth := float64(time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day())
+			for field, budgeted := range budget.Lines() {
+				if budgeted > 0 {
+					rates[field] = math.Max(budgeted-recurringPerField[field], 0) / daysInMonth * share(accountId, field)
+				}
+			}
+		}
+		var total float64
+		for _, rate := range rates {
+			total += rate
+		}
+		return total
+	}
+
+	// dated flows from recurring series and manual bills, keyed by account then date
+	flows := map[string]map[string]float64{}
+	addFlow := func(accountId string, day time.Time, amount float64) {
+		if flows[accountId] == nil {
+			flows[accountId] = map[string]float64{}
+		}
+		flows[accountId][day.Format(time.DateOnly)] += amount
+	}
+	for _, s := range activeSeries {
+		for _, day := range ProjectSeriesDates(s, from, until) {
+			addFlow(s.AccountId, day, s.NextExpectedAmount)
+		}
+	}
+	for _, bill := range ProjectBills(nil, in.ManualBills, from, until) {
+		day, _ := time.Parse(time.DateOnly, bill.DueDate)
+		addFlow(bill.AccountId, day, -bill.Amount)
+	}
+
+	response := models.ForecastResponse{
+		From:               from.Format(time.DateOnly),
+		Days:               in.Days,
+		Accounts:           []models.AccountForecast{},
+		DailyDiscretionary: map[string]float64{},
+	}
+	for field, amount := range fieldTotals {
+		response.DailyDiscretionary[field] = roundCents(amount / historyDays)
+	}
+
+	total := models.AccountForecast{AccountId: "total", Name: "All accounts"}
+	totalBalances := make([]float64, in.Days)
+	totalVariance := 0.0
+	for _, account := range in.Accounts {
+		balance, _ := strconv.ParseFloat(account.Balance, 64)
+		forecast := models.AccountForecast{AccountId: account.ID, Name: account.Name, StartingBalance: balance}
+		total.StartingBalance += balance
+
+		sd := stdDev(dailySpend[account.ID])
+		totalVariance += sd * sd
+		for i := 0; i < in.Days; i++ {
+			day := from.AddDate(0, 0, i)
+			balance += flows[account.ID][day.Format(time.DateOnly)] - dailyDiscretionary(account.ID, day)
+			band := forecastBandZ * sd * math.Sqrt(float64(i+1))
+			forecast.Points = append(forecast.Points, forecastPoint(day, balance, band))
+			totalBalances[i] += balance
+		}
+		forecast.LowPoint, forecast.Warning = lowPoint(forecast.Points)
+		response.Accounts = append(response.Accounts, forecast)
+	}
+
+	// bills that aren't tied to an account only show up in the total
+	unassigned := 0.0
+	for i := 0; i < in.Days; i++ {
+		day := from.AddDate(0, 0, i)
+		unassigned += flows[""][day.Format(time.DateOnly)]
+		band := forecastBandZ * math.Sqrt(totalVariance) * math.Sqrt(float64(i+1))
+		total.Points = append(total.Points, forecastPoint(day, totalBalances[i]+unassigned, band))
+	}
+	total.LowPoint, total.Warning = lowPoint(total.Points)
+	response.Total = total
+	return response
+}
+
+func forecastPoint(day time.Time, balance float64, band float64) models.ForecastPoint {
+	return models.ForecastPoint{
+		Date:    day.Format(time.DateOnly),
+		Balance: roundCents(balance),
+		Low:     roundCents(balance - band),
+		High:    roundCents(balance + band),
+	}
+}
+
+// Finds the lowest expected balance and warns when it, or its confidence band, goes below zero
+func lowPoint(points []models.ForecastPoint) (models.ForecastLowPoint, string) {
+	if len(points) == 0 {
+		return models.ForecastLowPoint{}, ""
+	}
+	low := points[0]
+	for _, p := range points[1:] {
+		if p.Balance < low.Balance {
+			low = p
+		}
+	}
+	lowest := models.ForecastLowPoint{Date: low.Date, Balance: low.Balance, Low: low.Low}
+	switch {
+	case low.Balance < 0:
+		return lowest, fmt.Sprintf("Balance is expected to drop to $%.2f on %s", low.Balance, low.Date)
+	case low.Low < 0:
+		return lowest, fmt.Sprintf("Balance could drop below $0 around %s", low.Date)
+	}
+	return lowest, ""
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var sumSquares float64
+	for _, v := range values {
+		sumSquares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sumSquares / float64(len(values)-1))
+}
+
+func FetchForecast(userId string, now time.Time, days int, pool *pgxpool.Pool) (models.ForecastResponse, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return models.ForecastResponse{}, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return models.ForecastResponse{}, err
+	}
+	txns, err := db.FetchAllTransactions(accounts, pool)
+	if err != nil {
+		return models.ForecastResponse{}, err
+	}
+	series, err := reviewedRecurringSeries(userId, txns, now, false, pool)
+	if err != nil {
+		return models.ForecastResponse{}, err
+	}
+	manual, err := db.FetchManualBills(userId, pool)
+	if err != nil {
+		return models.ForecastResponse{}, err
+	}
+	budgets, err := db.FetchAllExistingBudgets(userId, pool)
+	if err != nil {
+		return models.ForecastResponse{}, err
+	}
+
+	return BuildForecast(ForecastInput{
+		Accounts:     accounts,
+		Series:       series,
+		ManualBills:  manual,
+		Transactions: txns,
+		Budgets:      budgets,
+		Now:          now,
+		Days:         days,
+	}), nil
+}
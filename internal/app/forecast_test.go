@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestBuildForecast(t *testing.T) {
+	now := date("2026-10-19")
+
+	// $20 of groceries every day for the last 90 days
+	var history []models.Transaction
+	for i := 1; i <= 90; i++ {
+		day := now.AddDate(0, 0, -i).Format("2006-01-02")
+		txn := txnOn(day, fmt.Sprintf("Store %d", i), "-20.00")
+		txn.AccountID = "checking"
+		txn.Category = "Groceries"
+		history = append(history, txn)
+	}
+
+	forecast := BuildForecast(ForecastInput{
+		Accounts: []models.StoredAccount{{ID: "checking", Name: "Checking", Balance: "1000.00"}},
+		Series: []models.RecurringSeries{
+			{ID: "payroll", AccountId: "checking", Cadence: "biweekly", IntervalDays: 14, NextExpectedDate: "2026-10-30", NextExpectedAmount: 2000, IsIncome: true, Status: models.RecurringStatusActive},
+		},
+		ManualBills:  []models.ManualBill{{ID: "rent", AccountId: "checking", Amount: 1500, Cadence: "monthly", NextDueDate: "2026-10-25"}},
+		Transactions: history,
+		Now:          now,
+		Days:         30,
+	})
+
+	checking := forecast.Accounts[0]
+	if len(checking.Points) != 30 {
+		t.Fatalf("Expected 30 days of points, got %d", len(checking.Points))
+	}
+	// 11 days of groceries (220) and rent (1500) before payday
+	if checking.LowPoint.Date != "2026-10-29" || checking.LowPoint.Balance != -720 {
+		t.Errorf("Expected the low point of -720 on 2026-10-29, got %+v", checking.LowPoint)
+	}
+	if checking.Warning == "" {
+		t.Errorf("Expected an overdraft warning")
+	}
+	if forecast.DailyDiscretionary["groceries"] != 20 {
+		t.Errorf("Expected 20 a day of groceries, got %+v", forecast.DailyDiscretionary)
+	}
+
+	// a budget for November replaces the historical grocery average
+	budgets := []models.StoredBudget{{Year: 2026, Month: 11, Groceries: 300, BudgetPeriod: models.MonthlyPeriod(2026, 11)}}
+	budgeted := BuildForecast(ForecastInput{
+		Accounts:     []models.StoredAccount{{ID: "checking", Name: "Checking", Balance: "1000.00"}},
+		Transactions: history,
+		Budgets:      budgets,
+		Now:          now,
+		Days:         30,
+	})
+	last := budgeted.Accounts[0].Points[29]
+	// 13 days of October at 20 and 17 days of November at 10
+	if last.Balance != 1000-13*20-17*10 {
+		t.Errorf("Expected the November budget to be used, got %.2f", last.Balance)
+	}
+	if budgeted.Total.Points[29].Balance != last.Balance {
+		t.Errorf("Expected the total to match the only account")
+	}
+}
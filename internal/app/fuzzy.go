@@ -0,0 +1,126 @@
+package app
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+)
+
+// Matches search text against transactions the way Postgres does with the 'simple' text search
+// configuration and pg_trgm, so the in-memory repository and the highlighting agree with what the
+// database returns
+
+const wordSimilarityThreshold = db.WordSimilarityThreshold
+
+type textWord struct {
+	text       string
+	start, end int
+}
+
+// Splits text into lowercased runs of letters and digits, keeping their byte offsets in the text
+func splitWords(text string) []textWord {
+	var words []textWord
+	start := -1
+	for i, r := range text {
+		alphanumeric := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if alphanumeric && start < 0 {
+			start = i
+		} else if !alphanumeric && start >= 0 {
+			words = append(words, textWord{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, textWord{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return words
+}
+
+// pg_trgm pads each word with two spaces in front and one behind before taking trigrams
+func wordTrigrams(word string) []string {
+	padded := []rune("  " + word + " ")
+	trigrams := make([]string, 0, len(padded)-2)
+	for i := 0; i+3 <= len(padded); i++ {
+		trigrams = append(trigrams, string(padded[i:i+3]))
+	}
+	return trigrams
+}
+
+// Like pg_trgm's word_similarity: the best similarity between the trigrams of the query and any
+// contiguous extent of the trigrams of the text, so "starbcks" still scores high against
+// "STARBUCKS STORE 1234"
+func WordSimilarity(query string, text string) float64 {
+	queryTrigrams := map[string]bool{}
+	for _, word := range splitWords(query) {
+		for _, trigram := range wordTrigrams(word.text) {
+			queryTrigrams[trigram] = true
+		}
+	}
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+	var sequence []string
+	for _, word := range splitWords(text) {
+		sequence = append(sequence, wordTrigrams(word.text)...)
+	}
+
+	var best float64
+	for i := range sequence {
+		if !queryTrigrams[sequence[i]] {
+			continue
+		}
+		extent := map[string]bool{}
+		shared := 0
+		for j := i; j < len(sequence); j++ {
+			if !extent[sequence[j]] {
+				extent[sequence[j]] = true
+				if queryTrigrams[sequence[j]] {
+					shared++
+				}
+			}
+			similarity := float64(shared) / float64(len(queryTrigrams)+len(extent)-shared)
+			if similarity > best {
+				best = similarity
+			}
+		}
+	}
+	return best
+}
+
+// Whether every word of the query appears as a word in one of the texts, like
+// plainto_tsquery('simple', query) against a tsvector of the texts
+func FullTextMatch(query string, texts ...string) bool {
+	queryWords := splitWords(query)
+	if len(queryWords) == 0 {
+		return false
+	}
+	present := map[string]bool{}
+	for _, text := range texts {
+		for _, word := range splitWords(text) {
+			present[word.text] = true
+		}
+	}
+	for _, word := range queryWords {
+		if !present[word.text] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the byte ranges of the words in text that match a word of the query, exactly, as a
+// prefix or fuzzily
+func HighlightMatches(text string, query string) [][2]int {
+	queryWords := splitWords(query)
+	matches := [][2]int{}
+	for _, word := range splitWords(text) {
+		for _, queryWord := range queryWords {
+			if strings.HasPrefix(word.text, queryWord.text) || WordSimilarity(queryWord.text, word.text) >= wordSimilarityThreshold {
+				matches = append(matches, [2]int{word.start, word.end})
+				break
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWordSimilarity(t *testing.T) {
+	// example from the pg_trgm documentation
+	if similarity := WordSimilarity("word", "two words"); math.Abs(similarity-0.8) > 0.001 {
+		t.Errorf("Expected 0.8, got %f", similarity)
+	}
+	if similarity := WordSimilarity("starbcks", "STARBUCKS STORE 01234"); similarity < wordSimilarityThreshold {
+		t.Errorf("Expected a typo to still match, got %f", similarity)
+	}
+	if similarity := WordSimilarity("starbcks", "SHELL OIL 5744"); similarity >= wordSimilarityThreshold {
+		t.Errorf("Expected an unrelated payee not to match, got %f", similarity)
+	}
+}
+
+func TestFullTextMatch(t *testing.T) {
+	if !FullTextMatch("blue bottle", "SQ *BLUE BOTTLE COFFEE", "") {
+		t.Error("Expected all words to match across the payee")
+	}
+	if FullTextMatch("blue bottle", "BLUE APRON") {
+		t.Error("Expected a missing word to fail the match")
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	text := "STARBUCKS STORE 01234 SEATTLE"
+	matches := HighlightMatches(text, "starbcks seattle")
+	if len(matches) != 2 || text[matches[0][0]:matches[0][1]] != "STARBUCKS" || text[matches[1][0]:matches[1][1]] != "SEATTLE" {
+		t.Errorf("Unexpected highlights: %v", matches)
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// How far back snapshots are used to estimate how fast a goal is growing
+const goalProjectionLookbackDays = 90
+
+const averageDaysPerMonth = 365.25 / 12
+
+// Works out how far along a goal is from the balance of its account. The savings rate is estimated
+// from the oldest snapshot in the lookback window, and projected forward to find the completion
+// date. Without at least a day of history there is no rate yet, so the goal is assumed on track.
+func CalculateGoalProgress(goal models.Goal, balance float64, snapshots []models.GoalSnapshot, now time.Time) models.Goal {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	goal.CurrentAmount = roundCents(math.Max(balance, 0) * goal.AllocationPercent / 100)
+	remaining := goal.TargetAmount - goal.CurrentAmount
+
+	if remaining <= 0 {
+		goal.Status = models.GoalStatusAchieved
+		goal.RequiredMonthly = 0
+		goal.ProjectedCompletion = today.Format(time.DateOnly)
+		return goal
+	}
+
+	targetDate, err := time.Parse(time.DateOnly, goal.TargetDate)
+	if err != nil {
+		targetDate = today
+	}
+	monthsLeft := math.Max(targetDate.Sub(today).Hours()/24/averageDaysPerMonth, 1)
+	goal.RequiredMonthly = math.Ceil(remaining/monthsLeft*100) / 100
+
+	goal.ProjectedCompletion = ""
+	if len(snapshots) == 0 {
+		goal.Status = models.GoalStatusOnTrack
+		if goal.CurrentAmount == 0 {
+			goal.Status = models.GoalStatusNotStarted
+		}
+		return goal
+	}
+	oldest := snapshots[0]
+	oldestDate, err := time.Parse(time.DateOnly, oldest.Date)
+	days := today.Sub(oldestDate).Hours() / 24
+	if err != nil || days < 1 {
+		goal.Status = models.GoalStatusOnTrack
+		if goal.CurrentAmount == 0 {
+			goal.Status = models.GoalStatusNotStarted
+		}
+		return goal
+	}
+
+	ratePerDay := (goal.CurrentAmount - oldest.Amount) / days
+	if ratePerDay <= 0 {
+		goal.Status = models.GoalStatusBehind
+		if goal.CurrentAmount == 0 {
+			goal.Status = models.GoalStatusNotStarted
+		}
+		return goal
+	}
+
+	projected := today.AddDate(0, 0, int(math.Ceil(remaining/ratePerDay)))
+	goal.ProjectedCompletion = projected.Format(time.DateOnly)
+	if projected.After(targetDate) {
+		goal.Status = models.GoalStatusBehind
+	} else {
+		goal.Status = models.GoalStatusOnTrack
+	}
+	return goal
+}
+
+// Recalculates every goal of a user from their current account balances and snapshots today's
+// progress. Called after each sync and whenever a goal is created or changed.
+func RecalculateGoals(userId string, now time.Time, pool *pgxpool.Pool) ([]models.Goal, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return nil, err
+	}
+	balances := map[string]float64{}
+	for _, account := range accounts {
+		balance, _ := strconv.ParseFloat(account.Balance, 64)
+		balances[account.ID] = balance
+	}
+
+	goals, err := db.FetchGoals(userId, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	since := now.AddDate(0, 0, -goalProjectionLookbackDays).Format(time.DateOnly)
+	for i, goal := range goals {
+		snapshots, err := db.FetchGoalSnapshots(goal.ID, since, pool)
+		if err != nil {
+			return nil, err
+		}
+		goal = CalculateGoalProgress(goal, balances[goal.AccountId], snapshots, now)
+		if err := db.UpdateGoalProgress(goal, pool); err != nil {
+			return nil, err
+		}
+		snapshot := models.GoalSnapshot{Date: now.Format(time.DateOnly), Amount: goal.CurrentAmount}
+		if err := db.UpsertGoalSnapshot(goal.ID, snapshot, pool); err != nil {
+			return nil, err
+		}
+		goals[i] = goal
+	}
+	return goals, nil
+}
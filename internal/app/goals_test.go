@@ -0,0 +1,37 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestCalculateGoalProgress(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	goal := models.Goal{TargetAmount: 6000, TargetDate: "2027-10-19", AllocationPercent: 50}
+
+	// half of a 4000 balance, growing 400 a month over the last 3 months
+	snapshots := []models.GoalSnapshot{{Date: "2026-07-21", Amount: 800}}
+	onTrack := CalculateGoalProgress(goal, 4000, snapshots, now)
+	if onTrack.CurrentAmount != 2000 {
+		t.Errorf("Expected current amount of 2000, got %.2f", onTrack.CurrentAmount)
+	}
+	if onTrack.Status != models.GoalStatusOnTrack {
+		t.Errorf("Expected goal to be on track, got %s (projected %s)", onTrack.Status, onTrack.ProjectedCompletion)
+	}
+	if onTrack.RequiredMonthly < 333 || onTrack.RequiredMonthly > 334 {
+		t.Errorf("Expected roughly 333.33 a month to be required, got %.2f", onTrack.RequiredMonthly)
+	}
+
+	// barely growing, so it would take years
+	slow := CalculateGoalProgress(goal, 4000, []models.GoalSnapshot{{Date: "2026-07-21", Amount: 1990}}, now)
+	if slow.Status != models.GoalStatusBehind {
+		t.Errorf("Expected goal to be behind, got %s", slow.Status)
+	}
+
+	done := CalculateGoalProgress(goal, 12500, snapshots, now)
+	if done.Status != models.GoalStatusAchieved || done.RequiredMonthly != 0 {
+		t.Errorf("Expected goal to be achieved, got %+v", done)
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/storage"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Starts building a zip of all the user's data. Poll GET /me/export/{exportId} until it is ready
+// and download it from GET /me/export/{exportId}/download
+func HandleRequestDataExport(w http.ResponseWriter, r *http.Request, store storage.BlobStore, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := app.RequestDataExport(userID, store, pool)
+	if err != nil {
+		log.Printf("Failed to request data export: %v\n", err)
+		http.Error(w, "Data export could not be started, please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		http.Error(w, "Failed to send data export response", http.StatusInternalServerError)
+	}
+}
+
+// The user's data exports, newest first
+func HandleGetDataExports(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exports, err := db.FetchDataExports(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch data exports: %v\n", err)
+		http.Error(w, "Failed to fetch data exports", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(exports); err != nil {
+		http.Error(w, "Failed to send data exports response", http.StatusInternalServerError)
+	}
+}
+
+func HandleGetDataExport(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := db.FetchDataExport(userID, mux.Vars(r)["exportId"], pool)
+	if errors.Is(err, db.ErrDataExportNotFound) {
+		http.Error(w, "Data export not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to fetch data export: %v\n", err)
+		http.Error(w, "Failed to fetch data export", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		http.Error(w, "Failed to send data export response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDownloadDataExport(w http.ResponseWriter, r *http.Request, store storage.BlobStore, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := db.FetchDataExport(userID, mux.Vars(r)["exportId"], pool)
+	if errors.Is(err, db.ErrDataExportNotFound) {
+		http.Error(w, "Data export not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to fetch data export: %v\n", err)
+		http.Error(w, "Failed to fetch data export", http.StatusInternalServerError)
+		return
+	}
+	if export.Status != models.DataExportStatusReady {
+		http.Error(w, "Data export isn't ready", http.StatusConflict)
+		return
+	}
+
+	blob, err := store.Get(r.Context(), export.StorageKey)
+	if err != nil {
+		log.Printf("Failed to read data export %s: %v\n", export.ID, err)
+		http.Error(w, "Failed to read data export", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="pocketwise-data.zip"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(export.Size, 10))
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("Failed to send data export %s: %v\n", export.ID, err)
+	}
+}
+
+// Schedules the user's account and all its data to be deleted once the grace period is over.
+// Until then the deletion can be cancelled with DELETE /me/deletion
+func HandleDeleteAccount(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deletion, err := app.RequestAccountDeletion(userID, time.Now(), pool)
+	if err != nil {
+		log.Printf("Failed to schedule account deletion: %v\n", err)
+		http.Error(w, "Account deletion could not be scheduled, please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(deletion); err != nil {
+		http.Error(w, "Failed to send account deletion response", http.StatusInternalServerError)
+	}
+}
+
+// Shows the scheduled deletion of the user's account, or cancels it (on DELETE)
+func HandleAccountDeletion(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err := db.CancelAccountDeletion(userID, pool)
+		if errors.Is(err, db.ErrDeletionNotFound) {
+			http.Error(w, "No account deletion is scheduled", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Failed to cancel account deletion: %v\n", err)
+			http.Error(w, "Account deletion could not be cancelled, please try again later.", http.StatusInternalServerError)
+			return
+		}
+
+		// Send JSON response to the client
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.MessageResponse{Message: "Account deletion cancelled"}); err != nil {
+			http.Error(w, "Failed to send account deletion response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	deletion, err := db.FetchAccountDeletion(userID, pool)
+	if errors.Is(err, db.ErrDeletionNotFound) {
+		http.Error(w, "No account deletion is scheduled", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to fetch account deletion: %v\n", err)
+		http.Error(w, "Failed to fetch account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deletion); err != nil {
+		http.Error(w, "Failed to send account deletion response", http.StatusInternalServerError)
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/BBaCode/pocketwise-server/internal/app"
 	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/notify"
+	"github.com/BBaCode/pocketwise-server/internal/simplefin"
 	"github.com/BBaCode/pocketwise-server/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -59,7 +63,7 @@ func HandleGetAccounts(w http.ResponseWriter, r *http.Request, pool *pgxpool.Poo
 // This is really only used as an initial setup (need to add accounts to simplefin first)
 // If a user needs to add new accounts, they need to go to simplefin first I believe,
 // but maybe there is an API that can do it programmaticly
-func HandleAddAccounts(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+func HandleAddAccounts(w http.ResponseWriter, r *http.Request, sf *simplefin.Client, pool *pgxpool.Pool) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
@@ -78,12 +82,19 @@ func HandleAddAccounts(w http.ResponseWriter, r *http.Request, pool *pgxpool.Poo
 		return
 	}
 
-	req, err := http.NewRequest("GET", "https://beta-bridge.simplefin.org/simplefin/accounts", nil)
+	// the user's own SimpleFIN connection, or the server's shared credentials
+	accessURL, err := app.SimpleFINAccessURL(userID, pool)
 	if err != nil {
-		log.Fatalf("Failed to create request: %v", err)
+		log.Printf("Failed to fetch SimpleFIN connection: %v\n", err)
+		http.Error(w, "Something went wrong. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	req, err := sf.AccountsRequest(accessURL, nil)
+	if err != nil {
+		log.Printf("Failed to create request: %v\n", err)
+		http.Error(w, "Something went wrong. Please try again later.", http.StatusInternalServerError)
+		return
 	}
-
-	req.SetBasicAuth(os.Getenv("SIMPLE_FIN_USERNAME"), os.Getenv("SIMPLE_FIN_PASSWORD")) // Split username and password
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -125,7 +136,7 @@ func HandleAddAccounts(w http.ResponseWriter, r *http.Request, pool *pgxpool.Poo
 	}
 }
 
-func HandleGetUpdatedAccountData(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+func HandleGetUpdatedAccountData(w http.ResponseWriter, r *http.Request, sf *simplefin.Client, pool *pgxpool.Pool) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
@@ -151,12 +162,19 @@ func HandleGetUpdatedAccountData(w http.ResponseWriter, r *http.Request, pool *p
 		log.Fatalf("Failed to get successful response from FetchMostRecentTransaction: %s", err)
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://beta-bridge.simplefin.org/simplefin/accounts?start-date=%d", startDate), nil)
+	// the user's own SimpleFIN connection, or the server's shared credentials
+	accessURL, err := app.SimpleFINAccessURL(userID, pool)
 	if err != nil {
-		log.Fatalf("Failed to create request: %v", err)
+		log.Printf("Failed to fetch SimpleFIN connection: %v\n", err)
+		http.Error(w, "Something went wrong. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	req, err := sf.AccountsRequest(accessURL, url.Values{"start-date": {strconv.FormatInt(startDate, 10)}})
+	if err != nil {
+		log.Printf("Failed to create request: %v\n", err)
+		http.Error(w, "Something went wrong. Please try again later.", http.StatusInternalServerError)
+		return
 	}
-
-	req.SetBasicAuth(os.Getenv("SIMPLE_FIN_USERNAME"), os.Getenv("SIMPLE_FIN_PASSWORD")) // Split username and password
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -188,13 +206,8 @@ func HandleGetUpdatedAccountData(w http.ResponseWriter, r *http.Request, pool *p
 		var categorizedTxns []models.Transaction
 		// categorize transactions and append them to a new array to send to database
 		for _, txn := range account.Transactions {
-			category, err := db.FetchCategoryByPayee(txn, pool)
-			if len(category) > 0 {
-				txn.Category = category
-			} else {
-				txn, err = app.CategorizeTransaction(&txn)
-			}
 			txn.AccountID = account.ID
+			txn, err := app.CategorizeWithPayeeHistory(userID, txn, pool)
 			if err != nil {
 				log.Fatalf("Failed to categorize transactions with error: %s", err)
 			}
@@ -208,6 +221,32 @@ func HandleGetUpdatedAccountData(w http.ResponseWriter, r *http.Request, pool *p
 		}
 	}
 
+	// group the new transactions under their merchants before anything reports on them
+	if _, err := app.AssignMerchants(userID, pool); err != nil {
+		log.Printf("Failed to assign merchants: %v\n", err)
+	}
+
+	// pair up transfers first so they don't count as spending in the goal and budget checks below
+	if _, err := app.DetectTransfers(userID, time.Now().UTC(), pool); err != nil {
+		log.Printf("Failed to detect transfers: %v\n", err)
+	}
+
+	// balances changed, so goal progress and required contributions need recalculating
+	if _, err := app.RecalculateGoals(userID, time.Now().UTC(), pool); err != nil {
+		log.Printf("Failed to recalculate goals: %v\n", err)
+	}
+
+	// with the new transactions in, check whether any budget crossed one of its alert thresholds
+	dispatcher := notify.NewDispatcher(pool)
+	if err := app.EvaluateBudgetAlerts(userID, time.Now().UTC(), dispatcher, pool); err != nil {
+		log.Printf("Failed to evaluate budget alerts: %v\n", err)
+	}
+
+	// and flag anything unusual among them
+	if _, err := app.EvaluateAnomalies(userID, time.Now().UTC(), dispatcher, pool); err != nil {
+		log.Printf("Failed to evaluate anomalies: %v\n", err)
+	}
+
 	// Send JSON response to the client
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(accountsResponse); err != nil {
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Unusual transactions and spending spikes flagged after syncs. Pass ?dismissed=true to include
+// the ones the user already dismissed
+func HandleGetAlerts(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	anomalies, err := db.FetchAnomalies(userID, r.URL.Query().Get("dismissed") == "true", pool)
+	if err != nil {
+		log.Printf("Failed to fetch alerts: %v\n", err)
+		http.Error(w, "Failed to fetch alerts", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(anomalies); err != nil {
+		http.Error(w, "Failed to send alerts response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDismissAlert(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var response models.MessageResponse
+	if db.DismissAnomaly(userID, mux.Vars(r)["alertId"], pool) == nil {
+		response.Message = "Alert dismissed"
+	} else {
+		response.Message = "Alert could not be dismissed, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send alert response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Answers a question about the user's transactions, e.g. "how much did I spend on restaurants in
+// March vs February?", along with the plan it was turned into and the supporting results
+func HandleAsk(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var askRequest models.AskRequest
+	if err := json.NewDecoder(r.Body).Decode(&askRequest); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := app.AskQuestion(r.Context(), app.NewOpenAIChatModel(), app.PostgresTransactionRepository{Pool: pool}, userID, askRequest.Question, time.Now().UTC())
+	if errors.Is(err, app.ErrInvalidQueryPlan) {
+		http.Error(w, "Couldn't answer that question: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
+		log.Printf("Failed to answer question: %v\n", err)
+		http.Error(w, "Failed to answer question", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send answer response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/storage"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Lists the attachments of a transaction, or uploads one (on POST) from the "file" field of a
+// multipart form
+func HandleTransactionAttachments(w http.ResponseWriter, r *http.Request, store storage.BlobStore, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transactionId := mux.Vars(r)["transactionId"]
+	txns, err := db.FetchUserTransactionsByIds(userID, []string{transactionId}, pool)
+	if err != nil || len(txns) == 0 {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		// leave room for the multipart headers around the file
+		r.Body = http.MaxBytesReader(w, r.Body, app.MaxAttachmentSize+1<<20)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, app.ErrAttachmentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Missing file in multipart form", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, app.MaxAttachmentSize+1))
+		if err != nil {
+			http.Error(w, "Failed to read upload", http.StatusBadRequest)
+			return
+		}
+
+		attachment, err := app.SaveAttachment(r.Context(), userID, transactionId, header.Filename, data, store, pool)
+		switch {
+		case errors.Is(err, app.ErrAttachmentTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		case errors.Is(err, app.ErrAttachmentType):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		case err != nil:
+			log.Printf("Failed to save attachment: %v\n", err)
+			http.Error(w, "Failed to save attachment", http.StatusInternalServerError)
+			return
+		}
+
+		// Send JSON response to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(attachment); err != nil {
+			http.Error(w, "Failed to send attachment response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	attachments, err := db.FetchAttachments(userID, transactionId, pool)
+	if err != nil {
+		log.Printf("Failed to fetch attachments: %v\n", err)
+		http.Error(w, "Failed to fetch attachments", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(attachments); err != nil {
+		http.Error(w, "Failed to send attachments response", http.StatusInternalServerError)
+	}
+}
+
+// Downloads an attachment, or its thumbnail with ?thumbnail=true
+func HandleGetAttachment(w http.ResponseWriter, r *http.Request, store storage.BlobStore, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	attachment, err := db.FetchAttachment(userID, mux.Vars(r)["attachmentId"], pool)
+	if err != nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	key, contentType := attachment.StorageKey, attachment.ContentType
+	if r.URL.Query().Get("thumbnail") == "true" {
+		if !attachment.HasThumbnail {
+			http.Error(w, "Attachment has no thumbnail", http.StatusNotFound)
+			return
+		}
+		key, contentType = attachment.ThumbnailKey, "image/jpeg"
+	}
+
+	blob, err := store.Get(r.Context(), key)
+	if err != nil {
+		log.Printf("Failed to read attachment %s: %v\n", attachment.ID, err)
+		http.Error(w, "Failed to read attachment", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("Failed to send attachment %s: %v\n", attachment.ID, err)
+	}
+}
+
+func HandleDeleteAttachment(w http.ResponseWriter, r *http.Request, store storage.BlobStore, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	attachment, err := db.FetchAttachment(userID, mux.Vars(r)["attachmentId"], pool)
+	if err != nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	var attachmentResponse models.MessageResponse
+	if err := app.DeleteAttachment(r.Context(), attachment, store, pool); err == nil {
+		attachmentResponse.Message = "Attachment deleted successfully"
+	} else {
+		log.Printf("Failed to delete attachment %s: %v\n", attachment.ID, err)
+		attachmentResponse.Message = "Attachment could not be deleted, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(attachmentResponse); err != nil {
+		http.Error(w, "Failed to send attachment response", http.StatusInternalServerError)
+	}
+}
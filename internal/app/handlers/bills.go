@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// How far ahead the calendar feed lists bills
+const calendarFeedDays = 180
+
+var manualBillCadences = map[string]bool{"once": true, "weekly": true, "biweekly": true, "monthly": true, "quarterly": true, "annual": true}
+
+// Bills expected in the next ?days (default 30) along with the projected balance of each account
+func HandleGetUpcomingBills(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	days := 30
+	if param := r.URL.Query().Get("days"); param != "" {
+		var err error
+		days, err = strconv.Atoi(param)
+		if err != nil || days < 1 || days > 366 {
+			http.Error(w, "days must be between 1 and 366", http.StatusBadRequest)
+			return
+		}
+	}
+
+	upcoming, err := app.FetchUpcomingBills(userID, time.Now().UTC(), days, pool)
+	if err != nil {
+		log.Printf("Failed to fetch upcoming bills: %v\n", err)
+		http.Error(w, "Failed to fetch upcoming bills", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(upcoming); err != nil {
+		http.Error(w, "Failed to send bills response", http.StatusInternalServerError)
+	}
+}
+
+func HandleGetManualBills(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bills, err := db.FetchManualBills(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch manual bills: %v\n", err)
+		http.Error(w, "Failed to fetch bills", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bills); err != nil {
+		http.Error(w, "Failed to send bills response", http.StatusInternalServerError)
+	}
+}
+
+func validateManualBill(bill *models.ManualBill) error {
+	if bill.Name == "" {
+		return fmt.Errorf("bill name is required")
+	}
+	if bill.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+	if bill.Cadence == "" {
+		bill.Cadence = "monthly"
+	}
+	if !manualBillCadences[bill.Cadence] {
+		return fmt.Errorf("unknown cadence: %s", bill.Cadence)
+	}
+	if _, err := time.Parse(time.DateOnly, bill.NextDueDate); err != nil {
+		return fmt.Errorf("next_due_date must be formatted as YYYY-MM-DD")
+	}
+	if bill.Category == "" {
+		bill.Category = "Unknown"
+	}
+	return nil
+}
+
+// Creates a manual bill on POST and updates one on PUT /bills/manual/{billId}
+func HandleSaveManualBill(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var bill models.ManualBill
+	if err := json.NewDecoder(r.Body).Decode(&bill); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := validateManualBill(&bill); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bill.UserId = userID
+
+	var billResponse models.MessageResponse
+	var err error
+	if r.Method == http.MethodPut {
+		bill.ID = mux.Vars(r)["billId"]
+		err = db.UpdateManualBill(bill, pool)
+		billResponse.Message = "Bill updated successfully"
+	} else {
+		_, err = db.InsertManualBill(bill, pool)
+		billResponse.Message = "Bill created successfully"
+	}
+	if err != nil {
+		log.Printf("Failed to save manual bill: %v\n", err)
+		billResponse.Message = "Bill could not be saved, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(billResponse); err != nil {
+		http.Error(w, "Failed to send bill response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDeleteManualBill(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var billResponse models.MessageResponse
+	if db.DeleteManualBill(userID, mux.Vars(r)["billId"], pool) == nil {
+		billResponse.Message = "Bill deleted successfully"
+	} else {
+		billResponse.Message = "Bill could not be deleted, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(billResponse); err != nil {
+		http.Error(w, "Failed to send bill response", http.StatusInternalServerError)
+	}
+}
+
+// Issues a new secret calendar feed URL. Calling it again rotates the token so old URLs stop working
+func HandleCreateCalendarFeed(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokenBytes := make([]byte, 24)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+	if err := db.UpsertCalendarToken(userID, token, pool); err != nil {
+		log.Printf("Failed to save calendar token: %v\n", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	baseURL := os.Getenv("PUBLIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://" + r.Host
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.CalendarFeedResponse{URL: fmt.Sprintf("%s/bills/calendar/%s.ics", baseURL, token)}); err != nil {
+		http.Error(w, "Failed to send calendar feed response", http.StatusInternalServerError)
+	}
+}
+
+// Public .ics feed for calendar apps, which can't send a JWT. The token in the URL identifies the user
+func HandleGetCalendarFeed(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	userID, err := db.FetchUserIdByCalendarToken(mux.Vars(r)["token"], pool)
+	if err != nil {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UTC()
+	upcoming, err := app.FetchUpcomingBills(userID, now, calendarFeedDays, pool)
+	if err != nil {
+		log.Printf("Failed to build calendar feed: %v\n", err)
+		http.Error(w, "Failed to build calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="pocketwise-bills.ics"`)
+	if _, err := w.Write([]byte(app.BuildICalendar(upcoming.Bills, now))); err != nil {
+		log.Printf("Failed to write calendar feed: %v\n", err)
+	}
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Reads the {year} and {month} route variables of the /budgets/{year}/{month}/... routes
+func budgetMonthFromVars(r *http.Request) (int, int, error) {
+	vars := mux.Vars(r)
+	year, err := strconv.Atoi(vars["year"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid year: %s", vars["year"])
+	}
+	month, err := strconv.Atoi(vars["month"])
+	if err != nil || month < 1 || month > 12 {
+		return 0, 0, fmt.Errorf("invalid month: %s", vars["month"])
+	}
+	return year, month, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// Inserts a budget and turns the outcome into the message the client displays
+func insertBudgetWithMessage(budgetRequest models.BudgetRequest, pool *pgxpool.Pool) models.MessageResponse {
+	var budgetResponse models.MessageResponse
+	err := db.InsertNewBudget(budgetRequest, pool)
+	if err != nil {
+		log.Printf("Failed to insert budget: %v\n", err)
+		if isUniqueViolation(err) {
+			budgetResponse.Message = "Budget already exists for that month/year."
+		} else {
+			budgetResponse.Message = "Budget could not be created, please try again later."
+		}
+	} else {
+		budgetResponse.Message = "Budget created successfully"
+	}
+	return budgetResponse
+}
+
+func HandleCopyBudget(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	year, month, err := budgetMonthFromVars(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Read the request body, an empty body copies the previous month
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var copyRequest models.BudgetCopyRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &copyRequest); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	if copyRequest.Year == 0 || copyRequest.Month == 0 {
+		copyRequest.Year, copyRequest.Month = app.PreviousMonth(year, month)
+	}
+
+	source, err := db.FetchExistingBudget(models.BudgetRequest{UserId: userID, Year: copyRequest.Year, Month: copyRequest.Month}, pool)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("No budget found for %d/%d", copyRequest.Month, copyRequest.Year), http.StatusNotFound)
+		return
+	}
+
+	budgetResponse := insertBudgetWithMessage(source.Lines().ToBudgetRequest(userID, year, month), pool)
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(budgetResponse); err != nil {
+		http.Error(w, "Failed to send budget response", http.StatusInternalServerError)
+	}
+}
+
+// Proposes amounts for a month from the trailing average of actual spending. Nothing is saved,
+// the client can post the suggestion to /new-budget once the user is happy with it
+func HandleSuggestBudget(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	year, month, err := budgetMonthFromVars(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	months := 3
+	if param := r.URL.Query().Get("months"); param != "" {
+		months, err = strconv.Atoi(param)
+		if err != nil || months < 1 || months > 24 {
+			http.Error(w, "months must be between 1 and 24", http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestion, err := app.SuggestBudget(userID, year, month, months, pool)
+	if err != nil {
+		log.Printf("Failed to suggest budget: %v\n", err)
+		http.Error(w, "Failed to suggest budget", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(suggestion); err != nil {
+		http.Error(w, "Failed to send budget response", http.StatusInternalServerError)
+	}
+}
+
+func HandleApplyBudgetTemplate(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	year, month, err := budgetMonthFromVars(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := db.FetchBudgetTemplate(userID, mux.Vars(r)["templateId"], pool)
+	if err != nil {
+		http.Error(w, "Budget template not found", http.StatusNotFound)
+		return
+	}
+
+	budgetResponse := insertBudgetWithMessage(template.Lines().ToBudgetRequest(userID, year, month), pool)
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(budgetResponse); err != nil {
+		http.Error(w, "Failed to send budget response", http.StatusInternalServerError)
+	}
+}
+
+func HandleGetBudgetTemplates(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templates, err := db.FetchBudgetTemplates(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch budget templates: %v\n", err)
+		http.Error(w, "Failed to fetch budget templates", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(templates); err != nil {
+		http.Error(w, "Failed to send budget templates response", http.StatusInternalServerError)
+	}
+}
+
+func HandleAddBudgetTemplate(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Read the request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var templateRequest models.BudgetTemplateRequest
+	if err := json.Unmarshal(body, &templateRequest); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if templateRequest.Name == "" {
+		http.Error(w, "Template name is required", http.StatusBadRequest)
+		return
+	}
+
+	template := templateRequest.BudgetTemplate
+	// Saving an existing budget as a template takes its amounts, only the name comes from the body
+	if templateRequest.BudgetId != "" {
+		budget, err := db.FetchBudgetById(userID, templateRequest.BudgetId, pool)
+		if err != nil {
+			http.Error(w, "Budget not found", http.StatusNotFound)
+			return
+		}
+		template = budget.Lines().ToBudgetTemplate(userID, templateRequest.Name)
+	}
+	template.UserId = userID
+	template.Total = template.Lines().Total()
+
+	var templateResponse models.MessageResponse
+	template.ID, err = db.InsertBudgetTemplate(template, pool)
+	if err != nil {
+		log.Printf("Failed to insert budget template: %v\n", err)
+		if isUniqueViolation(err) {
+			templateResponse.Message = "A template with that name already exists."
+		} else {
+			templateResponse.Message = "Template could not be created, please try again later."
+		}
+	} else {
+		templateResponse.Message = "Template created successfully"
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(templateResponse); err != nil {
+		http.Error(w, "Failed to send budget template response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDeleteBudgetTemplate(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templateId := mux.Vars(r)["templateId"]
+	if templateId == "" {
+		http.Error(w, "Template ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var templateResponse models.MessageResponse
+	if db.DeleteBudgetTemplate(userID, templateId, pool) == nil {
+		templateResponse.Message = "Template deleted successfully"
+	} else {
+		templateResponse.Message = "Template could not be deleted, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(templateResponse); err != nil {
+		http.Error(w, "Failed to send budget template response", http.StatusInternalServerError)
+	}
+}
+
+// Returns or updates (on PUT) the opt-in for creating each month's budget automatically
+func HandleBudgetAutoCreateSettings(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var settings models.BudgetAutoCreateSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		settings.UserId = userID
+		if settings.Source == "" {
+			settings.Source = "previous_month"
+		}
+		switch settings.Source {
+		case "previous_month":
+			settings.TemplateId = ""
+		case "template":
+			if _, err := db.FetchBudgetTemplate(userID, settings.TemplateId, pool); err != nil {
+				http.Error(w, "Budget template not found", http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "source must be 'previous_month' or 'template'", http.StatusBadRequest)
+			return
+		}
+		if err := db.UpsertBudgetAutoCreateSettings(settings, pool); err != nil {
+			log.Printf("Failed to save budget auto create settings: %v\n", err)
+			http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	settings, err := db.FetchBudgetAutoCreateSettings(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch budget auto create settings: %v\n", err)
+		http.Error(w, "Failed to fetch settings", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		http.Error(w, "Failed to send settings response", http.StatusInternalServerError)
+	}
+}
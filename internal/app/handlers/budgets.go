@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/BBaCode/pocketwise-server/internal/app"
 	"github.com/BBaCode/pocketwise-server/internal/db"
 	"github.com/BBaCode/pocketwise-server/models"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -110,18 +115,12 @@ func HandleAddNewBudget(w http.ResponseWriter, r *http.Request, pool *pgxpool.Po
 	}
 	budgetRequest.UserId = userID
 
-	var budgetResponse models.MessageResponse
-	err = db.InsertNewBudget(budgetRequest, pool)
-	if err != nil {
-		fmt.Print(err)
-		if err.Error() == `ERROR: duplicate key value violates unique constraint "unique_month_year" (SQLSTATE 23505)` {
-			budgetResponse.Message = "Budget already exists for that month/year."
-		} else {
-			budgetResponse.Message = "Budget could not be created, please try again later."
-		}
-	} else {
-		budgetResponse.Message = "Budget created successfully"
+	if err := app.NormalizeBudgetPeriod(&budgetRequest.BudgetPeriod, &budgetRequest.Year, &budgetRequest.Month); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+
+	budgetResponse := insertBudgetWithMessage(budgetRequest, pool)
 	// Send JSON response to the client
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(budgetResponse); err != nil {
@@ -171,6 +170,13 @@ func HandleUpdateBudget(w http.ResponseWriter, r *http.Request, pool *pgxpool.Po
 	w.Header().Set("Access-Control-Allow-Methods", "DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	vars := mux.Vars(r)
 	budgetId := vars["budgetId"]
 
@@ -198,6 +204,21 @@ func HandleUpdateBudget(w http.ResponseWriter, r *http.Request, pool *pgxpool.Po
 		return
 	}
 
+	storedBudget, err := db.FetchBudgetById(userID, budgetId, pool)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to fetch budget: %v\n", err)
+		http.Error(w, "Failed to fetch budget", http.StatusInternalServerError)
+		return
+	}
+
+	if err := app.NormalizeBudgetUpdatePeriod(&updateBudgetRequest, storedBudget); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var budgetResponse models.MessageResponse
 	if db.UpdateExistingBudget(budgetId, updateBudgetRequest, pool) == nil {
 		budgetResponse.Message = "Budget updated successfully"
@@ -211,3 +232,91 @@ func HandleUpdateBudget(w http.ResponseWriter, r *http.Request, pool *pgxpool.Po
 		http.Error(w, "Failed to send accounts response", http.StatusInternalServerError)
 	}
 }
+
+// Reads the optional ?date=YYYY-MM-DD query param, defaulting to today
+func dateFromQuery(r *http.Request) (time.Time, error) {
+	param := r.URL.Query().Get("date")
+	if param == "" {
+		now := time.Now().UTC()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Parse(time.DateOnly, param)
+}
+
+// Resolves which budgets (one per period type, plus any custom ranges) apply on a given date
+func HandleGetActiveBudgets(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	date, err := dateFromQuery(r)
+	if err != nil {
+		http.Error(w, "date must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	activeBudgets, err := app.FetchActiveBudgets(userID, date, pool)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unexpected error fetching budgets: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(activeBudgets); err != nil {
+		http.Error(w, "Failed to send budgets response", http.StatusInternalServerError)
+	}
+}
+
+// Spending against a budget for the period window containing ?date. Passing ?view=month prorates
+// quarterly, yearly and long custom budgets down to the calendar month of the date
+func HandleGetBudgetProgress(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	budgetId := mux.Vars(r)["budgetId"]
+	if budgetId == "" {
+		http.Error(w, "Budget ID is required", http.StatusBadRequest)
+		return
+	}
+
+	date, err := dateFromQuery(r)
+	if err != nil {
+		http.Error(w, "date must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := app.FetchBudgetProgress(userID, budgetId, date, r.URL.Query().Get("view") == "month", pool)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, app.ErrBudgetNotActive) {
+		http.Error(w, "Budget is not active on that date", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("Failed to calculate budget progress: %v\n", err)
+		http.Error(w, "Failed to calculate budget progress", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(progress); err != nil {
+		http.Error(w, "Failed to send budget progress response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The user's monthly digests, newest first
+func HandleGetDigests(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	digests, err := db.FetchMonthlyDigests(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch digests: %v\n", err)
+		http.Error(w, "Failed to fetch digests", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(digests); err != nil {
+		http.Error(w, "Failed to send digests response", http.StatusInternalServerError)
+	}
+}
+
+// The digest of a finished month (YYYY-MM), written on the spot when the monthly job hasn't
+// written it yet
+func HandleGetDigest(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	monthStart, err := time.Parse("2006-01", mux.Vars(r)["month"])
+	if err != nil {
+		http.Error(w, "month must be formatted as YYYY-MM", http.StatusBadRequest)
+		return
+	}
+	if !monthStart.AddDate(0, 1, 0).Before(time.Now().UTC()) {
+		http.Error(w, "Digests are only available for finished months", http.StatusBadRequest)
+		return
+	}
+
+	digest, _, err := app.GenerateMonthlyDigest(r.Context(), app.NewChatModelFromEnv(), userID, monthStart, pool)
+	if errors.Is(err, app.ErrNoDigestActivity) {
+		http.Error(w, "Digest not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to generate digest: %v\n", err)
+		http.Error(w, "Failed to generate digest", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(digest); err != nil {
+		http.Error(w, "Failed to send digest response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Suspected duplicate transactions from the last few months, for the user to merge or dismiss
+func HandleGetDuplicates(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	duplicates, err := app.FetchDuplicates(userID, time.Now().UTC(), pool)
+	if err != nil {
+		log.Printf("Failed to find duplicates: %v\n", err)
+		http.Error(w, "Failed to find duplicates", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(duplicates); err != nil {
+		http.Error(w, "Failed to send duplicates response", http.StatusInternalServerError)
+	}
+}
+
+func HandleMergeDuplicates(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var mergeRequest models.MergeDuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&mergeRequest); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if mergeRequest.KeepId == "" || mergeRequest.RemoveId == "" || mergeRequest.KeepId == mergeRequest.RemoveId {
+		http.Error(w, "keep_id and remove_id must be two different transactions", http.StatusBadRequest)
+		return
+	}
+
+	var response models.MessageResponse
+	err := app.MergeDuplicates(userID, mergeRequest, time.Now().UTC(), pool)
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to merge duplicates: %v\n", err)
+		response.Message = "Transactions could not be merged, please try again later."
+	} else {
+		response.Message = "Transactions merged"
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send merge response", http.StatusInternalServerError)
+	}
+}
+
+// Marks two transactions as not being duplicates so they aren't suggested again
+func HandleDismissDuplicate(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var dismissRequest models.DismissDuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&dismissRequest); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	ids := dismissRequest.TransactionIds
+	if ids[0] == "" || ids[1] == "" || ids[0] == ids[1] {
+		http.Error(w, "transaction_ids must be two different transactions", http.StatusBadRequest)
+		return
+	}
+	txns, err := db.FetchUserTransactionsByIds(userID, ids[:], pool)
+	if err != nil || len(txns) != 2 {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	if ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+
+	var response models.MessageResponse
+	if err := db.InsertDuplicateDismissal(userID, ids, pool); err == nil {
+		response.Message = "Duplicate dismissed"
+	} else {
+		log.Printf("Failed to dismiss duplicate: %v\n", err)
+		response.Message = "Duplicate could not be dismissed, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send duplicate response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/exporter"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Remembers whether any of the response was sent, after which errors can't be reported with a status
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Downloads the user's transactions as csv, ofx, json, ledger or beancount. See app.ParseExportQuery
+// for the query parameters. The export is streamed as it's read from the database
+func HandleExport(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format, query, err := app.ParseExportQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", exporter.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, app.ExportFilename(format)))
+	out := &startedWriter{ResponseWriter: w}
+	err = app.ExportTransactions(r.Context(), out, userID, format, query, time.Now(), pool)
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to export transactions: %v\n", err)
+	if out.started {
+		return
+	}
+	w.Header().Del("Content-Disposition")
+	if errors.Is(err, db.ErrAccountNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Failed to export transactions", http.StatusInternalServerError)
+}
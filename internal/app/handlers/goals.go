@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Checks a goal request against the user's accounts and the other goals sharing the account
+func validateGoalRequest(userID string, goalId string, goalRequest *models.GoalRequest, pool *pgxpool.Pool) error {
+	if goalRequest.Name == "" {
+		return fmt.Errorf("goal name is required")
+	}
+	if goalRequest.TargetAmount <= 0 {
+		return fmt.Errorf("target_amount must be greater than 0")
+	}
+	if _, err := time.Parse(time.DateOnly, goalRequest.TargetDate); err != nil {
+		return fmt.Errorf("target_date must be formatted as YYYY-MM-DD")
+	}
+	if goalRequest.AllocationPercent == 0 {
+		goalRequest.AllocationPercent = 100
+	}
+	if goalRequest.AllocationPercent < 0 || goalRequest.AllocationPercent > 100 {
+		return fmt.Errorf("allocation_percent must be between 0 and 100")
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, account := range accounts {
+		if account.ID == goalRequest.AccountId {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("account not found")
+	}
+
+	allocated, err := db.FetchAllocatedPercent(goalRequest.AccountId, goalId, pool)
+	if err != nil {
+		return err
+	}
+	if allocated+goalRequest.AllocationPercent > 100 {
+		return fmt.Errorf("only %.0f%% of this account is left to allocate", 100-allocated)
+	}
+	return nil
+}
+
+func HandleGetGoals(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	goals, err := db.FetchGoals(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch goals: %v\n", err)
+		http.Error(w, "Failed to fetch goals", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(goals); err != nil {
+		http.Error(w, "Failed to send goals response", http.StatusInternalServerError)
+	}
+}
+
+func HandleGetGoal(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	goal, err := db.FetchGoal(userID, mux.Vars(r)["goalId"], pool)
+	if err != nil {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(goal); err != nil {
+		http.Error(w, "Failed to send goal response", http.StatusInternalServerError)
+	}
+}
+
+func HandleAddGoal(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var goalRequest models.GoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&goalRequest); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := validateGoalRequest(userID, "", &goalRequest, pool); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	goalId, err := db.InsertGoal(models.Goal{
+		UserId:            userID,
+		Name:              goalRequest.Name,
+		TargetAmount:      goalRequest.TargetAmount,
+		TargetDate:        goalRequest.TargetDate,
+		AccountId:         goalRequest.AccountId,
+		AllocationPercent: goalRequest.AllocationPercent,
+	}, pool)
+	if err != nil {
+		log.Printf("Failed to insert goal: %v\n", err)
+		http.Error(w, "Goal could not be created, please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	writeRecalculatedGoal(w, userID, goalId, pool)
+}
+
+func HandleUpdateGoal(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	goalId := mux.Vars(r)["goalId"]
+	if _, err := db.FetchGoal(userID, goalId, pool); err != nil {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+
+	var goalRequest models.GoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&goalRequest); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := validateGoalRequest(userID, goalId, &goalRequest, pool); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := db.UpdateGoal(models.Goal{
+		ID:                goalId,
+		UserId:            userID,
+		Name:              goalRequest.Name,
+		TargetAmount:      goalRequest.TargetAmount,
+		TargetDate:        goalRequest.TargetDate,
+		AccountId:         goalRequest.AccountId,
+		AllocationPercent: goalRequest.AllocationPercent,
+	}, pool)
+	if err != nil {
+		log.Printf("Failed to update goal: %v\n", err)
+		http.Error(w, "Goal could not be updated, please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	writeRecalculatedGoal(w, userID, goalId, pool)
+}
+
+// Recalculates the user's goals so a created or changed goal comes back with up to date progress
+func writeRecalculatedGoal(w http.ResponseWriter, userID string, goalId string, pool *pgxpool.Pool) {
+	goals, err := app.RecalculateGoals(userID, time.Now().UTC(), pool)
+	if err != nil {
+		log.Printf("Failed to recalculate goals: %v\n", err)
+	}
+
+	var goal models.Goal
+	for _, g := range goals {
+		if g.ID == goalId {
+			goal = g
+		}
+	}
+	if goal.ID == "" {
+		goal, err = db.FetchGoal(userID, goalId, pool)
+		if err != nil {
+			http.Error(w, "Goal not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(goal); err != nil {
+		http.Error(w, "Failed to send goal response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDeleteGoal(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var goalResponse models.MessageResponse
+	if db.DeleteGoal(userID, mux.Vars(r)["goalId"], pool) == nil {
+		goalResponse.Message = "Goal deleted successfully"
+	} else {
+		goalResponse.Message = "Goal could not be deleted, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(goalResponse); err != nil {
+		http.Error(w, "Failed to send goal response", http.StatusInternalServerError)
+	}
+}
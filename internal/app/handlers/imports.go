@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Formats the "format" form field takes. QFX is Quicken's name for OFX
+var importFormats = map[string]string{
+	"csv": models.ImportFormatCSV,
+	"ofx": models.ImportFormatOFX,
+	"qfx": models.ImportFormatOFX,
+	"qif": models.ImportFormatQIF,
+}
+
+// Uploads a CSV, OFX/QFX or QIF file from the "file" field of a multipart form into the account in
+// "account_id", and returns a preview of what it would add. Nothing is imported until the preview is
+// committed. CSV files are read with the mapping in "mapping" (JSON) or the saved profile in
+// "profile_id", or one suggested from the header; "save_profile" saves the mapping under that name
+func HandleImport(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// leave room for the multipart headers and the other fields around the file
+	r.Body = http.MaxBytesReader(w, r.Body, app.MaxImportSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Import files can be at most %d MB", app.MaxImportSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Missing file in multipart form", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, app.MaxImportSize+1))
+	if err != nil {
+		http.Error(w, "Failed to read upload", http.StatusBadRequest)
+		return
+	}
+	if len(data) > app.MaxImportSize {
+		http.Error(w, fmt.Sprintf("Import files can be at most %d MB", app.MaxImportSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	accountId := r.FormValue("account_id")
+	if _, err := db.FetchUserAccount(userID, accountId, pool); err != nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	var format string
+	if value := strings.ToLower(strings.TrimSpace(r.FormValue("format"))); value != "" {
+		var ok bool
+		if format, ok = importFormats[value]; !ok {
+			http.Error(w, "format must be csv, ofx, qfx or qif", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var mapping *models.CSVMapping
+	if value := r.FormValue("mapping"); value != "" {
+		mapping = &models.CSVMapping{}
+		if err := json.Unmarshal([]byte(value), mapping); err != nil {
+			http.Error(w, "Invalid JSON in mapping", http.StatusBadRequest)
+			return
+		}
+	} else if profileId := r.FormValue("profile_id"); profileId != "" {
+		profile, err := db.FetchImportProfile(userID, profileId, pool)
+		if err != nil {
+			http.Error(w, "Import profile not found", http.StatusNotFound)
+			return
+		}
+		mapping = &profile.Mapping
+	}
+
+	preview, err := app.PreviewImport(userID, accountId, header.Filename, format, data, mapping, pool)
+	switch {
+	case errors.Is(err, app.ErrInvalidImportMapping):
+		// send the columns back so the user can fix the mapping
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			http.Error(w, "Failed to send import response", http.StatusInternalServerError)
+		}
+		return
+	case errors.Is(err, app.ErrUnreadableImport):
+		http.Error(w, "Couldn't read the file: "+err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Failed to preview import: %v\n", err)
+		http.Error(w, "Failed to preview import", http.StatusInternalServerError)
+		return
+	}
+
+	if name := strings.TrimSpace(r.FormValue("save_profile")); name != "" && preview.Mapping != nil {
+		profile := models.ImportProfile{UserId: userID, Name: name, Mapping: *preview.Mapping}
+		if _, err := db.UpsertImportProfile(profile, pool); err != nil {
+			log.Printf("Failed to save import profile: %v\n", err)
+		}
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(preview); err != nil {
+		http.Error(w, "Failed to send import response", http.StatusInternalServerError)
+	}
+}
+
+// Adds the transactions of a previewed import to its account
+func HandleCommitImport(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	record, err := app.CommitImport(userID, mux.Vars(r)["importId"], time.Now().UTC(), pool)
+	switch {
+	case errors.Is(err, db.ErrImportNotFound):
+		http.Error(w, "Import not found", http.StatusNotFound)
+		return
+	case errors.Is(err, app.ErrImportNotPending):
+		http.Error(w, "Import was already committed", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Failed to commit import: %v\n", err)
+		http.Error(w, "Import could not be committed, please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(record); err != nil {
+		http.Error(w, "Failed to send import response", http.StatusInternalServerError)
+	}
+}
+
+// Removes the transactions a committed import added
+func HandleUndoImport(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var response models.MessageResponse
+	removed, err := app.UndoImport(userID, mux.Vars(r)["importId"], time.Now().UTC(), pool)
+	if errors.Is(err, db.ErrImportNotFound) {
+		http.Error(w, "Committed import not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to undo import: %v\n", err)
+		response.Message = "Import could not be undone, please try again later."
+	} else {
+		response.Message = fmt.Sprintf("Import undone, %d transactions removed", removed)
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send import response", http.StatusInternalServerError)
+	}
+}
+
+// The user's committed and undone imports, newest first
+func HandleGetImports(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	imports, err := db.FetchImports(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch imports: %v\n", err)
+		http.Error(w, "Failed to fetch imports", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(imports); err != nil {
+		http.Error(w, "Failed to send imports response", http.StatusInternalServerError)
+	}
+}
+
+// Lists the user's saved CSV mappings, or saves one (on POST). Saving a profile under an existing
+// name replaces its mapping
+func HandleImportProfiles(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var profile models.ImportProfile
+		if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		profile.Name = strings.TrimSpace(profile.Name)
+		if profile.Name == "" {
+			http.Error(w, "Profile name is required", http.StatusBadRequest)
+			return
+		}
+		if profile.Mapping.Date == "" || (profile.Mapping.Amount == "" && profile.Mapping.Debit == "" && profile.Mapping.Credit == "") {
+			http.Error(w, "The mapping needs a date column and an amount, or debit and credit, column", http.StatusBadRequest)
+			return
+		}
+		profile.UserId = userID
+
+		id, err := db.UpsertImportProfile(profile, pool)
+		if err != nil {
+			log.Printf("Failed to save import profile: %v\n", err)
+			http.Error(w, "Import profile could not be saved, please try again later.", http.StatusInternalServerError)
+			return
+		}
+		profile.ID = id
+
+		// Send JSON response to the client
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(profile); err != nil {
+			http.Error(w, "Failed to send import profile response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	profiles, err := db.FetchImportProfiles(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch import profiles: %v\n", err)
+		http.Error(w, "Failed to fetch import profiles", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(profiles); err != nil {
+		http.Error(w, "Failed to send import profiles response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDeleteImportProfile(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var response models.MessageResponse
+	err := db.DeleteImportProfile(userID, mux.Vars(r)["profileId"], pool)
+	if errors.Is(err, db.ErrProfileNotFound) {
+		http.Error(w, "Import profile not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to delete import profile: %v\n", err)
+		response.Message = "Import profile could not be deleted, please try again later."
+	} else {
+		response.Message = "Import profile deleted"
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send import profile response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Creates (POST) or updates (PUT) an account the user keeps up to date by hand
+func HandleSaveManualAccount(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("Invalid user ID: %v\n", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var accountRequest models.ManualAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&accountRequest); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	accountId := mux.Vars(r)["accountId"]
+	if r.Method == http.MethodPut {
+		if _, err := db.FetchManualAccount(userID, accountId, pool); err != nil {
+			http.Error(w, "Manual account not found", http.StatusNotFound)
+			return
+		}
+	}
+	account, err := app.ManualAccountFromRequest(userUUID, accountId, accountRequest, time.Now().UTC())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		err = db.UpdateManualAccount(account, pool)
+	} else {
+		err = db.InsertManualAccount(account, pool)
+	}
+	if err != nil {
+		log.Printf("Failed to save manual account: %v\n", err)
+		http.Error(w, "Account could not be saved, please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(account); err != nil {
+		http.Error(w, "Failed to send account response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDeleteManualAccount(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := db.DeleteManualAccount(userID, mux.Vars(r)["accountId"], pool)
+	if errors.Is(err, db.ErrAccountNotFound) {
+		http.Error(w, "Manual account not found", http.StatusNotFound)
+		return
+	}
+
+	var accountResponse models.MessageResponse
+	if err == nil {
+		accountResponse.Message = "Account deleted successfully"
+	} else {
+		log.Printf("Failed to delete manual account: %v\n", err)
+		accountResponse.Message = "Account could not be deleted, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(accountResponse); err != nil {
+		http.Error(w, "Failed to send account response", http.StatusInternalServerError)
+	}
+}
+
+// Adds (POST) or edits (PUT) a transaction in a manual account. The account balance moves with
+// the transaction, and transactions without a category go through the usual categorizer
+func HandleSaveManualTransaction(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountId := mux.Vars(r)["accountId"]
+	if _, err := db.FetchManualAccount(userID, accountId, pool); err != nil {
+		http.Error(w, "Manual account not found", http.StatusNotFound)
+		return
+	}
+
+	var txn models.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&txn); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	if err := app.NormalizeManualTransaction(&txn, now); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	txn.AccountID = accountId
+	if txn.Category == "" {
+		categorized, err := app.CategorizeWithPayeeHistory(userID, txn, pool)
+		if err != nil {
+			log.Printf("Failed to categorize manual transaction: %v\n", err)
+			categorized.Category = "Unknown"
+		}
+		txn = categorized
+	}
+
+	var err error
+	if r.Method == http.MethodPut {
+		txn.ID = mux.Vars(r)["transactionId"]
+		err = db.UpdateManualTransaction(txn, now.Unix(), pool)
+	} else {
+		txn.ID = "MAN-TRN-" + uuid.NewString()
+		err = db.InsertManualTransaction(txn, now.Unix(), pool)
+	}
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to save manual transaction: %v\n", err)
+		http.Error(w, "Transaction could not be saved, please try again later.", http.StatusInternalServerError)
+		return
+	}
+	if _, err := app.AssignMerchants(userID, pool); err != nil {
+		log.Printf("Failed to assign merchants: %v\n", err)
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(txn); err != nil {
+		http.Error(w, "Failed to send transaction response", http.StatusInternalServerError)
+	}
+}
+
+func HandleDeleteManualTransaction(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountId := mux.Vars(r)["accountId"]
+	if _, err := db.FetchManualAccount(userID, accountId, pool); err != nil {
+		http.Error(w, "Manual account not found", http.StatusNotFound)
+		return
+	}
+
+	err := db.DeleteManualTransaction(accountId, mux.Vars(r)["transactionId"], time.Now().UTC().Unix(), pool)
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	var txnResponse models.MessageResponse
+	if err == nil {
+		txnResponse.Message = "Transaction deleted successfully"
+	} else {
+		log.Printf("Failed to delete manual transaction: %v\n", err)
+		txnResponse.Message = "Transaction could not be deleted, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(txnResponse); err != nil {
+		http.Error(w, "Failed to send transaction response", http.StatusInternalServerError)
+	}
+}
+
+// Assets, liabilities and net worth across synced and manual accounts
+func HandleGetNetWorth(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("Invalid user ID: %v\n", err)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch accounts: %v\n", err)
+		http.Error(w, "Failed to fetch accounts", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(app.CalculateNetWorth(accounts)); err != nil {
+		http.Error(w, "Failed to send net worth response", http.StatusInternalServerError)
+	}
+}
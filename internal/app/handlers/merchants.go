@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Lists the user's merchants with their aliases and how many transactions each has
+func HandleGetMerchants(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// pick up transactions that came in before merchants existed
+	if _, err := app.AssignMerchants(userID, pool); err != nil {
+		log.Printf("Failed to assign merchants: %v\n", err)
+	}
+
+	merchants, err := db.FetchMerchants(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch merchants: %v\n", err)
+		http.Error(w, "Failed to fetch merchants", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(merchants); err != nil {
+		http.Error(w, "Failed to send merchants response", http.StatusInternalServerError)
+	}
+}
+
+// Renames a merchant and sets the category all of its transactions get
+func HandleUpdateMerchant(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var merchant models.Merchant
+	if err := json.NewDecoder(r.Body).Decode(&merchant); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	merchant.Name = strings.TrimSpace(merchant.Name)
+	if merchant.Name == "" {
+		http.Error(w, "Merchant name is required", http.StatusBadRequest)
+		return
+	}
+	merchant.ID = mux.Vars(r)["merchantId"]
+	merchant.UserId = userID
+
+	var response models.MessageResponse
+	if err := db.UpdateMerchant(merchant, app.MerchantKey(merchant.Name), pool); err == nil {
+		response.Message = "Merchant updated"
+	} else {
+		log.Printf("Failed to update merchant: %v\n", err)
+		response.Message = "Merchant could not be updated, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send merchant response", http.StatusInternalServerError)
+	}
+}
+
+// Adds (POST) or removes (DELETE) a payee alias of a merchant. Adding an alias that already has a
+// merchant of its own merges that merchant into this one
+func HandleMerchantAlias(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	merchantId := mux.Vars(r)["merchantId"]
+	var response models.MessageResponse
+	if r.Method == http.MethodDelete {
+		key := app.MerchantKey(app.NormalizeMerchantName(mux.Vars(r)["alias"]))
+		if err := db.DeleteMerchantAlias(userID, merchantId, key, pool); err == nil {
+			response.Message = "Alias removed"
+		} else {
+			log.Printf("Failed to delete merchant alias: %v\n", err)
+			response.Message = "Alias could not be removed, please try again later."
+		}
+	} else {
+		var aliasRequest models.MerchantAliasRequest
+		if err := json.NewDecoder(r.Body).Decode(&aliasRequest); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		// aliases are stored the way payees normalize, so the raw bank spelling works too
+		alias := app.NormalizeMerchantName(aliasRequest.Alias)
+		if alias == "" {
+			http.Error(w, "Alias is required", http.StatusBadRequest)
+			return
+		}
+		if err := db.AddMerchantAlias(userID, merchantId, alias, app.MerchantKey(alias), pool); err == nil {
+			response.Message = "Alias added"
+		} else {
+			log.Printf("Failed to add merchant alias: %v\n", err)
+			response.Message = "Alias could not be added, please try again later."
+		}
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send merchant alias response", http.StatusInternalServerError)
+	}
+}
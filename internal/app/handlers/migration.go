@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrates the transactions export of Mint, YNAB or Monarch (the source in the path) from the
+// "file" field of a multipart form. Running it again with the same export only adds what's missing
+func HandleMigrate(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	source := mux.Vars(r)["source"]
+	if !app.IsMigrationSource(source) {
+		http.Error(w, "Unknown migration source", http.StatusNotFound)
+		return
+	}
+
+	// leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, app.MaxImportSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Import files can be at most %d MB", app.MaxImportSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Missing file in multipart form", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, app.MaxImportSize+1))
+	if err != nil {
+		http.Error(w, "Failed to read upload", http.StatusBadRequest)
+		return
+	}
+	if len(data) > app.MaxImportSize {
+		http.Error(w, fmt.Sprintf("Import files can be at most %d MB", app.MaxImportSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := app.RunMigration(userID, source, header.Filename, data, time.Now().UTC(), pool)
+	if errors.Is(err, app.ErrUnreadableImport) {
+		http.Error(w, "Couldn't read the file: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("Failed to migrate from %s: %v\n", source, err)
+		http.Error(w, "Migration failed, please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Failed to send migration response", http.StatusInternalServerError)
+	}
+}
+
+// Lists how the source's categories map onto ours, or saves the user's changes (on PUT). Mapping a
+// category to "" goes back to the default
+func HandleCategoryMappings(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	source := mux.Vars(r)["source"]
+	if !app.IsMigrationSource(source) {
+		http.Error(w, "Unknown migration source", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var mappings []models.CategoryMapping
+		if err := json.NewDecoder(r.Body).Decode(&mappings); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		validated, err := app.ValidateCategoryMappings(mappings)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := db.UpdateCategoryMappings(userID, source, validated, pool); err != nil {
+			log.Printf("Failed to save category mappings: %v\n", err)
+			http.Error(w, "Category mappings could not be saved, please try again later.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	custom, err := db.FetchCategoryMappings(userID, source, pool)
+	if err != nil {
+		log.Printf("Failed to fetch category mappings: %v\n", err)
+		http.Error(w, "Failed to fetch category mappings", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(app.CategoryMappings(source, custom)); err != nil {
+		http.Error(w, "Failed to send category mappings response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/notify"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// In-app inbox. Pass ?unread=true to only get unread notifications
+func HandleGetNotifications(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notifications, err := db.FetchNotifications(userID, r.URL.Query().Get("unread") == "true", pool)
+	if err != nil {
+		log.Printf("Failed to fetch notifications: %v\n", err)
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(notifications); err != nil {
+		http.Error(w, "Failed to send notifications response", http.StatusInternalServerError)
+	}
+}
+
+func HandleMarkNotificationRead(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var response models.MessageResponse
+	if db.MarkNotificationRead(userID, mux.Vars(r)["notificationId"], pool) == nil {
+		response.Message = "Notification marked as read"
+	} else {
+		response.Message = "Notification could not be updated, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send notification response", http.StatusInternalServerError)
+	}
+}
+
+// Returns or updates (on PUT) how a user wants to be notified
+func HandleNotificationPreferences(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var prefs models.NotificationPreferences
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		prefs.UserId = userID
+		if prefs.Timezone == "" {
+			prefs.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+			http.Error(w, "Unknown timezone", http.StatusBadRequest)
+			return
+		}
+		if prefs.QuietHoursStart < 0 || prefs.QuietHoursStart > 23 || prefs.QuietHoursEnd < 0 || prefs.QuietHoursEnd > 23 {
+			http.Error(w, "Quiet hours must be between 0 and 23", http.StatusBadRequest)
+			return
+		}
+		if prefs.WebhookURL != "" {
+			if err := notify.ValidateWebhookURL(r.Context(), prefs.WebhookURL); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		// the secret is never sent back to the client, so an empty one keeps what was saved before
+		if prefs.WebhookSecret == "" {
+			existing, err := db.FetchNotificationPreferences(userID, pool)
+			if err == nil {
+				prefs.WebhookSecret = existing.WebhookSecret
+			}
+		}
+		if err := db.UpsertNotificationPreferences(prefs, pool); err != nil {
+			log.Printf("Failed to save notification preferences: %v\n", err)
+			http.Error(w, "Failed to save notification preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	prefs, err := db.FetchNotificationPreferences(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch notification preferences: %v\n", err)
+		http.Error(w, "Failed to fetch notification preferences", http.StatusInternalServerError)
+		return
+	}
+	// the webhook secret is write only
+	prefs.WebhookSecret = ""
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(prefs); err != nil {
+		http.Error(w, "Failed to send notification preferences response", http.StatusInternalServerError)
+	}
+}
+
+// Returns or replaces (on PUT) the overspend alert thresholds of a budget
+func HandleBudgetAlertThresholds(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	budgetId := mux.Vars(r)["budgetId"]
+	if _, err := db.FetchBudgetById(userID, budgetId, pool); err != nil {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var thresholdsRequest models.BudgetAlertThresholdsRequest
+		if err := json.NewDecoder(r.Body).Decode(&thresholdsRequest); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		fields := models.StoredBudget{}.Lines()
+		for _, threshold := range thresholdsRequest.Thresholds {
+			if _, ok := fields[threshold.Field]; !ok && threshold.Field != "total" {
+				http.Error(w, "Unknown budget field: "+threshold.Field, http.StatusBadRequest)
+				return
+			}
+			if threshold.Percent <= 0 {
+				http.Error(w, "Threshold percent must be greater than 0", http.StatusBadRequest)
+				return
+			}
+		}
+		if err := db.ReplaceBudgetAlertThresholds(userID, budgetId, thresholdsRequest.Thresholds, pool); err != nil {
+			log.Printf("Failed to save budget alert thresholds: %v\n", err)
+			http.Error(w, "Failed to save budget alert thresholds", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	thresholds, err := db.FetchBudgetAlertThresholds(userID, budgetId, pool)
+	if err != nil {
+		log.Printf("Failed to fetch budget alert thresholds: %v\n", err)
+		http.Error(w, "Failed to fetch budget alert thresholds", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.BudgetAlertThresholdsRequest{Thresholds: thresholds}); err != nil {
+		http.Error(w, "Failed to send budget alert thresholds response", http.StatusInternalServerError)
+	}
+}
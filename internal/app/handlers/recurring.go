@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Detected subscriptions, bills and paychecks. Pass ?include_dismissed=true to also get the
+// series the user dismissed
+func HandleGetRecurring(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	series, err := app.FetchRecurringSeries(userID, time.Now().UTC(), r.URL.Query().Get("include_dismissed") == "true", pool)
+	if err != nil {
+		log.Printf("Failed to detect recurring transactions: %v\n", err)
+		http.Error(w, "Failed to fetch recurring transactions", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(series); err != nil {
+		http.Error(w, "Failed to send recurring response", http.StatusInternalServerError)
+	}
+}
+
+// Confirms or dismisses a detected series: POST /recurring/{seriesId}/confirm or /dismiss
+func HandleReviewRecurring(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	var review string
+	switch vars["action"] {
+	case "confirm":
+		review = models.RecurringReviewConfirmed
+	case "dismiss":
+		review = models.RecurringReviewDismissed
+	default:
+		http.Error(w, "Unknown action", http.StatusNotFound)
+		return
+	}
+
+	var response models.MessageResponse
+	if err := db.UpsertRecurringReview(userID, vars["seriesId"], review, pool); err != nil {
+		log.Printf("Failed to save recurring review: %v\n", err)
+		response.Message = "Recurring transaction could not be updated, please try again later."
+	} else {
+		response.Message = "Recurring transaction " + review
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send recurring response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Spending totals per ?interval (month or week, default month) and ?group_by (category, merchant,
+// account or tag, default category) between ?from and ?to (YYYY-MM-DD, default the last six months)
+func HandleGetSpendingReport(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	groupBy := query.Get("group_by")
+	switch groupBy {
+	case "":
+		groupBy = models.ReportGroupByCategory
+	case models.ReportGroupByCategory, models.ReportGroupByMerchant, models.ReportGroupByAccount, models.ReportGroupByTag:
+	default:
+		http.Error(w, "group_by must be one of category, merchant, account or tag", http.StatusBadRequest)
+		return
+	}
+	interval := query.Get("interval")
+	switch interval {
+	case "":
+		interval = models.ReportIntervalMonth
+	case models.ReportIntervalMonth, models.ReportIntervalWeek:
+	default:
+		http.Error(w, "interval must be month or week", http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now().UTC()
+	if param := query.Get("to"); param != "" {
+		var err error
+		if to, err = time.Parse(time.DateOnly, param); err != nil {
+			http.Error(w, "to must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	from := app.ReportPeriodStart(to, models.ReportIntervalMonth).AddDate(0, -5, 0)
+	if param := query.Get("from"); param != "" {
+		var err error
+		if from, err = time.Parse(time.DateOnly, param); err != nil {
+			http.Error(w, "from must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if from.After(to) {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	report, err := app.FetchSpendingReport(userID, from, to, groupBy, interval, pool)
+	if err != nil {
+		log.Printf("Failed to build spending report: %v\n", err)
+		http.Error(w, "Failed to build spending report", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Failed to send spending report response", http.StatusInternalServerError)
+	}
+}
+
+// Monthly income against expenses for ?year (default the current one). Pass ?format=csv to
+// download the year as a spreadsheet instead of JSON
+func HandleGetCashFlow(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	year := time.Now().UTC().Year()
+	if param := r.URL.Query().Get("year"); param != "" {
+		var err error
+		year, err = strconv.Atoi(param)
+		if err != nil || year < 1970 || year > 9999 {
+			http.Error(w, "year must be a valid year", http.StatusBadRequest)
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	statement, err := app.FetchCashFlowStatement(userID, year, pool)
+	if err != nil {
+		log.Printf("Failed to build cash flow statement: %v\n", err)
+		http.Error(w, "Failed to build cash flow statement", http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="cash-flow-%d.csv"`, year))
+		if err := app.WriteCashFlowCSV(w, statement); err != nil {
+			log.Printf("Failed to write cash flow csv: %v\n", err)
+		}
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statement); err != nil {
+		http.Error(w, "Failed to send cash flow response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/simplefin"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Connects the user's own SimpleFIN bridge from a setup token (POST), or revokes the server's
+// access to it by forgetting its access URL (DELETE). Syncs use the user's connection from then on
+func HandleSimpleFINConnection(w http.ResponseWriter, r *http.Request, sf *simplefin.Client, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var response models.MessageResponse
+	if r.Method == http.MethodDelete {
+		err := db.DeleteSimpleFINConnection(userID, pool)
+		if errors.Is(err, db.ErrSimpleFINNotConnected) {
+			http.Error(w, "No SimpleFIN connection", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Failed to delete SimpleFIN connection: %v\n", err)
+			http.Error(w, "SimpleFIN could not be disconnected, please try again later.", http.StatusInternalServerError)
+			return
+		}
+		response.Message = "SimpleFIN disconnected"
+	} else {
+		var request models.SimpleFINConnectRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.SetupToken == "" {
+			http.Error(w, "setup_token is required", http.StatusBadRequest)
+			return
+		}
+		accessURL, err := sf.Claim(r.Context(), request.SetupToken)
+		if errors.Is(err, simplefin.ErrInvalidSetupToken) || errors.Is(err, simplefin.ErrHostNotAllowed) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.Printf("Failed to claim SimpleFIN setup token: %v\n", err)
+			http.Error(w, "SimpleFIN could not be connected, please try again later.", http.StatusBadGateway)
+			return
+		}
+		if err := db.SaveSimpleFINConnection(userID, accessURL, pool); err != nil {
+			log.Printf("Failed to save SimpleFIN connection: %v\n", err)
+			http.Error(w, "SimpleFIN could not be connected, please try again later.", http.StatusInternalServerError)
+			return
+		}
+		response.Message = "SimpleFIN connected"
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send SimpleFIN connection response", http.StatusInternalServerError)
+	}
+}
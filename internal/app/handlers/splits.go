@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/BBaCode/pocketwise-server/internal/app"
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns, replaces (on PUT) or removes (on DELETE) the split lines of a transaction
+func HandleTransactionSplits(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transactionId := mux.Vars(r)["transactionId"]
+	txns, err := db.FetchUserTransactionsByIds(userID, []string{transactionId}, pool)
+	if err != nil || len(txns) == 0 {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var splitsRequest models.TransactionSplitsRequest
+		if err := json.NewDecoder(r.Body).Decode(&splitsRequest); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := app.ValidateSplits(txns[0], splitsRequest.Splits); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := db.ReplaceTransactionSplits(transactionId, splitsRequest.Splits, pool); err != nil {
+			log.Printf("Failed to save transaction splits: %v\n", err)
+			http.Error(w, "Failed to save transaction splits", http.StatusInternalServerError)
+			return
+		}
+	case http.MethodDelete:
+		if err := db.ReplaceTransactionSplits(transactionId, nil, pool); err != nil {
+			log.Printf("Failed to remove transaction splits: %v\n", err)
+			http.Error(w, "Failed to remove transaction splits", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	splits, err := db.FetchTransactionSplits([]string{transactionId}, pool)
+	if err != nil {
+		log.Printf("Failed to fetch transaction splits: %v\n", err)
+		http.Error(w, "Failed to fetch transaction splits", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	response := models.TransactionSplitsRequest{Splits: splits[transactionId]}
+	if response.Splits == nil {
+		response.Splits = []models.TransactionSplit{}
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to send transaction splits response", http.StatusInternalServerError)
+	}
+}
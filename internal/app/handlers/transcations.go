@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/BBaCode/pocketwise-server/internal/app"
 	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/simplefin"
 	"github.com/BBaCode/pocketwise-server/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -51,6 +56,9 @@ func HandleGetAllTransactions(w http.ResponseWriter, r *http.Request, pool *pgxp
 	if err != nil {
 		log.Fatalf("Failed to fetch transactions with error: %s", err)
 	}
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		updatedTxns = app.FilterTransactionsByTag(updatedTxns, tag)
+	}
 
 	// Send JSON response to the client
 	w.Header().Set("Content-Type", "application/json")
@@ -59,7 +67,7 @@ func HandleGetAllTransactions(w http.ResponseWriter, r *http.Request, pool *pgxp
 	}
 }
 
-func HandleGetTransactions(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+func HandleGetTransactions(w http.ResponseWriter, r *http.Request, sf *simplefin.Client, pool *pgxpool.Pool) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
@@ -92,12 +100,19 @@ func HandleGetTransactions(w http.ResponseWriter, r *http.Request, pool *pgxpool
 		log.Fatalf("Failed to get successful response from FetchMostRecentTransaction: %s", err)
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://beta-bridge.simplefin.org/simplefin/accounts?start-date=%d&account=%s", startDate, reqBody.Account), nil)
+	// the user's own SimpleFIN connection, or the server's shared credentials
+	accessURL, err := app.SimpleFINAccessURL(userID, pool)
 	if err != nil {
-		log.Fatalf("Failed to create request: %v", err)
+		log.Printf("Failed to fetch SimpleFIN connection: %v\n", err)
+		http.Error(w, "Something went wrong. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	req, err := sf.AccountsRequest(accessURL, url.Values{"start-date": {strconv.FormatInt(startDate, 10)}, "account": {reqBody.Account}})
+	if err != nil {
+		log.Printf("Failed to create request: %v\n", err)
+		http.Error(w, "Something went wrong. Please try again later.", http.StatusInternalServerError)
+		return
 	}
-
-	req.SetBasicAuth(os.Getenv("SIMPLE_FIN_USERNAME"), os.Getenv("SIMPLE_FIN_PASSWORD")) // Split username and password
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -160,9 +175,12 @@ func HandleGetTransactions(w http.ResponseWriter, r *http.Request, pool *pgxpool
 		http.Error(w, "Failed to send accounts response", http.StatusInternalServerError)
 	}
 }
+
+// Updates the category, notes, tags and custom fields of a batch of transactions. Only the
+// fields sent for a transaction are changed
 func HandleUpdateTransactions(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, PATCH, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	fmt.Print(r.Method)
 
@@ -187,12 +205,22 @@ func HandleUpdateTransactions(w http.ResponseWriter, r *http.Request, pool *pgxp
 
 	var txnsCategoryUpdates models.UpdatedTransactions
 	if err := json.NewDecoder(r.Body).Decode(&txnsCategoryUpdates); err != nil {
-		log.Fatalf("Failed to decode transactions category request: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := app.NormalizeTransactionUpdates(txnsCategoryUpdates.UpdatedTransactions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	err = db.UpdateTransactionCategory(txnsCategoryUpdates, pool)
-	if err != nil {
-		log.Fatalf("Failed to fetch transactions with error: %s", err)
+	err = db.UpdateTransactions(userID, txnsCategoryUpdates.UpdatedTransactions, pool)
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to update transactions: %v\n", err)
+		http.Error(w, "Failed to update transactions", http.StatusInternalServerError)
+		return
 	}
 
 	updatedTxns, err := db.FetchAllTransactions(userAccounts, pool)
@@ -206,3 +234,82 @@ func HandleUpdateTransactions(w http.ResponseWriter, r *http.Request, pool *pgxp
 		http.Error(w, "Failed to send accounts response", http.StatusInternalServerError)
 	}
 }
+
+// Lists the user's tags with their usage between ?from and ?to (YYYY-MM-DD, default all time)
+func HandleGetTags(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var start, end int64 = 0, math.MaxInt64
+	if param := r.URL.Query().Get("from"); param != "" {
+		from, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			http.Error(w, "from must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		start = from.Unix()
+	}
+	if param := r.URL.Query().Get("to"); param != "" {
+		to, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			http.Error(w, "to must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		end = to.AddDate(0, 0, 1).Unix()
+	}
+
+	tags, err := db.FetchTagSummaries(userID, start, end, pool)
+	if err != nil {
+		log.Printf("Failed to fetch tags: %v\n", err)
+		http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		http.Error(w, "Failed to send tags response", http.StatusInternalServerError)
+	}
+}
+
+// Filtered, sorted and paginated transaction list. See app.ParseTransactionQuery for the
+// query parameters
+func HandleSearchTransactions(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query, err := app.ParseTransactionQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := app.SearchTransactions(app.PostgresTransactionRepository{Pool: pool}, userID, query)
+	if err != nil {
+		log.Printf("Failed to search transactions: %v\n", err)
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		http.Error(w, "Failed to send transactions response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func HandleGetTransfers(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groups, err := db.FetchTransferGroups(userID, pool)
+	if err != nil {
+		log.Printf("Failed to fetch transfers: %v\n", err)
+		http.Error(w, "Failed to fetch transfers", http.StatusInternalServerError)
+		return
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		http.Error(w, "Failed to send transfers response", http.StatusInternalServerError)
+	}
+}
+
+// Manually links two transactions as a transfer. They have to be in different accounts of the user
+// and move money in opposite directions, but the amounts may differ (e.g. a transfer fee)
+func HandleLinkTransfer(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var linkRequest models.LinkTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&linkRequest); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if len(linkRequest.TransactionIds) != 2 {
+		http.Error(w, "A transfer links exactly 2 transactions", http.StatusBadRequest)
+		return
+	}
+
+	txns, err := db.FetchUserTransactionsByIds(userID, linkRequest.TransactionIds, pool)
+	if err != nil || len(txns) != 2 {
+		http.Error(w, "Transactions not found", http.StatusNotFound)
+		return
+	}
+	first, _ := strconv.ParseFloat(txns[0].Amount, 64)
+	second, _ := strconv.ParseFloat(txns[1].Amount, 64)
+	if txns[0].AccountID == txns[1].AccountID || first*second >= 0 {
+		http.Error(w, "A transfer needs money leaving one account and arriving in another", http.StatusBadRequest)
+		return
+	}
+	if txns[0].TransferGroupId != "" || txns[1].TransferGroupId != "" {
+		http.Error(w, "Transaction is already part of a transfer", http.StatusConflict)
+		return
+	}
+
+	groupId, err := db.LinkTransfer(linkRequest.TransactionIds, pool)
+	if err != nil {
+		log.Printf("Failed to link transfer: %v\n", err)
+		http.Error(w, "Transfer could not be linked, please try again later.", http.StatusInternalServerError)
+		return
+	}
+	for i := range txns {
+		txns[i].TransferGroupId = groupId
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.TransferGroup{ID: groupId, Transactions: txns}); err != nil {
+		http.Error(w, "Failed to send transfer response", http.StatusInternalServerError)
+	}
+}
+
+func HandleUnlinkTransfer(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Extract user ID from request header (set by middleware)
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var transferResponse models.MessageResponse
+	if db.UnlinkTransfer(userID, mux.Vars(r)["groupId"], pool) == nil {
+		transferResponse.Message = "Transfer unlinked successfully"
+	} else {
+		transferResponse.Message = "Transfer could not be unlinked, please try again later."
+	}
+
+	// Send JSON response to the client
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(transferResponse); err != nil {
+		http.Error(w, "Failed to send transfer response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/importer"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Largest file POST /import accepts
+const MaxImportSize = 10 << 20
+
+var (
+	ErrUnreadableImport = errors.New("unreadable import file")
+	// The CSV column mapping doesn't fit the file. The preview still carries the file's columns and
+	// the mapping that was tried, for the user to correct
+	ErrInvalidImportMapping = errors.New("invalid column mapping")
+	ErrImportNotPending     = errors.New("import isn't awaiting commit")
+)
+
+// Parses an uploaded file into the transactions it would add to the account. The format is
+// detected when empty, and CSV files without a mapping get one suggested from their header
+func ParseImport(accountId string, filename string, format string, data []byte, mapping *models.CSVMapping) (models.ImportPreview, error) {
+	if format == "" {
+		format = importer.DetectFormat(filename, data)
+	}
+	preview := models.ImportPreview{
+		Import:       models.ImportRecord{AccountId: accountId, Format: format, Filename: filename, Status: models.ImportStatusPreview},
+		Transactions: []models.ImportPreviewRow{},
+		Errors:       []models.ImportRowError{},
+	}
+
+	if format == models.ImportFormatCSV {
+		columns, err := importer.CSVColumns(data)
+		if err != nil {
+			return preview, fmt.Errorf("%w: %v", ErrUnreadableImport, err)
+		}
+		if mapping == nil {
+			suggested := importer.SuggestCSVMapping(columns)
+			mapping = &suggested
+		}
+		preview.Columns, preview.Mapping = columns, mapping
+	}
+
+	var csvMapping models.CSVMapping
+	if mapping != nil {
+		csvMapping = *mapping
+	}
+	result, err := importer.Parse(format, data, csvMapping)
+	if err != nil && format == models.ImportFormatCSV {
+		preview.Errors = append(preview.Errors, models.ImportRowError{Message: err.Error()})
+		return preview, fmt.Errorf("%w: %v", ErrInvalidImportMapping, err)
+	} else if err != nil {
+		return preview, fmt.Errorf("%w: %v", ErrUnreadableImport, err)
+	}
+
+	importer.AssignIds(accountId, result.Transactions)
+	preview.Import.Transactions = result.Transactions
+	preview.Errors = result.Errors
+	for _, txn := range result.Transactions {
+		preview.Transactions = append(preview.Transactions, models.ImportPreviewRow{Transaction: txn})
+	}
+	return preview, nil
+}
+
+// Flags the rows that are already in the database so the preview shows what a commit would skip,
+// and counts the rest as the transactions the import adds
+func MarkImportDuplicates(preview *models.ImportPreview, existing map[string]bool) {
+	preview.Import.TransactionCount = 0
+	for i := range preview.Transactions {
+		preview.Transactions[i].Duplicate = existing[preview.Transactions[i].ID]
+		if !preview.Transactions[i].Duplicate {
+			preview.Import.TransactionCount++
+		}
+	}
+}
+
+func transactionIds(txns []models.Transaction) []string {
+	ids := make([]string, 0, len(txns))
+	for _, txn := range txns {
+		ids = append(ids, txn.ID)
+	}
+	return ids
+}
+
+// First phase of an import: parses the file and stores the result as a preview the user can look
+// over before committing it. Nothing is added to the account yet
+func PreviewImport(userId string, accountId string, filename string, format string, data []byte, mapping *models.CSVMapping, pool *pgxpool.Pool) (models.ImportPreview, error) {
+	preview, err := ParseImport(accountId, filename, format, data, mapping)
+	if err != nil {
+		return preview, err
+	}
+	existing, err := db.FetchExistingTransactionIds(transactionIds(preview.Import.Transactions), pool)
+	if err != nil {
+		return preview, err
+	}
+	MarkImportDuplicates(&preview, existing)
+
+	preview.Import.UserId = userId
+	if preview.Import.ID, err = db.InsertImport(preview.Import, pool); err != nil {
+		return preview, err
+	}
+	return preview, nil
+}
+
+// Second phase: categorizes the previewed transactions the way synced ones are and adds the ones the
+// account doesn't have yet
+func CommitImport(userId string, importId string, now time.Time, pool *pgxpool.Pool) (models.ImportRecord, error) {
+	record, err := db.FetchImport(userId, importId, pool)
+	if err != nil {
+		return record, err
+	}
+	if record.Status != models.ImportStatusPreview {
+		return record, ErrImportNotPending
+	}
+	if record.TransactionCount, err = insertImportTransactions(userId, record, now, pool); err != nil {
+		return record, err
+	}
+	record.Status = models.ImportStatusCommitted
+	afterImport(userId, now, pool)
+	return record, nil
+}
+
+// Adds the import's transactions that aren't in the database yet and commits the import, returning
+// how many were added. Rows that already have a category keep it, the rest go through the usual
+// categorizer
+func insertImportTransactions(userId string, record models.ImportRecord, now time.Time, pool *pgxpool.Pool) (int, error) {
+	existing, err := db.FetchExistingTransactionIds(transactionIds(record.Transactions), pool)
+	if err != nil {
+		return 0, err
+	}
+	var categorizedTxns []models.Transaction
+	for _, txn := range record.Transactions {
+		if existing[txn.ID] {
+			continue
+		}
+		if txn.Category == "" {
+			categorized, err := CategorizeWithPayeeHistory(userId, txn, pool)
+			if err != nil {
+				log.Printf("Failed to categorize imported transaction: %v\n", err)
+				categorized.Category = "Unknown"
+			}
+			// the categorizer only hands back the fields it knows about
+			categorized.Notes, categorized.Tags = txn.Notes, txn.Tags
+			txn = categorized
+		}
+		txn.AccountID = record.AccountId
+		categorizedTxns = append(categorizedTxns, txn)
+	}
+
+	return db.CommitImport(userId, record.ID, categorizedTxns, now.Unix(), pool)
+}
+
+// The same follow up a sync does. Budget and anomaly alerts are left out, imports are mostly
+// history and would alert on months that are long over
+func afterImport(userId string, now time.Time, pool *pgxpool.Pool) {
+	if _, err := AssignMerchants(userId, pool); err != nil {
+		log.Printf("Failed to assign merchants: %v\n", err)
+	}
+	if _, err := DetectTransfers(userId, now, pool); err != nil {
+		log.Printf("Failed to detect transfers: %v\n", err)
+	}
+	if _, err := RecalculateGoals(userId, now, pool); err != nil {
+		log.Printf("Failed to recalculate goals: %v\n", err)
+	}
+}
+
+// Removes the transactions a committed import added, and returns how many there were
+func UndoImport(userId string, importId string, now time.Time, pool *pgxpool.Pool) (int, error) {
+	removed, err := db.UndoImport(userId, importId, now.Unix(), pool)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := RecalculateGoals(userId, now, pool); err != nil {
+		log.Printf("Failed to recalculate goals: %v\n", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestParseImport(t *testing.T) {
+	data := []byte("Date,Description,Amount\n2026-10-01,COFFEE SHOP,-4.50\n2026-10-02,ACME PAYROLL,2100.00\n2026-10-02,COFFEE SHOP,-4.50\n")
+
+	// without a mapping one is suggested from the header
+	preview, err := ParseImport("acc", "checking.csv", "", data, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse import: %v", err)
+	}
+	if preview.Import.Format != models.ImportFormatCSV || preview.Mapping == nil || preview.Mapping.Amount != "Amount" || len(preview.Columns) != 3 {
+		t.Fatalf("Unexpected preview: %+v", preview)
+	}
+	if len(preview.Transactions) != 3 || len(preview.Import.Transactions) != 3 || preview.Transactions[0].AccountID != "acc" {
+		t.Fatalf("Expected 3 transactions on acc, got %+v", preview.Transactions)
+	}
+
+	MarkImportDuplicates(&preview, map[string]bool{preview.Transactions[1].ID: true})
+	if !preview.Transactions[1].Duplicate || preview.Transactions[0].Duplicate || preview.Import.TransactionCount != 2 {
+		t.Errorf("Expected the second row to be a duplicate and 2 rows to import, got %+v", preview)
+	}
+
+	// a mapping that doesn't fit still returns the columns for the mapping step
+	preview, err = ParseImport("acc", "checking.csv", "", data, &models.CSVMapping{Date: "Posted", Amount: "Amount"})
+	if !errors.Is(err, ErrInvalidImportMapping) || len(preview.Columns) != 3 || len(preview.Errors) != 1 {
+		t.Errorf("Expected an invalid mapping with the columns, got %+v (%v)", preview, err)
+	}
+
+	if _, err := ParseImport("acc", "statement.ofx", "", []byte("not ofx"), nil); !errors.Is(err, ErrUnreadableImport) {
+		t.Errorf("Expected an unreadable file, got %v", err)
+	}
+}
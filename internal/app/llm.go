@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// A chat model that answers a user prompt following a system prompt. OpenAI in the server, a
+// canned stand-in in tests
+type ChatModel interface {
+	Complete(ctx context.Context, system string, user string) (string, error)
+}
+
+type OpenAIChatModel struct {
+	client *openai.Client
+	Model  string
+}
+
+// Returns nil when OPENAI_API_KEY isn't set, for features that have a fallback without a model
+func NewChatModelFromEnv() ChatModel {
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		return nil
+	}
+	return NewOpenAIChatModel()
+}
+
+// Uses the key in OPENAI_API_KEY
+func NewOpenAIChatModel() *OpenAIChatModel {
+	return &OpenAIChatModel{client: openai.NewClient(os.Getenv("OPENAI_API_KEY")), Model: openai.GPT3Dot5Turbo}
+}
+
+func (m *OpenAIChatModel) Complete(ctx context.Context, system string, user string) (string, error) {
+	// The system level role set is telling the chatgpt bot what to do / what its job is
+	// the user level role is the actual prompt that will be acted upon.
+	resp, err := m.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: m.Model,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: system,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: user,
+				},
+			},
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("chat completion returned no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
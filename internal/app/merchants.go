@@ -0,0 +1,154 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Payment processors and card networks put their own code in front of the merchant name,
+// e.g. "SQ *BLUE BOTTLE" or "TST* JOE'S PIZZA"
+var processorPrefix = regexp.MustCompile(`^(SQ|TST|SP|PY|PP|PAYPAL|GOOGLE|APL|APPLE PAY|IN|DD|BT|WPY|CKO|EB|FS|ZLR)\s*\*\s*`)
+
+// Wording banks put in front of card and ACH transactions
+var bankPrefix = regexp.MustCompile(`^(POS DEBIT|POS|DEBIT CARD PURCHASE|DEBIT CARD|DEBIT|CHECKCARD|CHECK CARD|CARD PURCHASE|PURCHASE AUTHORIZED ON|PURCHASE|RECURRING PAYMENT|RECURRING|PREAUTHORIZED|ACH DEBIT|ACH|VISA)\s+`)
+
+var dates = regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2}|\d{1,2}/\d{1,2}(/\d{2,4})?)\b`)
+var domainSuffix = regexp.MustCompile(`\.(COM|NET|ORG|IO|CO)\b`)
+
+// Words that come right before a store number
+var storeWords = map[string]bool{"STORE": true, "STR": true, "NO": true, "#": true}
+
+// First words of two word city names, e.g. "NEW YORK", "SAN JOSE"
+var cityPrefixes = map[string]bool{"SAN": true, "SANTA": true, "LOS": true, "LAS": true, "NEW": true, "ST": true, "FORT": true, "FT": true, "EL": true, "PALO": true}
+
+var usStates = map[string]bool{
+	"AL": true, "AK": true, "AZ": true, "AR": true, "CA": true, "CO": true, "CT": true, "DE": true, "DC": true, "FL": true,
+	"GA": true, "HI": true, "ID": true, "IL": true, "IN": true, "IA": true, "KS": true, "KY": true, "LA": true, "ME": true,
+	"MD": true, "MA": true, "MI": true, "MN": true, "MS": true, "MO": true, "MT": true, "NE": true, "NV": true, "NH": true,
+	"NJ": true, "NM": true, "NY": true, "NC": true, "ND": true, "OH": true, "OK": true, "OR": true, "PA": true, "RI": true,
+	"SC": true, "SD": true, "TN": true, "TX": true, "UT": true, "VT": true, "VA": true, "WA": true, "WV": true, "WI": true,
+	"WY": true, "US": true, "USA": true,
+}
+
+func hasDigit(word string) bool {
+	return strings.IndexFunc(word, unicode.IsDigit) >= 0
+}
+
+func titleCase(name string) string {
+	runes := []rune(strings.ToLower(name))
+	for i, r := range runes {
+		if i == 0 || strings.ContainsRune(" -&./", runes[i-1]) {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// Reduces a bank payee string to the merchant's name by stripping processor prefixes, store
+// numbers, reference numbers, dates and locations, e.g.
+// "SQ *BLUE BOTTLE COF 0423 OAKLAND CA" -> "Blue Bottle Cof"
+func NormalizeMerchantName(payee string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(payee), " "))
+	for {
+		stripped := bankPrefix.ReplaceAllString(processorPrefix.ReplaceAllString(name, ""), "")
+		if stripped == name {
+			break
+		}
+		name = stripped
+	}
+	name = dates.ReplaceAllString(name, " ")
+	// whatever follows an asterisk is an order or trip reference ("UBER *TRIP", "AMZN MKTP US*2K3")
+	if i := strings.Index(name, "*"); i > 0 {
+		name = name[:i]
+	}
+
+	words := strings.Fields(name)
+	// store, phone and reference numbers end the name, and the location follows them
+	for i, word := range words {
+		if i > 0 && (hasDigit(word) || strings.HasPrefix(word, "#")) {
+			words = words[:i]
+			if storeWords[words[len(words)-1]] && len(words) > 1 {
+				words = words[:len(words)-1]
+			}
+			break
+		}
+	}
+	// without a store number the location is at the end: "... OAKLAND CA"
+	if len(words) >= 2 && usStates[words[len(words)-1]] {
+		words = words[:len(words)-1]
+		if len(words) >= 3 {
+			words = words[:len(words)-1]
+			if len(words) >= 3 && cityPrefixes[words[len(words)-1]] {
+				words = words[:len(words)-1]
+			}
+		}
+	}
+
+	name = domainSuffix.ReplaceAllString(strings.Join(words, " "), "")
+	name = strings.TrimFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if name == "" {
+		return titleCase(strings.TrimSpace(payee))
+	}
+	return titleCase(name)
+}
+
+// Merchants are matched case-insensitively on their normalized name
+func MerchantKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// The name a transaction is matched to a merchant by. Some banks leave the payee empty and only
+// fill in the description
+func transactionMerchantName(txn models.Transaction) string {
+	if strings.TrimSpace(txn.Payee) != "" {
+		return NormalizeMerchantName(txn.Payee)
+	}
+	return NormalizeMerchantName(txn.Description)
+}
+
+// Matches every transaction of the user that has no merchant yet to one, creating merchants for
+// payees that haven't been seen before. Returns how many transactions were assigned
+func AssignMerchants(userId string, pool *pgxpool.Pool) (int, error) {
+	transactions, err := db.FetchTransactionsWithoutMerchant(userId, pool)
+	if err != nil {
+		return 0, err
+	}
+
+	// group by merchant first so each one is looked up and updated only once
+	byKey := map[string][]string{}
+	names := map[string]string{}
+	for _, txn := range transactions {
+		name := transactionMerchantName(txn)
+		key := MerchantKey(name)
+		if key == "" {
+			continue
+		}
+		byKey[key] = append(byKey[key], txn.ID)
+		names[key] = name
+	}
+
+	assigned := 0
+	for key, ids := range byKey {
+		merchantId, _, err := db.FetchMerchantIdByKey(userId, key, pool)
+		if err != nil {
+			return assigned, fmt.Errorf("failed to look up merchant %q: %w", key, err)
+		}
+		if merchantId == "" {
+			merchantId, err = db.InsertMerchant(userId, names[key], key, pool)
+			if err != nil {
+				return assigned, fmt.Errorf("failed to create merchant %q: %w", key, err)
+			}
+		}
+		if err := db.SetTransactionsMerchant(ids, merchantId, pool); err != nil {
+			return assigned, fmt.Errorf("failed to assign merchant %q: %w", key, err)
+		}
+		assigned += len(ids)
+	}
+	return assigned, nil
+}
@@ -0,0 +1,25 @@
+package app
+
+import "testing"
+
+func TestNormalizeMerchantName(t *testing.T) {
+	tests := map[string]string{
+		"SQ *BLUE BOTTLE COF 0423 OAKLAND CA":    "Blue Bottle Cof",
+		"SQ *BLUE BOTTLE COF 0611 BERKELEY CA":   "Blue Bottle Cof",
+		"NETFLIX.COM 866-579-7172":               "Netflix",
+		"STARBUCKS STORE 01234":                  "Starbucks",
+		"TST* JOE'S PIZZA 10/12 NEW YORK NY":     "Joe's Pizza",
+		"UBER *TRIP HELP.UBER.COM":               "Uber",
+		"DEBIT CARD PURCHASE SHELL OIL 57442":    "Shell Oil",
+		"7-ELEVEN 32145":                         "7-Eleven",
+		"AMZN MKTP US*2K3LM0QX2":                 "Amzn Mktp",
+		"TRADER JOE'S #123 SAN FRANCISCO CA":     "Trader Joe's",
+		"Payroll":                                "Payroll",
+		"POS DEBIT WHOLEFDS MKT 10234 AUSTIN TX": "Wholefds Mkt",
+	}
+	for payee, expected := range tests {
+		if got := NormalizeMerchantName(payee); got != expected {
+			t.Errorf("NormalizeMerchantName(%q) = %q, expected %q", payee, got, expected)
+		}
+	}
+}
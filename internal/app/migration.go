@@ -0,0 +1,273 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/importer"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrUnknownMigrationSource = errors.New("unknown migration source")
+
+// Default mappings of each app's built in categories onto ours, keyed by the lower cased category.
+// Categories that share a name with one of ours map onto it without being listed
+var defaultCategoryMappings = map[string]map[string]string{
+	models.MigrationSourceMint: {
+		"food & dining": "Food & Dining", "restaurants": "Food & Dining", "fast food": "Food & Dining",
+		"coffee shops": "Food & Dining", "alcohol & bars": "Food & Dining",
+		"auto & transport": "Transportation", "gas & fuel": "Transportation", "parking": "Transportation",
+		"public transportation": "Transportation", "service & parts": "Transportation", "auto payment": "Transportation",
+		"ride share":    "Transportation",
+		"movies & dvds": "Entertainment", "music": "Entertainment", "amusement": "Entertainment", "arts": "Entertainment",
+		"health & fitness": "Health & Wellness", "doctor": "Health & Wellness", "dentist": "Health & Wellness",
+		"pharmacy": "Health & Wellness", "gym": "Health & Wellness", "eyecare": "Health & Wellness", "sports": "Health & Wellness",
+		"clothing": "Shopping", "electronics & software": "Shopping", "books": "Shopping", "hobbies": "Shopping",
+		"sporting goods": "Shopping", "home": "Shopping", "furnishings": "Shopping", "home improvement": "Shopping",
+		"bills & utilities": "Utilities", "mobile phone": "Utilities", "internet": "Utilities", "television": "Utilities",
+		"home phone":      "Utilities",
+		"mortgage & rent": "Rent",
+		"air travel":      "Travel", "hotel": "Travel", "rental car & taxi": "Travel", "vacation": "Travel",
+		"tuition": "Education", "student loan": "Education", "books & supplies": "Education",
+		"gift": "Gifts & Donations", "charity": "Gifts & Donations",
+		"auto insurance": "Insurance", "health insurance": "Insurance", "life insurance": "Insurance", "home insurance": "Insurance",
+		"hair": "Personal Care", "spa & massage": "Personal Care", "laundry": "Personal Care",
+		"paycheck": "Income", "bonus": "Income", "interest income": "Income", "reimbursement": "Income",
+		"uncategorized": "Unknown",
+	},
+	models.MigrationSourceYNAB: {
+		"dining out": "Food & Dining", "restaurants": "Food & Dining", "coffee": "Food & Dining",
+		"auto maintenance": "Transportation", "gas": "Transportation", "car payment": "Transportation",
+		"fun money": "Entertainment", "hobbies": "Entertainment",
+		"medical": "Health & Wellness", "fitness": "Health & Wellness",
+		"clothing": "Shopping", "home maintenance": "Shopping", "stuff i forgot to budget for": "Shopping",
+		"electric": "Utilities", "water": "Utilities", "internet": "Utilities", "phone": "Utilities", "cellphone": "Utilities",
+		"rent/mortgage": "Rent", "mortgage": "Rent",
+		"vacation":     "Travel",
+		"student loan": "Education",
+		"music":        "Subscriptions", "tv streaming": "Subscriptions", "software subscriptions": "Subscriptions",
+		"gifts": "Gifts & Donations", "charity": "Gifts & Donations", "giving": "Gifts & Donations",
+		"renter's/home insurance": "Insurance", "car insurance": "Insurance", "life insurance": "Insurance",
+		"inflow: ready to assign": "Income", "ready to assign": "Income", "inflow: to be budgeted": "Income", "to be budgeted": "Income",
+	},
+	models.MigrationSourceMonarch: {
+		"restaurants & bars": "Food & Dining", "coffee shops": "Food & Dining",
+		"gas": "Transportation", "auto payment": "Transportation", "auto maintenance": "Transportation",
+		"public transit": "Transportation", "taxi & ride shares": "Transportation", "parking & tolls": "Transportation",
+		"entertainment & recreation": "Entertainment",
+		"medical":                    "Health & Wellness", "dentist": "Health & Wellness", "fitness": "Health & Wellness",
+		"clothing": "Shopping", "electronics": "Shopping", "furniture & housewares": "Shopping", "home improvement": "Shopping",
+		"gas & electric": "Utilities", "water": "Utilities", "internet & cable": "Utilities", "phone": "Utilities", "garbage": "Utilities",
+		"mortgage":          "Rent",
+		"travel & vacation": "Travel",
+		"student loans":     "Education",
+		"gifts":             "Gifts & Donations", "charity": "Gifts & Donations",
+		"personal":  "Personal Care",
+		"paychecks": "Income", "interest": "Income", "business income": "Income", "other income": "Income",
+		"uncategorized": "Unknown",
+	},
+}
+
+func IsMigrationSource(source string) bool {
+	_, ok := defaultCategoryMappings[source]
+	return ok
+}
+
+// Our category for a category of the source app: the user's mapping, then the default one, then one
+// of ours with the same name. Empty when none of them match
+func MapCategory(source string, sourceCategory string, custom map[string]string) string {
+	key := strings.ToLower(strings.TrimSpace(sourceCategory))
+	if category, ok := custom[key]; ok {
+		return category
+	}
+	if category, ok := defaultCategoryMappings[source][key]; ok {
+		return category
+	}
+	for _, category := range TransactionCategories {
+		if strings.EqualFold(category, key) {
+			return category
+		}
+	}
+	return ""
+}
+
+// The default mappings of a source with the user's own on top, sorted by source category
+func CategoryMappings(source string, custom map[string]string) []models.CategoryMapping {
+	mappings := []models.CategoryMapping{}
+	for sourceCategory, category := range defaultCategoryMappings[source] {
+		if _, ok := custom[sourceCategory]; !ok {
+			mappings = append(mappings, models.CategoryMapping{SourceCategory: sourceCategory, Category: category})
+		}
+	}
+	for sourceCategory, category := range custom {
+		mappings = append(mappings, models.CategoryMapping{SourceCategory: sourceCategory, Category: category, Custom: true})
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].SourceCategory < mappings[j].SourceCategory })
+	return mappings
+}
+
+// Checks edited mappings and keys them by the lower cased source category. An empty category
+// removes the user's mapping
+func ValidateCategoryMappings(mappings []models.CategoryMapping) (map[string]string, error) {
+	validated := map[string]string{}
+	for _, mapping := range mappings {
+		sourceCategory := strings.ToLower(strings.TrimSpace(mapping.SourceCategory))
+		if sourceCategory == "" {
+			return nil, errors.New("source_category is required")
+		}
+		if mapping.Category != "" && !containsString(TransactionCategories, mapping.Category) {
+			return nil, fmt.Errorf("unknown category %q", mapping.Category)
+		}
+		validated[sourceCategory] = mapping.Category
+	}
+	return validated, nil
+}
+
+// Account type for a manual account created from an account name in the export
+func migratedAccountType(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "credit") || strings.Contains(name, "card") || strings.Contains(name, "visa") || strings.Contains(name, "amex"):
+		return "credit"
+	case strings.Contains(name, "saving"):
+		return "savings"
+	case strings.Contains(name, "checking"):
+		return "checking"
+	case strings.Contains(name, "mortgage"):
+		return "mortgage"
+	case strings.Contains(name, "loan"):
+		return "loan"
+	case strings.Contains(name, "cash"):
+		return "cash"
+	}
+	return "other"
+}
+
+// The transactions of one account in the export
+type migrationGroup struct {
+	account      models.MigrationAccount
+	transactions []models.Transaction
+}
+
+// Groups the export by account in file order and matches each account name to one of the user's
+// accounts (ignoring case). Categories are mapped onto ours and the ones that couldn't be are
+// returned sorted; their transactions are left uncategorized for the usual categorizer
+func planMigration(source string, rows []importer.MigratedTransaction, accounts []models.StoredAccount, custom map[string]string) ([]migrationGroup, []string) {
+	accountIds := map[string]string{}
+	for _, account := range accounts {
+		key := strings.ToLower(strings.TrimSpace(account.Name))
+		if _, ok := accountIds[key]; !ok {
+			accountIds[key] = account.ID
+		}
+	}
+
+	var groups []migrationGroup
+	groupIndex := map[string]int{}
+	unmapped := map[string]bool{}
+	for _, row := range rows {
+		key := strings.ToLower(strings.TrimSpace(row.AccountName))
+		i, ok := groupIndex[key]
+		if !ok {
+			i = len(groups)
+			groupIndex[key] = i
+			account := models.MigrationAccount{Name: strings.TrimSpace(row.AccountName), AccountId: accountIds[key]}
+			if account.Name == "" {
+				account.Name = "Imported from " + source
+			}
+			groups = append(groups, migrationGroup{account: account})
+		}
+
+		txn := row.Transaction
+		if txn.Category = MapCategory(source, row.SourceCategory, custom); txn.Category == "" && row.SourceCategory != "" {
+			unmapped[row.SourceCategory] = true
+		}
+		tags := []string{}
+		for _, tag := range txn.Tags {
+			if tag = NormalizeTag(tag); tag != "" && len(tag) <= maxTagLength && !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		txn.Tags = tags
+		groups[i].transactions = append(groups[i].transactions, txn)
+	}
+
+	unmappedCategories := []string{}
+	for category := range unmapped {
+		unmappedCategories = append(unmappedCategories, category)
+	}
+	sort.Strings(unmappedCategories)
+	return groups, unmappedCategories
+}
+
+// Brings the transactions export of Mint, YNAB or Monarch into the user's accounts. Accounts are
+// matched by name and manual accounts are created for the rest. Each account's transactions are
+// recorded as an import of their own so it can be undone. Transaction ids come from their content,
+// so running the same export again only adds what's missing
+func RunMigration(userId string, source string, filename string, data []byte, now time.Time, pool *pgxpool.Pool) (models.MigrationResult, error) {
+	result := models.MigrationResult{Source: source, Accounts: []models.MigrationAccount{}, UnmappedCategories: []string{}, Errors: []models.ImportRowError{}}
+	if !IsMigrationSource(source) {
+		return result, ErrUnknownMigrationSource
+	}
+	rows, rowErrors, err := importer.ParseMigration(source, data)
+	if err != nil {
+		return result, fmt.Errorf("%w: %v", ErrUnreadableImport, err)
+	}
+	result.Errors = rowErrors
+
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return result, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return result, err
+	}
+	custom, err := db.FetchCategoryMappings(userId, source, pool)
+	if err != nil {
+		return result, err
+	}
+	groups, unmapped := planMigration(source, rows, accounts, custom)
+	result.UnmappedCategories = unmapped
+
+	for _, group := range groups {
+		account := group.account
+		if account.AccountId == "" {
+			request := models.ManualAccountRequest{Name: account.Name, AccountType: migratedAccountType(account.Name), Balance: "0"}
+			manual, err := ManualAccountFromRequest(userUUID, "", request, now)
+			if err != nil {
+				return result, err
+			}
+			if err := db.InsertManualAccount(manual, pool); err != nil {
+				return result, fmt.Errorf("failed to create account %s: %w", account.Name, err)
+			}
+			account.AccountId, account.Created = manual.ID, true
+		}
+
+		importer.AssignIds(account.AccountId, group.transactions)
+		existing, err := db.FetchExistingTransactionIds(transactionIds(group.transactions), pool)
+		if err != nil {
+			return result, err
+		}
+		if len(existing) < len(group.transactions) {
+			record := models.ImportRecord{UserId: userId, AccountId: account.AccountId, Format: source, Filename: filename, Status: models.ImportStatusPreview, Transactions: group.transactions}
+			if record.ID, err = db.InsertImport(record, pool); err != nil {
+				return result, err
+			}
+			if account.TransactionCount, err = insertImportTransactions(userId, record, now, pool); err != nil {
+				return result, err
+			}
+			account.ImportId = record.ID
+		}
+		account.Skipped = len(group.transactions) - account.TransactionCount
+		result.Accounts = append(result.Accounts, account)
+	}
+
+	afterImport(userId, now, pool)
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/internal/importer"
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestMapCategory(t *testing.T) {
+	custom := map[string]string{"coffee shops": "Groceries"}
+	cases := []struct {
+		source, sourceCategory, expected string
+	}{
+		{models.MigrationSourceMint, "Fast Food", "Food & Dining"},
+		// the user's mapping wins over the default
+		{models.MigrationSourceMint, "Coffee Shops", "Groceries"},
+		// same name as one of ours
+		{models.MigrationSourceMonarch, "groceries", "Groceries"},
+		{models.MigrationSourceYNAB, "Inflow: Ready to Assign", "Income"},
+		{models.MigrationSourceYNAB, "Vacation Fund 2027", ""},
+	}
+	for _, c := range cases {
+		if category := MapCategory(c.source, c.sourceCategory, custom); category != c.expected {
+			t.Errorf("Expected %s to map to %q, got %q", c.sourceCategory, c.expected, category)
+		}
+	}
+
+	if _, err := ValidateCategoryMappings([]models.CategoryMapping{{SourceCategory: "Dining", Category: "Eating Out"}}); err == nil {
+		t.Errorf("Expected a category outside the taxonomy to be rejected")
+	}
+	mappings := CategoryMappings(models.MigrationSourceMint, custom)
+	for _, mapping := range mappings {
+		if mapping.SourceCategory == "coffee shops" && (!mapping.Custom || mapping.Category != "Groceries") {
+			t.Errorf("Expected the user's mapping to replace the default, got %+v", mapping)
+		}
+	}
+}
+
+func TestPlanMigration(t *testing.T) {
+	row := func(account string, category string, payee string, tags ...string) importer.MigratedTransaction {
+		txn := txnOn("2026-10-01", payee, "-10.00")
+		txn.ID, txn.AccountID, txn.Tags = "", "", tags
+		return importer.MigratedTransaction{Transaction: txn, AccountName: account, SourceCategory: category}
+	}
+	rows := []importer.MigratedTransaction{
+		row("Sapphire Card", "Restaurants", "Chipotle", "Work Trip", "work trip"),
+		row("Old Savings", "Transfer", "Transfer to Checking"),
+		row("sapphire card ", "Gas & Fuel", "Shell"),
+		row("Old Savings", "", "Interest"),
+	}
+	accounts := []models.StoredAccount{{ID: "acc", Name: "Sapphire Card"}}
+
+	groups, unmapped := planMigration(models.MigrationSourceMint, rows, accounts, nil)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 accounts, got %+v", groups)
+	}
+	card, savings := groups[0], groups[1]
+	if card.account.AccountId != "acc" || len(card.transactions) != 2 {
+		t.Errorf("Expected both card transactions on the existing account, got %+v", card)
+	}
+	if card.transactions[0].Category != "Food & Dining" || card.transactions[1].Category != "Transportation" {
+		t.Errorf("Unexpected card categories: %+v", card.transactions)
+	}
+	if len(card.transactions[0].Tags) != 1 || card.transactions[0].Tags[0] != "work-trip" {
+		t.Errorf("Expected the tags to be normalized and deduplicated, got %v", card.transactions[0].Tags)
+	}
+	if savings.account.AccountId != "" || savings.account.Name != "Old Savings" || migratedAccountType(savings.account.Name) != "savings" {
+		t.Errorf("Expected a new savings account to be needed, got %+v", savings.account)
+	}
+	if len(unmapped) != 1 || unmapped[0] != "Transfer" || savings.transactions[0].Category != "" {
+		t.Errorf("Expected Transfer to be unmapped and left for the categorizer, got %v %+v", unmapped, savings.transactions)
+	}
+}
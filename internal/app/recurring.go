@@ -0,0 +1,266 @@
+package app
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type cadence struct {
+	name           string
+	days           int
+	minDays        int
+	maxDays        int
+	minOccurrences int
+}
+
+// Allowed interval ranges are loose enough to absorb weekends, short months and posting delays
+var cadences = []cadence{
+	{"weekly", 7, 6, 8, 4},
+	{"biweekly", 14, 12, 16, 4},
+	{"monthly", 30, 26, 35, 3},
+	{"quarterly", 91, 84, 98, 3},
+	{"annual", 365, 350, 380, 2},
+}
+
+// Share of a series' intervals and amounts that have to fit the pattern
+const recurringMatchRatio = 2.0 / 3.0
+
+// How far an amount may stray from the series' median amount and still count as the same charge
+const recurringAmountTolerance = 0.25
+
+func recurringSeriesId(merchantKey string, income bool) string {
+	key := merchantKey
+	if income {
+		key += "|income"
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Returns the ID of the recurring series a transaction would belong to. Transfers between the
+// user's own accounts (savings, card payments) aren't bills or income and belong to none
+func RecurringSeriesIdForTransaction(txn models.Transaction) (string, bool) {
+	if txn.TransferGroupId != "" {
+		return "", false
+	}
+	amount, err := strconv.ParseFloat(txn.Amount, 64)
+	if err != nil || amount == 0 {
+		return "", false
+	}
+	// grouped by merchant, so the aliases of a merchant make up one series
+	key := transactionMerchantKey(txn)
+	if key == "" {
+		return "", false
+	}
+	return recurringSeriesId(key, amount > 0), true
+}
+
+func TransactionTime(txn models.Transaction) time.Time {
+	if txn.TransactedAt > 0 {
+		return time.Unix(txn.TransactedAt, 0).UTC()
+	}
+	return time.Unix(txn.Posted, 0).UTC()
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// Finds payees that show up on a regular cadence with a roughly constant amount. Transactions are
+// grouped by merchant and direction (money in vs out) and each group is matched against
+// the known cadences. A series whose next charge is well overdue is reported as cancelled.
+func DetectRecurring(txns []models.Transaction, now time.Time) []models.RecurringSeries {
+	groups := map[string][]models.Transaction{}
+	for _, txn := range txns {
+		if key, ok := RecurringSeriesIdForTransaction(txn); ok {
+			groups[key] = append(groups[key], txn)
+		}
+	}
+
+	series := []models.RecurringSeries{}
+	for id, group := range groups {
+		if s, ok := detectSeries(id, group, now); ok {
+			series = append(series, s)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].NextExpectedDate < series[j].NextExpectedDate })
+	return series
+}
+
+func detectSeries(id string, group []models.Transaction, now time.Time) (models.RecurringSeries, bool) {
+	if len(group) < 2 {
+		return models.RecurringSeries{}, false
+	}
+	sort.Slice(group, func(i, j int) bool { return TransactionTime(group[i]).Before(TransactionTime(group[j])) })
+
+	amounts := make([]float64, len(group))
+	for i, txn := range group {
+		amounts[i], _ = strconv.ParseFloat(txn.Amount, 64)
+	}
+	var intervals []float64
+	for i := 1; i < len(group); i++ {
+		days := TransactionTime(group[i]).Sub(TransactionTime(group[i-1])).Hours() / 24
+		// same day charges (e.g. a split payment) don't tell us anything about the cadence
+		if days >= 1 {
+			intervals = append(intervals, days)
+		}
+	}
+	if len(intervals) == 0 {
+		return models.RecurringSeries{}, false
+	}
+
+	medianInterval := median(intervals)
+	var match *cadence
+	for i := range cadences {
+		if medianInterval >= float64(cadences[i].minDays) && medianInterval <= float64(cadences[i].maxDays) {
+			match = &cadences[i]
+			break
+		}
+	}
+	if match == nil || len(group) < match.minOccurrences {
+		return models.RecurringSeries{}, false
+	}
+
+	regularIntervals := 0
+	for _, days := range intervals {
+		if days >= float64(match.minDays) && days <= float64(match.maxDays) {
+			regularIntervals++
+		}
+	}
+	if float64(regularIntervals) < recurringMatchRatio*float64(len(intervals)) {
+		return models.RecurringSeries{}, false
+	}
+
+	medianAmount := median(amounts)
+	consistentAmounts := 0
+	for _, amount := range amounts {
+		if math.Abs(amount-medianAmount) <= math.Abs(medianAmount)*recurringAmountTolerance {
+			consistentAmounts++
+		}
+	}
+	if float64(consistentAmounts) < recurringMatchRatio*float64(len(amounts)) {
+		return models.RecurringSeries{}, false
+	}
+
+	last := group[len(group)-1]
+	lastDate := TransactionTime(last)
+	lastAmount := amounts[len(amounts)-1]
+	next := nextOccurrence(lastDate, *match)
+
+	var total float64
+	for _, amount := range amounts {
+		total += amount
+	}
+
+	s := models.RecurringSeries{
+		ID:                 id,
+		Payee:              last.Payee,
+		NormalizedPayee:    MerchantKey(transactionMerchantName(last)),
+		AccountId:          last.AccountID,
+		Category:           last.Category,
+		Cadence:            match.name,
+		IntervalDays:       match.days,
+		Occurrences:        len(group),
+		AverageAmount:      roundCents(total / float64(len(amounts))),
+		LastAmount:         lastAmount,
+		LastDate:           lastDate.Format(time.DateOnly),
+		NextExpectedDate:   next.Format(time.DateOnly),
+		NextExpectedAmount: lastAmount,
+		IsIncome:           lastAmount > 0,
+		Status:             models.RecurringStatusActive,
+		Review:             models.RecurringReviewDetected,
+	}
+
+	// a charge that is overdue by more than half its cadence (at least 5 days) has most likely stopped
+	grace := time.Duration(math.Max(float64(match.days)/2, 5)*24) * time.Hour
+	if now.After(next.Add(grace)) {
+		s.Status = models.RecurringStatusCancelled
+	}
+
+	// the latest charge costing more than what the series usually cost before it
+	if len(amounts) >= 2 && !s.IsIncome {
+		previous := median(amounts[:len(amounts)-1])
+		if math.Abs(lastAmount)-math.Abs(previous) >= math.Max(0.01, math.Abs(previous)*0.005) {
+			s.PriceIncrease = &models.PriceChange{Previous: previous, Current: lastAmount, Date: s.LastDate}
+		}
+	}
+	return s, true
+}
+
+func nextOccurrence(last time.Time, c cadence) time.Time {
+	return AddCadence(last, c.name)
+}
+
+// Steps a date forward by one period of the named cadence
+func AddCadence(t time.Time, cadence string) time.Time {
+	switch cadence {
+	case "weekly":
+		return t.AddDate(0, 0, 7)
+	case "biweekly":
+		return t.AddDate(0, 0, 14)
+	case "monthly":
+		return t.AddDate(0, 1, 0)
+	case "quarterly":
+		return t.AddDate(0, 3, 0)
+	case "annual":
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 0, 30)
+	}
+}
+
+// Detects the user's recurring series and applies their confirm/dismiss reviews. Dismissed series
+// are left out unless includeDismissed is set.
+func FetchRecurringSeries(userId string, now time.Time, includeDismissed bool, pool *pgxpool.Pool) ([]models.RecurringSeries, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := db.FetchExistingAccounts(userUUID, pool)
+	if err != nil {
+		return nil, err
+	}
+	txns, err := db.FetchAllTransactions(accounts, pool)
+	if err != nil {
+		return nil, err
+	}
+	return reviewedRecurringSeries(userId, txns, now, includeDismissed, pool)
+}
+
+// Detects recurring series in the transactions and applies the user's reviews to them
+func reviewedRecurringSeries(userId string, txns []models.Transaction, now time.Time, includeDismissed bool, pool *pgxpool.Pool) ([]models.RecurringSeries, error) {
+	reviews, err := db.FetchRecurringReviews(userId, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	series := []models.RecurringSeries{}
+	for _, s := range DetectRecurring(txns, now) {
+		if review, ok := reviews[s.ID]; ok {
+			s.Review = review
+		}
+		if s.Review == models.RecurringReviewDismissed && !includeDismissed {
+			continue
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
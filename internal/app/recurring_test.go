@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func txnOn(day string, payee string, amount string) models.Transaction {
+	d, _ := time.Parse(time.DateOnly, day)
+	return models.Transaction{ID: fmt.Sprintf("%s-%s", payee, day), AccountID: "acc", Payee: payee, Amount: amount, TransactedAt: d.Unix()}
+}
+
+func TestDetectRecurring(t *testing.T) {
+	txns := []models.Transaction{
+		// monthly subscription with a price increase in the latest charge
+		txnOn("2026-06-03", "NETFLIX.COM 866-579", "-15.49"),
+		txnOn("2026-07-03", "NETFLIX.COM 866-580", "-15.49"),
+		txnOn("2026-08-04", "NETFLIX.COM 866-579", "-15.49"),
+		txnOn("2026-09-03", "NETFLIX.COM 866-579", "-15.49"),
+		txnOn("2026-10-03", "Netflix.com", "-17.99"),
+		// biweekly paycheck
+		txnOn("2026-08-07", "ACME PAYROLL", "2100.00"),
+		txnOn("2026-08-21", "ACME PAYROLL", "2100.00"),
+		txnOn("2026-09-04", "ACME PAYROLL", "2100.00"),
+		txnOn("2026-09-18", "ACME PAYROLL", "2150.00"),
+		txnOn("2026-10-02", "ACME PAYROLL", "2150.00"),
+		txnOn("2026-10-16", "ACME PAYROLL", "2150.00"),
+		// gym membership that stopped in the summer
+		txnOn("2026-03-10", "City Gym", "-40.00"),
+		txnOn("2026-04-10", "City Gym", "-40.00"),
+		txnOn("2026-05-10", "City Gym", "-40.00"),
+		txnOn("2026-06-10", "City Gym", "-40.00"),
+		// random shopping is not recurring
+		txnOn("2026-09-01", "Target", "-23.10"),
+		txnOn("2026-09-05", "Target", "-120.55"),
+		txnOn("2026-10-11", "Target", "-8.00"),
+	}
+
+	series := DetectRecurring(txns, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	byPayee := map[string]models.RecurringSeries{}
+	for _, s := range series {
+		byPayee[s.NormalizedPayee] = s
+	}
+	if len(series) != 3 {
+		t.Fatalf("Expected 3 series, got %d: %+v", len(series), series)
+	}
+
+	netflix := byPayee["netflix"]
+	if netflix.Cadence != "monthly" || netflix.NextExpectedDate != "2026-11-03" || netflix.Status != models.RecurringStatusActive {
+		t.Errorf("Unexpected netflix series: %+v", netflix)
+	}
+	if netflix.PriceIncrease == nil || netflix.PriceIncrease.Previous != -15.49 {
+		t.Errorf("Expected a price increase from 15.49, got %+v", netflix.PriceIncrease)
+	}
+
+	payroll := byPayee["acme payroll"]
+	if payroll.Cadence != "biweekly" || !payroll.IsIncome || payroll.NextExpectedDate != "2026-10-30" {
+		t.Errorf("Unexpected payroll series: %+v", payroll)
+	}
+
+	if gym := byPayee["city gym"]; gym.Status != models.RecurringStatusCancelled {
+		t.Errorf("Expected the gym membership to be cancelled, got %+v", gym)
+	}
+}
+
+func TestDetectRecurringSkipsTransfers(t *testing.T) {
+	var txns []models.Transaction
+	for _, day := range []string{"2026-06-01", "2026-07-01", "2026-08-01", "2026-09-01", "2026-10-01"} {
+		out := txnOn(day, "Transfer to Savings", "-500.00")
+		out.AccountID, out.TransferGroupId = "checking", "transfer-"+day
+		in := txnOn(day, "Transfer from Checking", "500.00")
+		in.AccountID, in.TransferGroupId = "savings", "transfer-"+day
+		txns = append(txns, out, in)
+	}
+	// the same payee without a linked transfer is still a bill
+	for _, day := range []string{"2026-06-15", "2026-07-15", "2026-08-15", "2026-09-15"} {
+		txns = append(txns, txnOn(day, "City Power", "-80.00"))
+	}
+
+	series := DetectRecurring(txns, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if len(series) != 1 || series[0].Payee != "City Power" {
+		t.Errorf("Expected only the power bill to recur, got %+v", series)
+	}
+}
+
+func TestDetectRecurringGroupsByMerchant(t *testing.T) {
+	var txns []models.Transaction
+	// the same gym bills under its old and new name, which the user made aliases of one merchant
+	for i, day := range []string{"2026-06-10", "2026-07-10", "2026-08-10", "2026-09-10", "2026-10-10"} {
+		payee := "FITCO CLUBS"
+		if i >= 3 {
+			payee = "BRIGHT GYM"
+		}
+		txn := txnOn(day, payee, "-40.00")
+		txn.MerchantId = "merchant-gym"
+		txns = append(txns, txn)
+	}
+
+	series := DetectRecurring(txns, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if len(series) != 1 || series[0].Occurrences != 5 || series[0].Cadence != "monthly" {
+		t.Errorf("Expected one monthly series across the aliases, got %+v", series)
+	}
+}
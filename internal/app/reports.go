@@ -0,0 +1,150 @@
+package app
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// How many groups are listed as top movers
+const topMoverCount = 5
+
+// First day of the month or week (weeks start on Monday, like Postgres' date_trunc) containing t
+func ReportPeriodStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval == models.ReportIntervalWeek {
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day.AddDate(0, 0, 1-day.Day())
+}
+
+func addReportPeriods(start time.Time, interval string, n int) time.Time {
+	if interval == models.ReportIntervalWeek {
+		return start.AddDate(0, 0, 7*n)
+	}
+	return start.AddDate(0, n, 0)
+}
+
+// Percentage change from previous to current, nil when there was nothing before to compare with
+func changePercent(previous float64, current float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	percent := math.Round((current-previous)/previous*10000) / 100
+	return &percent
+}
+
+// Fetches the spending totals for every period between from and to (both inclusive days, widened
+// to whole periods) plus the period before, which the first period's changes are measured against
+func FetchSpendingReport(userId string, from time.Time, to time.Time, groupBy string, interval string, pool *pgxpool.Pool) (models.SpendingReport, error) {
+	start := addReportPeriods(ReportPeriodStart(from, interval), interval, -1)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	totals, err := db.FetchSpendingTotals(userId, groupBy, interval, start.Unix(), end.Unix(), pool)
+	if err != nil {
+		return models.SpendingReport{}, err
+	}
+	return BuildSpendingReport(totals, from, to, groupBy, interval), nil
+}
+
+// Lays the totals out period by period, with every group's change against the period before.
+// Periods without spending are still listed so charts get a continuous axis. The top movers are
+// the groups whose spending changed the most between the last two periods
+func BuildSpendingReport(totals []models.SpendingTotal, from time.Time, to time.Time, groupBy string, interval string) models.SpendingReport {
+	byPeriod := map[string]map[string]models.SpendingTotal{}
+	for _, total := range totals {
+		if byPeriod[total.Period] == nil {
+			byPeriod[total.Period] = map[string]models.SpendingTotal{}
+		}
+		byPeriod[total.Period][total.Key] = total
+	}
+
+	report := models.SpendingReport{
+		From:      from.Format(time.DateOnly),
+		To:        to.Format(time.DateOnly),
+		GroupBy:   groupBy,
+		Interval:  interval,
+		Periods:   []models.SpendingPeriod{},
+		TopMovers: []models.SpendingMover{},
+	}
+
+	start := ReportPeriodStart(from, interval)
+	previous := byPeriod[addReportPeriods(start, interval, -1).Format(time.DateOnly)]
+	for ; !start.After(to); start = addReportPeriods(start, interval, 1) {
+		current := byPeriod[start.Format(time.DateOnly)]
+		period := models.SpendingPeriod{
+			Start:  start.Format(time.DateOnly),
+			End:    addReportPeriods(start, interval, 1).AddDate(0, 0, -1).Format(time.DateOnly),
+			Groups: []models.SpendingGroup{},
+		}
+
+		var previousTotal float64
+		for _, total := range previous {
+			previousTotal += total.Total
+		}
+		for key, total := range current {
+			period.Total += total.Total
+			period.Groups = append(period.Groups, models.SpendingGroup{
+				Key:              key,
+				Total:            roundCents(total.Total),
+				TransactionCount: total.TransactionCount,
+				Change:           roundCents(total.Total - previous[key].Total),
+				ChangePercent:    changePercent(previous[key].Total, total.Total),
+			})
+		}
+		// groups that had spending before but none now dropped by their whole amount
+		for key, total := range previous {
+			if _, ok := current[key]; !ok {
+				period.Groups = append(period.Groups, models.SpendingGroup{
+					Key:           key,
+					Change:        roundCents(-total.Total),
+					ChangePercent: changePercent(total.Total, 0),
+				})
+			}
+		}
+		sort.Slice(period.Groups, func(i, j int) bool {
+			if period.Groups[i].Total != period.Groups[j].Total {
+				return period.Groups[i].Total > period.Groups[j].Total
+			}
+			return period.Groups[i].Key < period.Groups[j].Key
+		})
+		period.Change = roundCents(period.Total - previousTotal)
+		period.ChangePercent = changePercent(previousTotal, period.Total)
+		report.Total += period.Total
+		period.Total = roundCents(period.Total)
+
+		report.Periods = append(report.Periods, period)
+		previous = current
+	}
+	report.Total = roundCents(report.Total)
+
+	if len(report.Periods) > 0 {
+		last := report.Periods[len(report.Periods)-1]
+		for _, group := range last.Groups {
+			if group.Change == 0 {
+				continue
+			}
+			report.TopMovers = append(report.TopMovers, models.SpendingMover{
+				Key:           group.Key,
+				Previous:      roundCents(group.Total - group.Change),
+				Current:       group.Total,
+				Change:        group.Change,
+				ChangePercent: group.ChangePercent,
+			})
+		}
+		sort.Slice(report.TopMovers, func(i, j int) bool {
+			a, b := math.Abs(report.TopMovers[i].Change), math.Abs(report.TopMovers[j].Change)
+			if a != b {
+				return a > b
+			}
+			return report.TopMovers[i].Key < report.TopMovers[j].Key
+		})
+		if len(report.TopMovers) > topMoverCount {
+			report.TopMovers = report.TopMovers[:topMoverCount]
+		}
+	}
+	return report
+}
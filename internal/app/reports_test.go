@@ -0,0 +1,59 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestReportPeriodStart(t *testing.T) {
+	// 2024-03-14 is a Thursday
+	if got := ReportPeriodStart(date("2024-03-14"), models.ReportIntervalWeek); !got.Equal(date("2024-03-11")) {
+		t.Errorf("week start = %v", got)
+	}
+	if got := ReportPeriodStart(date("2024-03-17"), models.ReportIntervalWeek); !got.Equal(date("2024-03-11")) {
+		t.Errorf("sunday week start = %v", got)
+	}
+	if got := ReportPeriodStart(date("2024-03-14"), models.ReportIntervalMonth); !got.Equal(date("2024-03-01")) {
+		t.Errorf("month start = %v", got)
+	}
+}
+
+func TestBuildSpendingReport(t *testing.T) {
+	totals := []models.SpendingTotal{
+		// the period before the report, only used for the first period's changes
+		{Period: "2024-01-01", Key: "Groceries", Total: 200, TransactionCount: 4},
+		{Period: "2024-02-01", Key: "Groceries", Total: 250, TransactionCount: 5},
+		{Period: "2024-02-01", Key: "Travel", Total: 600, TransactionCount: 1},
+		{Period: "2024-04-01", Key: "Groceries", Total: 150, TransactionCount: 3},
+		{Period: "2024-04-01", Key: "Food & Dining", Total: 80, TransactionCount: 6},
+	}
+	report := BuildSpendingReport(totals, date("2024-02-10"), date("2024-04-20"), models.ReportGroupByCategory, models.ReportIntervalMonth)
+
+	if len(report.Periods) != 3 {
+		t.Fatalf("expected 3 periods, got %+v", report.Periods)
+	}
+	feb := report.Periods[0]
+	if feb.Start != "2024-02-01" || feb.End != "2024-02-29" || feb.Total != 850 || feb.Change != 650 {
+		t.Errorf("unexpected february %+v", feb)
+	}
+	if feb.Groups[0].Key != "Travel" || feb.Groups[0].ChangePercent != nil {
+		t.Errorf("expected travel first without a percentage, got %+v", feb.Groups[0])
+	}
+	if feb.Groups[1].Key != "Groceries" || feb.Groups[1].Change != 50 || *feb.Groups[1].ChangePercent != 25 {
+		t.Errorf("unexpected february groceries %+v", feb.Groups[1])
+	}
+
+	// march has no spending but still shows what dropped
+	mar := report.Periods[1]
+	if mar.Total != 0 || mar.Change != -850 || len(mar.Groups) != 2 {
+		t.Errorf("unexpected march %+v", mar)
+	}
+
+	if report.Total != 1080 {
+		t.Errorf("total = %v", report.Total)
+	}
+	if len(report.TopMovers) != 2 || report.TopMovers[0].Key != "Groceries" || report.TopMovers[0].Previous != 0 || report.TopMovers[1].Key != "Food & Dining" {
+		t.Errorf("unexpected top movers %+v", report.TopMovers)
+	}
+}
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/internal/simplefin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The access URL to sync the user's accounts with: their own SimpleFIN connection, or the server's
+// shared credentials when they haven't connected one
+func SimpleFINAccessURL(userId string, pool *pgxpool.Pool) (string, error) {
+	accessURL, err := db.FetchSimpleFINAccessURL(userId, pool)
+	if errors.Is(err, db.ErrSimpleFINNotConnected) {
+		return simplefin.SharedAccessURL(), nil
+	}
+	return accessURL, err
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// Checks that split lines can replace the transaction's category: at least two lines, each with a
+// category and an amount in the same direction as the transaction, adding up to it to the cent
+func ValidateSplits(txn models.Transaction, splits []models.TransactionSplit) error {
+	total, ok := amountCents(txn.Amount)
+	if !ok {
+		return fmt.Errorf("transaction %s has an invalid amount", txn.ID)
+	}
+	if len(splits) < 2 {
+		return errors.New("a split needs at least 2 lines")
+	}
+
+	var sum int64
+	for _, split := range splits {
+		if split.Category == "" {
+			return errors.New("every split line needs a category")
+		}
+		cents, ok := amountCents(split.Amount)
+		if !ok || cents == 0 {
+			return fmt.Errorf("invalid split amount %q", split.Amount)
+		}
+		if (cents < 0) != (total < 0) {
+			return errors.New("split amounts must have the same sign as the transaction")
+		}
+		sum += cents
+	}
+	if sum != total {
+		return fmt.Errorf("split lines add up to %.2f but the transaction is %.2f", float64(sum)/100, float64(total)/100)
+	}
+	return nil
+}
+
+// Returns the transaction once per split line, with the line's amount and category, or the
+// transaction itself when it isn't split. Aggregations go through this so split lines count
+// under their own categories
+func SplitLines(txn models.Transaction) []models.Transaction {
+	if len(txn.Splits) == 0 {
+		return []models.Transaction{txn}
+	}
+	lines := make([]models.Transaction, len(txn.Splits))
+	for i, split := range txn.Splits {
+		line := txn
+		line.Amount = split.Amount
+		line.Category = split.Category
+		line.Splits = nil
+		lines[i] = line
+	}
+	return lines
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestValidateSplits(t *testing.T) {
+	costco := txnOn("2026-10-04", "Costco", "-150.00")
+	tests := []struct {
+		name   string
+		splits []models.TransactionSplit
+		valid  bool
+	}{
+		{"adds up", []models.TransactionSplit{{Amount: "-100.00", Category: "Groceries"}, {Amount: "-49.99", Category: "Shopping"}, {Amount: "-0.01", Category: "Personal Care"}}, true},
+		{"short by a cent", []models.TransactionSplit{{Amount: "-100.00", Category: "Groceries"}, {Amount: "-49.99", Category: "Shopping"}}, false},
+		{"single line", []models.TransactionSplit{{Amount: "-150.00", Category: "Groceries"}}, false},
+		{"wrong sign", []models.TransactionSplit{{Amount: "-160.00", Category: "Groceries"}, {Amount: "10.00", Category: "Shopping"}}, false},
+		{"missing category", []models.TransactionSplit{{Amount: "-100.00", Category: "Groceries"}, {Amount: "-50.00"}}, false},
+	}
+	for _, test := range tests {
+		if err := ValidateSplits(costco, test.splits); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid=%v, got %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	costco := txnOn("2026-10-04", "Costco", "-150.00")
+	if lines := SplitLines(costco); len(lines) != 1 || lines[0].Amount != "-150.00" {
+		t.Fatalf("Expected an unsplit transaction to be its own line, got %+v", lines)
+	}
+
+	costco.Splits = []models.TransactionSplit{{Amount: "-100.00", Category: "Groceries"}, {Amount: "-50.00", Category: "Shopping"}}
+	lines := SplitLines(costco)
+	if len(lines) != 2 || lines[0].Category != "Groceries" || lines[1].Amount != "-50.00" || lines[1].Payee != "Costco" {
+		t.Errorf("Unexpected split lines: %+v", lines)
+	}
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+const maxTagLength = 50
+
+// Tags are compared case-insensitively, so "Vacation 2026" and "vacation-2026" are the same tag
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tags must be between 1 and %d characters", maxTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
+
+// Validates a batch of transaction changes and normalizes their tags in place
+func NormalizeTransactionUpdates(updates []models.TransactionCategoryRequest) error {
+	for i := range updates {
+		update := &updates[i]
+		if update.ID == "" {
+			return errors.New("every update needs a transaction id")
+		}
+		var err error
+		if update.Tags != nil {
+			tags, err := normalizeTags(*update.Tags)
+			if err != nil {
+				return err
+			}
+			update.Tags = &tags
+		}
+		if update.AddTags, err = normalizeTags(update.AddTags); err != nil {
+			return err
+		}
+		if update.RemoveTags, err = normalizeTags(update.RemoveTags); err != nil {
+			return err
+		}
+		for key := range update.CustomFields {
+			if strings.TrimSpace(key) == "" {
+				return errors.New("custom field names can't be empty")
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the transactions carrying the tag
+func FilterTransactionsByTag(txns []models.Transaction, tag string) []models.Transaction {
+	tag = NormalizeTag(tag)
+	filtered := []models.Transaction{}
+	for _, txn := range txns {
+		for _, txnTag := range txn.Tags {
+			if txnTag == tag {
+				filtered = append(filtered, txn)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestNormalizeTransactionUpdates(t *testing.T) {
+	tags := []string{"Vacation 2026", " reimbursable "}
+	updates := []models.TransactionCategoryRequest{{ID: "txn-1", Tags: &tags, AddTags: []string{"Tax  Deductible"}}}
+	if err := NormalizeTransactionUpdates(updates); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := *updates[0].Tags; got[0] != "vacation-2026" || got[1] != "reimbursable" {
+		t.Errorf("Unexpected tags: %v", got)
+	}
+	if updates[0].AddTags[0] != "tax-deductible" {
+		t.Errorf("Unexpected added tags: %v", updates[0].AddTags)
+	}
+
+	if err := NormalizeTransactionUpdates([]models.TransactionCategoryRequest{{ID: "txn-1", AddTags: []string{"  "}}}); err == nil {
+		t.Error("Expected an empty tag to be rejected")
+	}
+}
+
+func TestFilterTransactionsByTag(t *testing.T) {
+	hotel := txnOn("2026-07-01", "Hotel", "-400.00")
+	hotel.Tags = []string{"reimbursable", "vacation-2026"}
+	coffee := txnOn("2026-07-02", "Coffee", "-4.00")
+
+	filtered := FilterTransactionsByTag([]models.Transaction{hotel, coffee}, "Vacation 2026")
+	if len(filtered) != 1 || filtered[0].Payee != "Hotel" {
+		t.Errorf("Expected only the hotel, got %+v", filtered)
+	}
+}
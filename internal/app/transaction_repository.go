@@ -0,0 +1,137 @@
+package app
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Where the transaction list comes from. Postgres in the server, an in-memory list in tests
+type TransactionRepository interface {
+	// Returns the page of transactions matching the query, plus one extra when there is a next
+	// page, and the total number of matches
+	SearchTransactions(userId string, q models.TransactionQuery) ([]models.RankedTransaction, int, error)
+}
+
+type PostgresTransactionRepository struct {
+	Pool *pgxpool.Pool
+}
+
+func (r PostgresTransactionRepository) SearchTransactions(userId string, q models.TransactionQuery) ([]models.RankedTransaction, int, error) {
+	return db.SearchTransactions(userId, q, r.Pool)
+}
+
+// Applies the same filters, ranking and pagination as the Postgres query to transactions held in
+// memory. Search matching goes through the trigram and full text matchers in fuzzy.go
+type MemoryTransactionRepository struct {
+	// Owning user ID per account ID
+	AccountOwners map[string]string
+	Transactions  []models.Transaction
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesQuery(txn models.Transaction, q models.TransactionQuery) (float64, bool) {
+	amount, _ := strconv.ParseFloat(txn.Amount, 64)
+	switch {
+	case q.From != 0 && txn.TransactedAt < q.From, q.To != 0 && txn.TransactedAt >= q.To:
+		return 0, false
+	case len(q.AccountIds) > 0 && !containsString(q.AccountIds, txn.AccountID):
+		return 0, false
+	case q.MinAmount != nil && amount < *q.MinAmount, q.MaxAmount != nil && amount > *q.MaxAmount:
+		return 0, false
+	}
+	if len(q.Categories) > 0 {
+		matched := containsString(q.Categories, txn.Category)
+		for _, split := range txn.Splits {
+			matched = matched || containsString(q.Categories, split.Category)
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+	if len(q.Tags) > 0 {
+		matched := false
+		for _, tag := range txn.Tags {
+			matched = matched || containsString(q.Tags, tag)
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+	if q.Search == "" {
+		return 0, true
+	}
+
+	var rank float64
+	fullText := FullTextMatch(q.Search, txn.Payee, txn.Description, txn.Memo, txn.Notes)
+	if fullText {
+		rank = 1
+	}
+	similarity := max(WordSimilarity(q.Search, txn.Payee), WordSimilarity(q.Search, txn.Description))
+	if !fullText && similarity < wordSimilarityThreshold {
+		return 0, false
+	}
+	return rank + similarity, true
+}
+
+// Sort key of a transaction, compared numerically
+func sortValue(txn models.RankedTransaction, sortOrder string) float64 {
+	switch sortOrder {
+	case models.TransactionSortAmountAsc, models.TransactionSortAmountDesc:
+		amount, _ := strconv.ParseFloat(txn.Amount, 64)
+		return amount
+	case models.TransactionSortRelevance:
+		return txn.Rank
+	default:
+		return float64(txn.TransactedAt)
+	}
+}
+
+func (r MemoryTransactionRepository) SearchTransactions(userId string, q models.TransactionQuery) ([]models.RankedTransaction, int, error) {
+	matches := []models.RankedTransaction{}
+	for _, txn := range r.Transactions {
+		if r.AccountOwners[txn.AccountID] != userId {
+			continue
+		}
+		if rank, ok := matchesQuery(txn, q); ok {
+			matches = append(matches, models.RankedTransaction{Transaction: txn, Rank: rank})
+		}
+	}
+
+	descending := q.Sort != models.TransactionSortDateAsc && q.Sort != models.TransactionSortAmountAsc
+	// whether a comes before b in the list
+	before := func(aValue float64, aId string, bValue float64, bId string) bool {
+		if aValue != bValue {
+			return (aValue > bValue) == descending
+		}
+		return (strings.Compare(aId, bId) > 0) == descending
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return before(sortValue(matches[i], q.Sort), matches[i].ID, sortValue(matches[j], q.Sort), matches[j].ID)
+	})
+
+	total := len(matches)
+	if q.After != nil {
+		afterValue, _ := strconv.ParseFloat(q.After.Value, 64)
+		start := sort.Search(len(matches), func(i int) bool {
+			return before(afterValue, q.After.ID, sortValue(matches[i], q.Sort), matches[i].ID)
+		})
+		matches = matches[start:]
+	}
+	if len(matches) > q.Limit+1 {
+		matches = matches[:q.Limit+1]
+	}
+	return matches, total, nil
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+const (
+	defaultTransactionPageSize = 50
+	maxTransactionPageSize     = 200
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Cursors are opaque to clients, they only pass back what they were given
+func EncodeTransactionCursor(cursor models.TransactionCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeTransactionCursor(encoded string) (models.TransactionCursor, error) {
+	var cursor models.TransactionCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(data, &cursor) != nil || cursor.ID == "" {
+		return cursor, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// Accepts both repeated parameters (?account=a&account=b) and comma separated lists
+func listParam(values url.Values, name string) []string {
+	var list []string
+	for _, value := range values[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func amountParam(values url.Values, name string) (*float64, error) {
+	param := values.Get(name)
+	if param == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &amount, nil
+}
+
+// Parses the query string of the transaction list: from and to (YYYY-MM-DD, both inclusive),
+// account, category and tag lists, min_amount and max_amount, q for typo tolerant search, sort
+// (relevance by default when searching), limit and the cursor of the previous page
+func ParseTransactionQuery(values url.Values) (models.TransactionQuery, error) {
+	q := models.TransactionQuery{
+		AccountIds: listParam(values, "account"),
+		Categories: listParam(values, "category"),
+		Search:     strings.TrimSpace(values.Get("q")),
+		Sort:       values.Get("sort"),
+		Limit:      defaultTransactionPageSize,
+	}
+	for _, tag := range listParam(values, "tag") {
+		q.Tags = append(q.Tags, NormalizeTag(tag))
+	}
+
+	if param := values.Get("from"); param != "" {
+		from, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			return q, errors.New("from must be formatted as YYYY-MM-DD")
+		}
+		q.From = from.Unix()
+	}
+	if param := values.Get("to"); param != "" {
+		to, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			return q, errors.New("to must be formatted as YYYY-MM-DD")
+		}
+		q.To = to.AddDate(0, 0, 1).Unix()
+	}
+
+	var err error
+	if q.MinAmount, err = amountParam(values, "min_amount"); err != nil {
+		return q, err
+	}
+	if q.MaxAmount, err = amountParam(values, "max_amount"); err != nil {
+		return q, err
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = models.TransactionSortDateDesc
+		if q.Search != "" {
+			q.Sort = models.TransactionSortRelevance
+		}
+	case models.TransactionSortRelevance:
+		if q.Search == "" {
+			return q, errors.New("sorting by relevance needs a search")
+		}
+	case models.TransactionSortDateDesc, models.TransactionSortDateAsc, models.TransactionSortAmountAsc, models.TransactionSortAmountDesc:
+	default:
+		return q, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+
+	if param := values.Get("limit"); param != "" {
+		if q.Limit, err = strconv.Atoi(param); err != nil || q.Limit < 1 || q.Limit > maxTransactionPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxTransactionPageSize)
+		}
+	}
+
+	if param := values.Get("cursor"); param != "" {
+		cursor, err := DecodeTransactionCursor(param)
+		// a cursor only makes sense with the sort order it came from
+		if err != nil || cursor.Sort != q.Sort {
+			return q, errInvalidCursor
+		}
+		q.After = &cursor
+	}
+	return q, nil
+}
+
+// Returns one page of transactions, the cursor for the next one if there is more, and the
+// fragments that matched the search
+func SearchTransactions(repo TransactionRepository, userId string, q models.TransactionQuery) (models.TransactionPage, error) {
+	ranked, total, err := repo.SearchTransactions(userId, q)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+
+	page := models.TransactionPage{Transactions: []models.Transaction{}, TotalCount: total}
+	if len(ranked) > q.Limit {
+		last := ranked[q.Limit-1]
+		cursor := models.TransactionCursor{Sort: q.Sort, Value: strconv.FormatInt(last.TransactedAt, 10), ID: last.ID}
+		switch q.Sort {
+		case models.TransactionSortAmountAsc, models.TransactionSortAmountDesc:
+			cursor.Value = last.Amount
+		case models.TransactionSortRelevance:
+			cursor.Value = strconv.FormatFloat(last.Rank, 'g', -1, 64)
+		}
+		page.NextCursor = EncodeTransactionCursor(cursor)
+		ranked = ranked[:q.Limit]
+	}
+	for _, txn := range ranked {
+		page.Transactions = append(page.Transactions, txn.Transaction)
+	}
+
+	if q.Search != "" {
+		page.Highlights = map[string][]models.SearchHighlight{}
+		for _, txn := range page.Transactions {
+			fields := []struct{ name, text string }{{"payee", txn.Payee}, {"description", txn.Description}, {"memo", txn.Memo}, {"notes", txn.Notes}}
+			for _, field := range fields {
+				if matches := HighlightMatches(field.text, q.Search); len(matches) > 0 {
+					page.Highlights[txn.ID] = append(page.Highlights[txn.ID], models.SearchHighlight{Field: field.name, Text: field.text, Matches: matches})
+				}
+			}
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestParseTransactionQuery(t *testing.T) {
+	values, _ := url.ParseQuery("from=2026-01-01&to=2026-01-31&account=a,b&account=c&category=Groceries&tag=Vacation 2026&min_amount=-100&q=costco&sort=amount_asc&limit=20")
+	q, err := ParseTransactionQuery(values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if q.From != time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix() || q.To != time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("Unexpected date range: %d - %d", q.From, q.To)
+	}
+	if len(q.AccountIds) != 3 || q.Categories[0] != "Groceries" || q.Tags[0] != "vacation-2026" {
+		t.Errorf("Unexpected list filters: %+v", q)
+	}
+	if q.MinAmount == nil || *q.MinAmount != -100 || q.MaxAmount != nil || q.Search != "costco" || q.Sort != models.TransactionSortAmountAsc || q.Limit != 20 {
+		t.Errorf("Unexpected filters: %+v", q)
+	}
+
+	defaults, _ := ParseTransactionQuery(url.Values{})
+	if defaults.Sort != models.TransactionSortDateDesc || defaults.Limit != defaultTransactionPageSize {
+		t.Errorf("Unexpected defaults: %+v", defaults)
+	}
+
+	for _, invalid := range []string{"sort=random", "limit=1000", "from=01/01/2026", "max_amount=lots", "cursor=garbage"} {
+		values, _ := url.ParseQuery(invalid)
+		if _, err := ParseTransactionQuery(values); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestTransactionCursor(t *testing.T) {
+	cursor := models.TransactionCursor{Sort: models.TransactionSortDateDesc, Value: "1767225600", ID: "TRN-1"}
+	values := url.Values{"cursor": {EncodeTransactionCursor(cursor)}}
+	q, err := ParseTransactionQuery(values)
+	if err != nil || q.After == nil || *q.After != cursor {
+		t.Fatalf("Expected the cursor to round trip, got %+v %v", q.After, err)
+	}
+
+	// a cursor from another sort order is rejected
+	values.Set("sort", models.TransactionSortAmountAsc)
+	if _, err := ParseTransactionQuery(values); err == nil {
+		t.Error("Expected a cursor from a different sort to be rejected")
+	}
+}
+
+func TestSearchTransactionsPagination(t *testing.T) {
+	repo := MemoryTransactionRepository{AccountOwners: map[string]string{"checking": "user-1", "other": "user-2"}}
+	for day := 1; day <= 5; day++ {
+		txn := txnOn(fmt.Sprintf("2026-03-%02d", day), fmt.Sprintf("Store %d", day), "-10.00")
+		txn.ID = fmt.Sprintf("TRN-%d", day)
+		txn.AccountID = "checking"
+		repo.Transactions = append(repo.Transactions, txn)
+	}
+	stranger := txnOn("2026-03-03", "Store 9", "-10.00")
+	stranger.AccountID = "other"
+	repo.Transactions = append(repo.Transactions, stranger)
+
+	var ids []string
+	values := url.Values{"limit": {"2"}}
+	for pages := 0; pages < 5; pages++ {
+		q, err := ParseTransactionQuery(values)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		page, err := SearchTransactions(repo, "user-1", q)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if page.TotalCount != 5 {
+			t.Errorf("Expected a total of 5, got %d", page.TotalCount)
+		}
+		for _, txn := range page.Transactions {
+			ids = append(ids, txn.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		values.Set("cursor", page.NextCursor)
+	}
+	if strings.Join(ids, ",") != "TRN-5,TRN-4,TRN-3,TRN-2,TRN-1" {
+		t.Errorf("Unexpected pages: %v", ids)
+	}
+}
+
+func TestSearchTransactionsFuzzy(t *testing.T) {
+	repo := MemoryTransactionRepository{AccountOwners: map[string]string{"checking": "user-1"}}
+	for i, payee := range []string{"STARBUCKS STORE 01234", "SHELL OIL 5744", "Starbucks"} {
+		txn := txnOn("2026-03-01", payee, "-5.00")
+		txn.ID = fmt.Sprintf("TRN-%d", i)
+		txn.AccountID = "checking"
+		repo.Transactions = append(repo.Transactions, txn)
+	}
+
+	q, err := ParseTransactionQuery(url.Values{"q": {"starbcks"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	page, err := SearchTransactions(repo, "user-1", q)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// the exact payee ranks above the one with extra words
+	if len(page.Transactions) != 2 || page.Transactions[0].ID != "TRN-2" || page.Transactions[1].ID != "TRN-0" {
+		t.Fatalf("Unexpected results: %+v", page.Transactions)
+	}
+	if highlights := page.Highlights["TRN-0"]; len(highlights) == 0 || highlights[0].Field != "payee" || highlights[0].Matches[0] != [2]int{0, 9} {
+		t.Errorf("Unexpected highlights: %+v", page.Highlights)
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// How far apart the two sides of a transfer can post. Transfers between banks often take a few days
+const transferWindow = 4 * 24 * time.Hour
+
+// How far back the matcher looks for unmatched transactions after a sync
+const transferLookbackDays = 45
+
+func amountCents(amount string) (int64, bool) {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(math.Round(value * 100)), true
+}
+
+// Pairs money leaving one account with the same amount arriving in another account within the
+// transfer window. Each outflow is matched to the closest unused inflow in time, so two identical
+// transfers on different days pair up correctly.
+func MatchTransfers(txns []models.Transaction) [][2]models.Transaction {
+	sorted := append([]models.Transaction(nil), txns...)
+	sort.Slice(sorted, func(i, j int) bool { return TransactionTime(sorted[i]).Before(TransactionTime(sorted[j])) })
+
+	inflows := map[int64][]int{}
+	for i, txn := range sorted {
+		if cents, ok := amountCents(txn.Amount); ok && cents > 0 {
+			inflows[cents] = append(inflows[cents], i)
+		}
+	}
+
+	used := map[int]bool{}
+	var pairs [][2]models.Transaction
+	for i, out := range sorted {
+		cents, ok := amountCents(out.Amount)
+		if !ok || cents >= 0 {
+			continue
+		}
+		best := -1
+		var bestGap time.Duration
+		for _, j := range inflows[-cents] {
+			in := sorted[j]
+			if used[j] || in.AccountID == out.AccountID {
+				continue
+			}
+			gap := TransactionTime(in).Sub(TransactionTime(out))
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap <= transferWindow && (best == -1 || gap < bestGap) {
+				best, bestGap = j, gap
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			used[i] = true
+			pairs = append(pairs, [2]models.Transaction{out, sorted[best]})
+		}
+	}
+	return pairs
+}
+
+// Links any new transfers among the user's recent transactions. Meant to run after a sync
+func DetectTransfers(userId string, now time.Time, pool *pgxpool.Pool) (int, error) {
+	since := now.AddDate(0, 0, -transferLookbackDays).Unix()
+	candidates, err := db.FetchTransferCandidates(userId, since, pool)
+	if err != nil {
+		return 0, err
+	}
+
+	linked := 0
+	for _, pair := range MatchTransfers(candidates) {
+		if _, err := db.LinkTransfer([]string{pair[0].ID, pair[1].ID}, pool); err != nil {
+			log.Printf("Failed to link transfer %s -> %s: %v\n", pair[0].ID, pair[1].ID, err)
+			continue
+		}
+		linked++
+	}
+	return linked, nil
+}
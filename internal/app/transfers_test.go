@@ -0,0 +1,36 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestMatchTransfers(t *testing.T) {
+	onAccount := func(txn models.Transaction, account string, id string) models.Transaction {
+		txn.AccountID = account
+		txn.ID = id
+		return txn
+	}
+	txns := []models.Transaction{
+		onAccount(txnOn("2026-10-01", "Transfer to savings", "-500.00"), "checking", "out-1"),
+		onAccount(txnOn("2026-10-02", "Transfer from checking", "500.00"), "savings", "in-1"),
+		// card payment posts a few days later on the card
+		onAccount(txnOn("2026-10-05", "Card autopay", "-812.34"), "checking", "out-2"),
+		onAccount(txnOn("2026-10-08", "Payment thank you", "812.34"), "card", "in-2"),
+		// refund in the same account is not a transfer
+		onAccount(txnOn("2026-10-09", "Store", "-20.00"), "card", "purchase"),
+		onAccount(txnOn("2026-10-10", "Store refund", "20.00"), "card", "refund"),
+		// too far apart
+		onAccount(txnOn("2026-10-01", "Venmo", "-75.00"), "checking", "out-3"),
+		onAccount(txnOn("2026-10-20", "Venmo", "75.00"), "savings", "in-3"),
+	}
+
+	pairs := MatchTransfers(txns)
+	if len(pairs) != 2 {
+		t.Fatalf("Expected 2 transfers, got %d: %+v", len(pairs), pairs)
+	}
+	if pairs[0][0].ID != "out-1" || pairs[0][1].ID != "in-1" || pairs[1][0].ID != "out-2" || pairs[1][1].ID != "in-2" {
+		t.Errorf("Unexpected pairs: %+v", pairs)
+	}
+}
@@ -0,0 +1,389 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+	ErrDeletionNotFound   = errors.New("account deletion not found")
+)
+
+// A table holding user data and the condition selecting one user's rows, with the user ID as $1
+type userTable struct {
+	name      string
+	condition string
+	// Kept when the account is deleted, as the record that it was
+	retained bool
+	// Columns written to data exports, all of them when empty. Leaves credentials out of the zip
+	exported string
+}
+
+const (
+	userTransactions = `transaction_id IN (SELECT t.id FROM public.transactions t JOIN public.accounts a ON a.id = t.account_id WHERE a.user_id = $1)`
+	ownedByUser      = `user_id = $1`
+)
+
+// Every table with user data, parents before the tables that reference them. Account deletion
+// goes through them in reverse. New tables holding user data have to be added here
+var userTables = []userTable{
+	{name: "users", condition: `id = $1`},
+	{name: "notification_preferences", condition: ownedByUser},
+	{name: "calendar_tokens", condition: ownedByUser},
+	{name: "simplefin_connections", condition: ownedByUser, exported: `user_id, created_at`},
+	{name: "accounts", condition: ownedByUser},
+	{name: "merchants", condition: ownedByUser},
+	{name: "merchant_aliases", condition: ownedByUser},
+	{name: "import_profiles", condition: ownedByUser},
+	{name: "imports", condition: ownedByUser},
+	{name: "category_mappings", condition: ownedByUser},
+	{name: "transactions", condition: `account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)`},
+	{name: "transaction_splits", condition: userTransactions},
+	{name: "tags", condition: ownedByUser},
+	{name: "transaction_tags", condition: userTransactions},
+	{name: "transaction_custom_fields", condition: userTransactions},
+	{name: "attachments", condition: ownedByUser},
+	{name: "merged_transactions", condition: ownedByUser},
+	{name: "duplicate_dismissals", condition: ownedByUser},
+	{name: "anomaly_alerts", condition: ownedByUser},
+	{name: "budgets", condition: ownedByUser},
+	{name: "budget_templates", condition: ownedByUser},
+	{name: "budget_auto_create", condition: ownedByUser},
+	{name: "budget_alert_thresholds", condition: ownedByUser},
+	{name: "budget_alert_events", condition: ownedByUser},
+	{name: "goals", condition: ownedByUser},
+	{name: "goal_snapshots", condition: `goal_id IN (SELECT id FROM public.goals WHERE user_id = $1)`},
+	{name: "manual_bills", condition: ownedByUser},
+	{name: "recurring_series_reviews", condition: ownedByUser},
+	{name: "notifications", condition: ownedByUser},
+	{name: "deferred_notifications", condition: ownedByUser},
+	{name: "monthly_digests", condition: ownedByUser},
+	{name: "data_exports", condition: ownedByUser},
+	{name: "account_deletions", condition: ownedByUser},
+	{name: "audit_log", condition: ownedByUser, retained: true},
+}
+
+// Names of the tables a data export covers, in export order
+func UserTables() []string {
+	names := make([]string, 0, len(userTables))
+	for _, table := range userTables {
+		names = append(names, table.name)
+	}
+	return names
+}
+
+// Hands each of the user's rows in the table to fn as a JSON object, one at a time
+func StreamUserTable(ctx context.Context, userId string, table string, fn func(row []byte) error, pool *pgxpool.Pool) error {
+	for _, t := range userTables {
+		if t.name != table {
+			continue
+		}
+		columns := t.exported
+		if columns == "" {
+			columns = "*"
+		}
+		query := fmt.Sprintf(`SELECT row_to_json(r)::text FROM (SELECT %s FROM public.%s WHERE %s) r`, columns, t.name, t.condition)
+		rows, err := pool.Query(ctx, query, userId)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", table, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row string
+			if err := rows.Scan(&row); err != nil {
+				return err
+			}
+			if err := fn([]byte(row)); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}
+	return fmt.Errorf("unknown user table %q", table)
+}
+
+const insertAuditRecordQuery = `INSERT INTO public.audit_log (user_id, action, details) VALUES ($1, $2, $3)`
+
+const dataExportColumns = `id::text, user_id::text, status, size, error, created_at::text, COALESCE(completed_at::text, ''),
+	COALESCE(expires_at::text, ''), COALESCE(storage_key, '')`
+
+func scanDataExport(row pgx.Row) (models.DataExport, error) {
+	var e models.DataExport
+	err := row.Scan(&e.ID, &e.UserId, &e.Status, &e.Size, &e.Error, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt, &e.StorageKey)
+	return e, err
+}
+
+func queryDataExports(pool *pgxpool.Pool, query string, args ...any) ([]models.DataExport, error) {
+	rows, err := pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []models.DataExport{}
+	for rows.Next() {
+		e, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// Queues a data export for the user, or returns the one that is already queued or being built.
+// Only a new export gets an audit record
+func InsertDataExport(userId string, pool *pgxpool.Pool) (models.DataExport, error) {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return models.DataExport{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// serializes requests of the same user so only one export is queued at a time
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "data_export:"+userId); err != nil {
+		return models.DataExport{}, fmt.Errorf("failed to lock data exports: %w", err)
+	}
+	query := fmt.Sprintf(`SELECT %s FROM public.data_exports WHERE user_id = $1 AND status IN ($2, $3) ORDER BY created_at DESC LIMIT 1`, dataExportColumns)
+	e, err := scanDataExport(tx.QueryRow(ctx, query, userId, models.DataExportStatusPending, models.DataExportStatusRunning))
+	if err == nil {
+		return e, nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return e, fmt.Errorf("failed to fetch data export: %w", err)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO public.data_exports (user_id, status) VALUES ($1, $2) RETURNING %s`, dataExportColumns)
+	if e, err = scanDataExport(tx.QueryRow(ctx, query, userId, models.DataExportStatusPending)); err != nil {
+		return e, fmt.Errorf("failed to insert data export: %w", err)
+	}
+	if _, err := tx.Exec(ctx, insertAuditRecordQuery, userId, models.AuditDataExportRequested, fmt.Sprintf(`{"export_id": %q}`, e.ID)); err != nil {
+		return e, fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return e, tx.Commit(ctx)
+}
+
+func FetchDataExport(userId string, exportId string, pool *pgxpool.Pool) (models.DataExport, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.data_exports WHERE user_id = $1 AND id::text = $2`, dataExportColumns)
+	e, err := scanDataExport(pool.QueryRow(context.Background(), query, userId, exportId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e, ErrDataExportNotFound
+	}
+	return e, err
+}
+
+// The user's exports, newest first
+func FetchDataExports(userId string, pool *pgxpool.Pool) ([]models.DataExport, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.data_exports WHERE user_id = $1 ORDER BY created_at DESC`, dataExportColumns)
+	return queryDataExports(pool, query, userId)
+}
+
+// Marks a queued export as being built. Exports left running for longer than staleAfter, by a
+// server that went away part way, are taken over. False when another worker has it
+func ClaimDataExport(exportId string, staleAfter time.Duration, pool *pgxpool.Pool) (bool, error) {
+	query := `UPDATE public.data_exports SET status = $1, started_at = now()
+		WHERE id::text = $2 AND (status = $3 OR (status = $1 AND started_at < now() - make_interval(secs => $4)))`
+	tag, err := pool.Exec(context.Background(), query, models.DataExportStatusRunning, exportId, models.DataExportStatusPending, staleAfter.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim data export: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Exports waiting to be built, including ones whose build stalled for longer than staleAfter
+func FetchQueuedDataExports(staleAfter time.Duration, limit int, pool *pgxpool.Pool) ([]models.DataExport, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.data_exports
+		WHERE status = $1 OR (status = $2 AND started_at < now() - make_interval(secs => $3)) ORDER BY created_at LIMIT $4`, dataExportColumns)
+	return queryDataExports(pool, query, models.DataExportStatusPending, models.DataExportStatusRunning, staleAfter.Seconds(), limit)
+}
+
+// Marks an export as ready for download. Returns ErrDataExportNotFound when it is gone, e.g.
+// because the account was deleted while the zip was being built
+func CompleteDataExport(exportId string, storageKey string, size int64, expiresAt time.Time, pool *pgxpool.Pool) error {
+	query := `UPDATE public.data_exports SET status = $1, storage_key = $2, size = $3, completed_at = now(), expires_at = $4 WHERE id::text = $5`
+	tag, err := pool.Exec(context.Background(), query, models.DataExportStatusReady, storageKey, size, expiresAt, exportId)
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDataExportNotFound
+	}
+	return nil
+}
+
+func FailDataExport(exportId string, message string, pool *pgxpool.Pool) error {
+	query := `UPDATE public.data_exports SET status = $1, error = $2, completed_at = now() WHERE id::text = $3`
+	if _, err := pool.Exec(context.Background(), query, models.DataExportStatusFailed, message, exportId); err != nil {
+		return fmt.Errorf("failed to mark data export as failed: %w", err)
+	}
+	return nil
+}
+
+// Finished exports past their expiry, waiting for their zip to be removed
+func FetchExpiredDataExports(now time.Time, limit int, pool *pgxpool.Pool) ([]models.DataExport, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.data_exports WHERE expires_at < $1 LIMIT $2`, dataExportColumns)
+	return queryDataExports(pool, query, now, limit)
+}
+
+func DeleteDataExport(exportId string, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(context.Background(), `DELETE FROM public.data_exports WHERE id::text = $1`, exportId); err != nil {
+		return fmt.Errorf("failed to delete data export: %w", err)
+	}
+	return nil
+}
+
+const accountDeletionColumns = `user_id::text, requested_at::text, scheduled_for::text`
+
+func scanAccountDeletion(row pgx.Row) (models.AccountDeletion, error) {
+	var d models.AccountDeletion
+	err := row.Scan(&d.UserId, &d.RequestedAt, &d.ScheduledFor)
+	return d, err
+}
+
+// Schedules the user's account to be deleted, or returns the deletion that is already scheduled
+func ScheduleAccountDeletion(userId string, scheduledFor time.Time, pool *pgxpool.Pool) (models.AccountDeletion, error) {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return models.AccountDeletion{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf(`INSERT INTO public.account_deletions (user_id, scheduled_for) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING RETURNING %s`, accountDeletionColumns)
+	d, err := scanAccountDeletion(tx.QueryRow(ctx, query, userId, scheduledFor))
+	if errors.Is(err, pgx.ErrNoRows) {
+		query = fmt.Sprintf(`SELECT %s FROM public.account_deletions WHERE user_id = $1`, accountDeletionColumns)
+		if d, err = scanAccountDeletion(tx.QueryRow(ctx, query, userId)); err != nil {
+			return d, fmt.Errorf("failed to fetch account deletion: %w", err)
+		}
+		return d, nil
+	} else if err != nil {
+		return d, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+
+	details := fmt.Sprintf(`{"scheduled_for": %q}`, d.ScheduledFor)
+	if _, err := tx.Exec(ctx, insertAuditRecordQuery, userId, models.AuditAccountDeletionRequested, details); err != nil {
+		return d, fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return d, tx.Commit(ctx)
+}
+
+func FetchAccountDeletion(userId string, pool *pgxpool.Pool) (models.AccountDeletion, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.account_deletions WHERE user_id = $1`, accountDeletionColumns)
+	d, err := scanAccountDeletion(pool.QueryRow(context.Background(), query, userId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return d, ErrDeletionNotFound
+	}
+	return d, err
+}
+
+func CancelAccountDeletion(userId string, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM public.account_deletions WHERE user_id = $1`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeletionNotFound
+	}
+	if _, err := tx.Exec(ctx, insertAuditRecordQuery, userId, models.AuditAccountDeletionCancelled, `{}`); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Users whose grace period is over
+func FetchDueAccountDeletions(now time.Time, pool *pgxpool.Pool) ([]string, error) {
+	rows, err := pool.Query(context.Background(), `SELECT user_id::text FROM public.account_deletions WHERE scheduled_for <= $1 ORDER BY scheduled_for`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account deletions: %w", err)
+	}
+	defer rows.Close()
+
+	userIds := []string{}
+	for rows.Next() {
+		var userId string
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		userIds = append(userIds, userId)
+	}
+	return userIds, rows.Err()
+}
+
+// Hard deletes everything stored for the user whose deletion is due, and records it in the audit
+// log with the number of rows removed per table and whether the user's SimpleFIN connection was
+// revoked. Returns ErrDeletionNotFound when the deletion was cancelled or isn't due yet. Files in
+// the blob store are left to the caller
+func DeleteUserData(userId string, now time.Time, pool *pgxpool.Pool) (map[string]int64, error) {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var requestedAt string
+	query := `SELECT requested_at::text FROM public.account_deletions WHERE user_id = $1 AND scheduled_for <= $2 FOR UPDATE`
+	err = tx.QueryRow(ctx, query, userId, now).Scan(&requestedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeletionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch account deletion: %w", err)
+	}
+
+	var syncedAccounts int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM public.accounts WHERE user_id = $1 AND NOT manual`, userId).Scan(&syncedAccounts); err != nil {
+		return nil, fmt.Errorf("failed to count synced accounts: %w", err)
+	}
+
+	// deleting the access URL takes away the server's access to the user's bank connections. Users
+	// syncing through the server's shared credentials have nothing of their own to revoke
+	var simplefinConnected bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.simplefin_connections WHERE user_id = $1)`, userId).Scan(&simplefinConnected); err != nil {
+		return nil, fmt.Errorf("failed to check SimpleFIN connection: %w", err)
+	}
+
+	deleted := map[string]int64{}
+	for i := len(userTables) - 1; i >= 0; i-- {
+		table := userTables[i]
+		if table.retained {
+			continue
+		}
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM public.%s WHERE %s`, table.name, table.condition), userId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", table.name, err)
+		}
+		deleted[table.name] = tag.RowsAffected()
+	}
+
+	details, err := json.Marshal(map[string]any{
+		"requested_at":            requestedAt,
+		"rows_deleted":            deleted,
+		"synced_accounts_removed": syncedAccounts,
+		"simplefin_revoked":       simplefinConnected,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, insertAuditRecordQuery, userId, models.AuditAccountDeleted, string(details)); err != nil {
+		return nil, fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return deleted, tx.Commit(ctx)
+}
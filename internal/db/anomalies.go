@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Stores the anomalies and returns the ones that weren't flagged before, with their ids set
+func InsertAnomalies(anomalies []models.Anomaly, pool *pgxpool.Pool) ([]models.Anomaly, error) {
+	query := `INSERT INTO public.anomaly_alerts (user_id, kind, key, transaction_id, category, amount, reason)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+		ON CONFLICT (user_id, kind, key) DO NOTHING
+		RETURNING id::text, created_at::text`
+	inserted := []models.Anomaly{}
+	for _, anomaly := range anomalies {
+		rows, err := pool.Query(context.Background(), query, anomaly.UserId, anomaly.Kind, anomaly.Key, anomaly.TransactionId, anomaly.Category, anomaly.Amount, anomaly.Reason)
+		if err != nil {
+			return inserted, fmt.Errorf("failed to insert anomaly: %w", err)
+		}
+		// conflicts return no row, the anomaly was flagged before
+		for rows.Next() {
+			if err := rows.Scan(&anomaly.ID, &anomaly.CreatedAt); err != nil {
+				rows.Close()
+				return inserted, err
+			}
+			inserted = append(inserted, anomaly)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return inserted, err
+		}
+	}
+	return inserted, nil
+}
+
+// Newest first. Dismissed anomalies are only included when asked for
+func FetchAnomalies(userId string, includeDismissed bool, pool *pgxpool.Pool) ([]models.Anomaly, error) {
+	query := `SELECT id::text, user_id::text, kind, key, COALESCE(transaction_id, ''), COALESCE(category, ''), amount::float8, reason, dismissed, created_at::text
+		FROM public.anomaly_alerts WHERE user_id = $1 AND (NOT dismissed OR $2) ORDER BY created_at DESC`
+	rows, err := pool.Query(context.Background(), query, userId, includeDismissed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.Anomaly{}
+	for rows.Next() {
+		var a models.Anomaly
+		if err := rows.Scan(&a.ID, &a.UserId, &a.Kind, &a.Key, &a.TransactionId, &a.Category, &a.Amount, &a.Reason, &a.Dismissed, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+func DismissAnomaly(userId string, anomalyId string, pool *pgxpool.Pool) error {
+	query := `UPDATE public.anomaly_alerts SET dismissed = true WHERE user_id = $1 AND id = $2`
+	_, err := pool.Exec(context.Background(), query, userId, anomalyId)
+	return err
+}
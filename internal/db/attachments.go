@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const attachmentColumns = `id, COALESCE(transaction_id, ''), user_id, filename, content_type, size, storage_key, COALESCE(thumbnail_key, ''), created_at::text`
+
+func scanAttachment(row pgx.Row) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.TransactionId, &a.UserId, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.ThumbnailKey, &a.CreatedAt)
+	a.HasThumbnail = a.ThumbnailKey != ""
+	return a, err
+}
+
+func queryAttachments(pool *pgxpool.Pool, query string, args ...any) ([]models.Attachment, error) {
+	rows, err := pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func FetchAttachments(userId string, transactionId string, pool *pgxpool.Pool) ([]models.Attachment, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.attachments WHERE user_id = $1 AND transaction_id = $2 ORDER BY created_at`, attachmentColumns)
+	return queryAttachments(pool, query, userId, transactionId)
+}
+
+func FetchAttachment(userId string, attachmentId string, pool *pgxpool.Pool) (models.Attachment, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.attachments WHERE user_id = $1 AND id = $2`, attachmentColumns)
+	return scanAttachment(pool.QueryRow(context.Background(), query, userId, attachmentId))
+}
+
+// All of the user's attachments, including ones waiting for cleanup
+func FetchUserAttachments(userId string, pool *pgxpool.Pool) ([]models.Attachment, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.attachments WHERE user_id = $1 ORDER BY created_at`, attachmentColumns)
+	return queryAttachments(pool, query, userId)
+}
+
+// Attachments whose transaction was deleted, waiting for their files to be removed
+func FetchOrphanedAttachments(limit int, pool *pgxpool.Pool) ([]models.Attachment, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.attachments WHERE transaction_id IS NULL LIMIT $1`, attachmentColumns)
+	return queryAttachments(pool, query, limit)
+}
+
+func InsertAttachment(a models.Attachment, pool *pgxpool.Pool) (string, error) {
+	var id string
+	query := `INSERT INTO public.attachments (transaction_id, user_id, filename, content_type, size, storage_key, thumbnail_key)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')) RETURNING id`
+	err := pool.QueryRow(context.Background(), query, a.TransactionId, a.UserId, a.Filename, a.ContentType, a.Size, a.StorageKey, a.ThumbnailKey).Scan(&id)
+	return id, err
+}
+
+func DeleteAttachment(attachmentId string, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(context.Background(), `DELETE FROM public.attachments WHERE id = $1`, attachmentId)
+	return err
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const manualBillColumns = `id, user_id, name, amount, COALESCE(account_id, ''), category, cadence, next_due_date::text, notes, created_at::text`
+
+func scanManualBill(row pgx.Row) (models.ManualBill, error) {
+	var b models.ManualBill
+	err := row.Scan(&b.ID, &b.UserId, &b.Name, &b.Amount, &b.AccountId, &b.Category, &b.Cadence, &b.NextDueDate, &b.Notes, &b.CreatedAt)
+	return b, err
+}
+
+func FetchManualBills(userId string, pool *pgxpool.Pool) ([]models.ManualBill, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.manual_bills WHERE user_id = $1 ORDER BY next_due_date`, manualBillColumns)
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manual bills: %w", err)
+	}
+	defer rows.Close()
+
+	bills := []models.ManualBill{}
+	for rows.Next() {
+		b, err := scanManualBill(rows)
+		if err != nil {
+			return nil, err
+		}
+		bills = append(bills, b)
+	}
+	return bills, rows.Err()
+}
+
+func InsertManualBill(b models.ManualBill, pool *pgxpool.Pool) (string, error) {
+	var id string
+	query := `INSERT INTO public.manual_bills (user_id, name, amount, account_id, category, cadence, next_due_date, notes) VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8) RETURNING id`
+	err := pool.QueryRow(context.Background(), query, b.UserId, b.Name, b.Amount, b.AccountId, b.Category, b.Cadence, b.NextDueDate, b.Notes).Scan(&id)
+	return id, err
+}
+
+func UpdateManualBill(b models.ManualBill, pool *pgxpool.Pool) error {
+	query := `UPDATE public.manual_bills SET name = $1, amount = $2, account_id = NULLIF($3, ''), category = $4, cadence = $5, next_due_date = $6, notes = $7 WHERE user_id = $8 AND id = $9`
+	_, err := pool.Exec(context.Background(), query, b.Name, b.Amount, b.AccountId, b.Category, b.Cadence, b.NextDueDate, b.Notes, b.UserId, b.ID)
+	return err
+}
+
+func DeleteManualBill(userId string, billId string, pool *pgxpool.Pool) error {
+	query := `DELETE FROM public.manual_bills WHERE user_id = $1 AND id = $2`
+	_, err := pool.Exec(context.Background(), query, userId, billId)
+	return err
+}
+
+/////////////////////// CALENDAR FEED TOKENS ///////////////////////////
+
+// Saves a new calendar feed token for the user, replacing (and so revoking) any previous one
+func UpsertCalendarToken(userId string, token string, pool *pgxpool.Pool) error {
+	query := `INSERT INTO public.calendar_tokens (user_id, token) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = now()`
+	_, err := pool.Exec(context.Background(), query, userId, token)
+	return err
+}
+
+func FetchUserIdByCalendarToken(token string, pool *pgxpool.Pool) (string, error) {
+	var userId string
+	err := pool.QueryRow(context.Background(), `SELECT user_id FROM public.calendar_tokens WHERE token = $1`, token).Scan(&userId)
+	return userId, err
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const budgetTemplateColumns = `id, user_id, name, total, food, groceries, transportation, entertainment, health, shopping, utilities, housing, travel, education, subscriptions, gifts, insurance, personal_care, other, unknown, created_at::text`
+
+func scanBudgetTemplate(row pgx.Row) (models.BudgetTemplate, error) {
+	var t models.BudgetTemplate
+	err := row.Scan(&t.ID, &t.UserId, &t.Name, &t.Total, &t.Food, &t.Groceries, &t.Transportation, &t.Entertainment, &t.Health, &t.Shopping, &t.Utilities, &t.Housing, &t.Travel, &t.Education, &t.Subscriptions, &t.Gifts, &t.Insurance, &t.PersonalCare, &t.Other, &t.Unknown, &t.CreatedAt)
+	return t, err
+}
+
+func FetchBudgetById(userId string, budgetId string, pool *pgxpool.Pool) (models.StoredBudget, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.budgets WHERE user_id = $1 AND id = $2`, budgetColumns)
+	return scanBudget(pool.QueryRow(context.Background(), query, userId, budgetId))
+}
+
+func FetchBudgetTemplates(userId string, pool *pgxpool.Pool) ([]models.BudgetTemplate, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.budget_templates WHERE user_id = $1 ORDER BY name`, budgetTemplateColumns)
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch budget templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.BudgetTemplate{}
+	for rows.Next() {
+		t, err := scanBudgetTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func FetchBudgetTemplate(userId string, templateId string, pool *pgxpool.Pool) (models.BudgetTemplate, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.budget_templates WHERE user_id = $1 AND id = $2`, budgetTemplateColumns)
+	return scanBudgetTemplate(pool.QueryRow(context.Background(), query, userId, templateId))
+}
+
+func InsertBudgetTemplate(t models.BudgetTemplate, pool *pgxpool.Pool) (string, error) {
+	var id string
+	query := `INSERT INTO public.budget_templates (user_id, name, total, food, groceries, transportation, entertainment, health, shopping, utilities, housing, travel, education, subscriptions, gifts, insurance, personal_care, other, unknown) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19) RETURNING id`
+	err := pool.QueryRow(context.Background(), query, t.UserId, t.Name, t.Total, t.Food, t.Groceries, t.Transportation, t.Entertainment, t.Health, t.Shopping, t.Utilities, t.Housing, t.Travel, t.Education, t.Subscriptions, t.Gifts, t.Insurance, t.PersonalCare, t.Other, t.Unknown).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func DeleteBudgetTemplate(userId string, templateId string, pool *pgxpool.Pool) error {
+	query := `DELETE FROM public.budget_templates WHERE user_id = $1 AND id = $2`
+	_, err := pool.Exec(context.Background(), query, userId, templateId)
+	return err
+}
+
+/////////////////////// AUTO CREATE SETTINGS ///////////////////////////
+
+func FetchBudgetAutoCreateSettings(userId string, pool *pgxpool.Pool) (models.BudgetAutoCreateSettings, error) {
+	settings := models.BudgetAutoCreateSettings{UserId: userId, Source: "previous_month"}
+	query := `SELECT enabled, source, COALESCE(template_id::text, '') FROM public.budget_auto_create WHERE user_id = $1`
+	err := pool.QueryRow(context.Background(), query, userId).Scan(&settings.Enabled, &settings.Source, &settings.TemplateId)
+	if err == pgx.ErrNoRows {
+		// Users who never opted in simply have auto creation disabled
+		return settings, nil
+	}
+	return settings, err
+}
+
+func UpsertBudgetAutoCreateSettings(settings models.BudgetAutoCreateSettings, pool *pgxpool.Pool) error {
+	var templateId *string
+	if settings.TemplateId != "" {
+		templateId = &settings.TemplateId
+	}
+	query := `INSERT INTO public.budget_auto_create (user_id, enabled, source, template_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET enabled = EXCLUDED.enabled, source = EXCLUDED.source, template_id = EXCLUDED.template_id`
+	_, err := pool.Exec(context.Background(), query, settings.UserId, settings.Enabled, settings.Source, templateId)
+	return err
+}
+
+// Returns the settings of every user that opted into automatic budget creation
+func FetchEnabledBudgetAutoCreateSettings(pool *pgxpool.Pool) ([]models.BudgetAutoCreateSettings, error) {
+	query := `SELECT user_id, enabled, source, COALESCE(template_id::text, '') FROM public.budget_auto_create WHERE enabled`
+	rows, err := pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch budget auto create settings: %w", err)
+	}
+	defer rows.Close()
+
+	var all []models.BudgetAutoCreateSettings
+	for rows.Next() {
+		var s models.BudgetAutoCreateSettings
+		if err := rows.Scan(&s.UserId, &s.Enabled, &s.Source, &s.TemplateId); err != nil {
+			return nil, err
+		}
+		all = append(all, s)
+	}
+	return all, rows.Err()
+}
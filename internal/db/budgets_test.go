@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func TestBudgetRoundTrip(t *testing.T) {
+	pool := testPool(t)
+	userId := testUser(t, pool)
+
+	request := models.BudgetRequest{UserId: userId, Year: 2026, Month: 10, Total: 2500, Food: 400}
+	if err := InsertNewBudget(request, pool); err != nil {
+		t.Fatalf("Failed to insert budget: %v", err)
+	}
+
+	budget, err := FetchExistingBudget(request, pool)
+	if err != nil {
+		t.Fatalf("Expected the budget to be read back, got %v", err)
+	}
+	if budget.Period != models.BudgetPeriodMonthly || budget.StartDate != "2026-10-01" || budget.EndDate != "2026-11-01" {
+		t.Errorf("Unexpected period %+v", budget.BudgetPeriod)
+	}
+	if budget.Total != 2500 || budget.Food != 400 {
+		t.Errorf("Unexpected amounts, total %v and food %v", budget.Total, budget.Food)
+	}
+
+	budgets, err := FetchAllExistingBudgets(userId, pool)
+	if err != nil || len(budgets) != 1 || budgets[0].StartDate != "2026-10-01" {
+		t.Errorf("Expected the budget in the user's budgets, got %+v (%v)", budgets, err)
+	}
+}
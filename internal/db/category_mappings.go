@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The user's category mappings for a migration source, keyed by the lower cased source category
+func FetchCategoryMappings(userId string, source string, pool *pgxpool.Pool) (map[string]string, error) {
+	query := `SELECT source_category, category FROM public.category_mappings WHERE user_id = $1 AND source = $2`
+	rows, err := pool.Query(context.Background(), query, userId, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := map[string]string{}
+	for rows.Next() {
+		var sourceCategory, category string
+		if err := rows.Scan(&sourceCategory, &category); err != nil {
+			return nil, err
+		}
+		mappings[sourceCategory] = category
+	}
+	return mappings, rows.Err()
+}
+
+// Saves the mappings in one go. An empty category removes the user's mapping so the default applies again
+func UpdateCategoryMappings(userId string, source string, mappings map[string]string, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for sourceCategory, category := range mappings {
+		if category == "" {
+			_, err = tx.Exec(ctx, `DELETE FROM public.category_mappings WHERE user_id = $1 AND source = $2 AND source_category = $3`, userId, source, sourceCategory)
+		} else {
+			_, err = tx.Exec(ctx, `INSERT INTO public.category_mappings (user_id, source, source_category, category) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (user_id, source, source_category) DO UPDATE SET category = EXCLUDED.category`, userId, source, sourceCategory, category)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save mapping of %s: %w", sourceCategory, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
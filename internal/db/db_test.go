@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+// Connects to the database in SUPABASE_DB_URL (from ../../.env when present) the same way the
+// server does. Tests that need it are skipped when no database is configured
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	godotenv.Load("../../.env")
+	if os.Getenv("SUPABASE_DB_URL") == "" {
+		t.Skip("SUPABASE_DB_URL is not set")
+	}
+	pool, err := Connect(DBConfig{ConnectionString: os.Getenv("SUPABASE_DB_URL")})
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// Creates a throwaway user, removed with everything referencing it when the test ends
+func testUser(t *testing.T, pool *pgxpool.Pool) string {
+	t.Helper()
+	userId := uuid.NewString()
+	query := `INSERT INTO public.users (id, email, first_name, last_name) VALUES ($1, $2, 'Test', 'User')`
+	if _, err := pool.Exec(context.Background(), query, userId, userId+"@example.com"); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), `DELETE FROM public.budgets WHERE user_id = $1`, userId)
+		pool.Exec(context.Background(), `DELETE FROM public.transactions WHERE account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)`, userId)
+		pool.Exec(context.Background(), `DELETE FROM public.accounts WHERE user_id = $1`, userId)
+		pool.Exec(context.Background(), `DELETE FROM public.users WHERE id = $1`, userId)
+	})
+	return userId
+}
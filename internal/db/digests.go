@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrDigestNotFound = errors.New("digest not found")
+
+const digestColumns = `id::text, user_id::text, month, summary, generated_by, facts::text, created_at::text`
+
+func scanDigest(row pgx.Row) (models.MonthlyDigest, error) {
+	var (
+		digest models.MonthlyDigest
+		facts  string
+	)
+	if err := row.Scan(&digest.ID, &digest.UserId, &digest.Month, &digest.Summary, &digest.GeneratedBy, &facts, &digest.CreatedAt); err != nil {
+		return digest, err
+	}
+	err := json.Unmarshal([]byte(facts), &digest.Facts)
+	return digest, err
+}
+
+// Users with at least one account, the ones a digest can be written for
+func FetchUserIdsWithAccounts(pool *pgxpool.Pool) ([]string, error) {
+	rows, err := pool.Query(context.Background(), `SELECT DISTINCT user_id::text FROM public.accounts`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	defer rows.Close()
+
+	userIds := []string{}
+	for rows.Next() {
+		var userId string
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		userIds = append(userIds, userId)
+	}
+	return userIds, rows.Err()
+}
+
+// Stores the digest unless the user already has one for the month, and returns whichever is stored
+func InsertMonthlyDigest(digest models.MonthlyDigest, pool *pgxpool.Pool) (models.MonthlyDigest, bool, error) {
+	facts, err := json.Marshal(digest.Facts)
+	if err != nil {
+		return digest, false, err
+	}
+	query := fmt.Sprintf(`INSERT INTO public.monthly_digests (user_id, month, summary, generated_by, facts) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, month) DO NOTHING RETURNING %s`, digestColumns)
+	inserted, err := scanDigest(pool.QueryRow(context.Background(), query, digest.UserId, digest.Month, digest.Summary, digest.GeneratedBy, string(facts)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		existing, err := FetchMonthlyDigest(digest.UserId, digest.Month, pool)
+		return existing, false, err
+	}
+	return inserted, err == nil, err
+}
+
+func FetchMonthlyDigest(userId string, month string, pool *pgxpool.Pool) (models.MonthlyDigest, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.monthly_digests WHERE user_id = $1 AND month = $2`, digestColumns)
+	digest, err := scanDigest(pool.QueryRow(context.Background(), query, userId, month))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return digest, ErrDigestNotFound
+	}
+	return digest, err
+}
+
+// Newest month first
+func FetchMonthlyDigests(userId string, pool *pgxpool.Pool) ([]models.MonthlyDigest, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.monthly_digests WHERE user_id = $1 ORDER BY month DESC`, digestColumns)
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch digests: %w", err)
+	}
+	defer rows.Close()
+
+	digests := []models.MonthlyDigest{}
+	for rows.Next() {
+		digest, err := scanDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
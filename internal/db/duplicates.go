@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns the user's transactions since the timestamp with their tags, notes and splits, for
+// comparing suspected duplicates
+func FetchDuplicateCandidates(userId string, since int64, pool *pgxpool.Pool) ([]models.Transaction, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.transactions
+		WHERE account_id IN (SELECT id FROM public.accounts WHERE user_id = $1) AND transacted_at >= $2`, transactionColumns)
+	rows, err := pool.Query(context.Background(), query, userId, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch duplicate candidates: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := attachTransactionDetails(transactions, pool); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// Pairs the user said aren't duplicates, keyed by the lower id and then the higher one
+func FetchDuplicateDismissals(userId string, pool *pgxpool.Pool) (map[[2]string]bool, error) {
+	rows, err := pool.Query(context.Background(), `SELECT transaction_id, other_transaction_id FROM public.duplicate_dismissals WHERE user_id = $1`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch duplicate dismissals: %w", err)
+	}
+	defer rows.Close()
+
+	dismissed := map[[2]string]bool{}
+	for rows.Next() {
+		var pair [2]string
+		if err := rows.Scan(&pair[0], &pair[1]); err != nil {
+			return nil, err
+		}
+		dismissed[pair] = true
+	}
+	return dismissed, rows.Err()
+}
+
+// Ids must be ordered lower first and belong to the user
+func InsertDuplicateDismissal(userId string, ids [2]string, pool *pgxpool.Pool) error {
+	query := `INSERT INTO public.duplicate_dismissals (user_id, transaction_id, other_transaction_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	_, err := pool.Exec(context.Background(), query, userId, ids[0], ids[1])
+	return err
+}
+
+// Folds the removed transaction into the kept one and deletes it. The kept transaction gets the
+// given category and notes, and takes over the removed one's tags, custom fields it doesn't have
+// yet, attachments, merchant and transfer, and its splits when it has none. The removed id is
+// remembered so a later sync doesn't insert it again. Both transactions must belong to the user
+func MergeDuplicateTransactions(userId string, keep models.Transaction, remove models.Transaction, balanceDate int64, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE public.transactions SET category = $1, notes = NULLIF($2, ''),
+			merchant_id = COALESCE(merchant_id, (SELECT merchant_id FROM public.transactions WHERE id = $4)),
+			transfer_group_id = COALESCE(transfer_group_id, (SELECT transfer_group_id FROM public.transactions WHERE id = $4))
+		WHERE id = $3`
+	if _, err := tx.Exec(ctx, query, keep.Category, keep.Notes, keep.ID, remove.ID); err != nil {
+		return fmt.Errorf("failed to update kept transaction: %w", err)
+	}
+
+	moves := []struct{ name, query string }{
+		{"tags", `INSERT INTO public.transaction_tags (transaction_id, tag_id)
+			SELECT $1, tag_id FROM public.transaction_tags WHERE transaction_id = $2 ON CONFLICT DO NOTHING`},
+		{"custom fields", `INSERT INTO public.transaction_custom_fields (transaction_id, key, value)
+			SELECT $1, key, value FROM public.transaction_custom_fields WHERE transaction_id = $2 ON CONFLICT DO NOTHING`},
+		{"splits", `UPDATE public.transaction_splits SET transaction_id = $1
+			WHERE transaction_id = $2 AND NOT EXISTS (SELECT 1 FROM public.transaction_splits WHERE transaction_id = $1)`},
+		{"attachments", `UPDATE public.attachments SET transaction_id = $1 WHERE transaction_id = $2`},
+	}
+	for _, move := range moves {
+		if _, err := tx.Exec(ctx, move.query, keep.ID, remove.ID); err != nil {
+			return fmt.Errorf("failed to move %s: %w", move.name, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO public.merged_transactions (id, user_id, kept_id) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET kept_id = EXCLUDED.kept_id`, remove.ID, userId, keep.ID); err != nil {
+		return fmt.Errorf("failed to record merged transaction: %w", err)
+	}
+	// transactions merged into the removed one before now point at the kept one
+	if _, err := tx.Exec(ctx, `UPDATE public.merged_transactions SET kept_id = $1 WHERE kept_id = $2`, keep.ID, remove.ID); err != nil {
+		return fmt.Errorf("failed to update merged transactions: %w", err)
+	}
+
+	var negated string
+	err = tx.QueryRow(ctx, `DELETE FROM public.transactions WHERE id = $1 RETURNING (-amount::numeric)::text`, remove.ID).Scan(&negated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTransactionNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete duplicate: %w", err)
+	}
+	// a duplicate on a manual account was counted in its balance
+	if err := adjustManualBalance(ctx, tx, remove.AccountID, negated, balanceDate); err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Hands the user's transactions matching the date and account filters of the query to fn one at a
+// time, oldest first, with their tags and splits. Rows are read off the connection as fn consumes
+// them rather than loaded up front, so exports of any size stay in constant memory. byAccount
+// orders by account first, for formats that group transactions per account
+func StreamTransactions(ctx context.Context, userId string, q models.TransactionQuery, byAccount bool, fn func(models.Transaction) error, pool *pgxpool.Pool) error {
+	conditions, args, _ := transactionFilters(userId, models.TransactionQuery{From: q.From, To: q.To, AccountIds: q.AccountIds})
+	order := "t.transacted_at, t.id"
+	if byAccount {
+		order = "t.account_id, " + order
+	}
+	query := fmt.Sprintf(`SELECT %s,
+			COALESCE((SELECT array_agg(tg.name ORDER BY tg.name) FROM public.transaction_tags tt JOIN public.tags tg ON tg.id = tt.tag_id
+				WHERE tt.transaction_id = t.id), '{}'),
+			COALESCE((SELECT json_agg(json_build_object('id', s.id, 'transaction_id', s.transaction_id, 'amount', s.amount::text, 'category', s.category, 'memo', s.memo) ORDER BY s.position)
+				FROM public.transaction_splits s WHERE s.transaction_id = t.id), '[]')::text
+		FROM public.transactions t WHERE %s ORDER BY %s`, transactionColumns, strings.Join(conditions, " AND "), order)
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			txn    models.Transaction
+			splits string
+		)
+		if err := rows.Scan(append(transactionScanTargets(&txn), &txn.Tags, &splits)...); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(splits), &txn.Splits); err != nil {
+			return err
+		}
+		if len(txn.Splits) == 0 {
+			txn.Splits = nil
+		}
+		if len(txn.Tags) == 0 {
+			txn.Tags = nil
+		}
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
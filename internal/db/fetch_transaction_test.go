@@ -2,17 +2,10 @@ package db
 
 import (
 	"testing"
-
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
 )
 
-func TestFetchExistingTransaction(t *testing.T, pool *pgxpool.Pool) {
-	// Load environment variables (optional if already done globally in main)
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		t.Fatalf("Error loading .env file: %v", err)
-	}
+func TestFetchExistingTransaction(t *testing.T) {
+	pool := testPool(t)
 
 	// Call FetchExistingTransaction
 	categorizedTransactions, err := FetchExistingTransactions("ACT-17dbc9ca-ce58-4d16-b4f1-f8edc1dd7364", pool)
@@ -27,12 +20,8 @@ func TestFetchExistingTransaction(t *testing.T, pool *pgxpool.Pool) {
 	}
 }
 
-func TestFetchMostRecentTransaction(t *testing.T, pool *pgxpool.Pool) {
-	// Load environment variables (optional if already done globally in main)
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		t.Fatalf("Error loading .env file: %v", err)
-	}
+func TestFetchMostRecentTransaction(t *testing.T) {
+	pool := testPool(t)
 
 	// Call FetchMostRecentTransaction
 	mostRecentTransaction, err := FetchMostRecentTransactionForAnAccount("ACT-17dbc9ca-ce58-4d16-b4f1-f8edc1dd7364", pool)
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const goalColumns = `id, user_id, name, target_amount, target_date::text, account_id, allocation_percent, current_amount, required_monthly, status, COALESCE(projected_completion::text, ''), created_at::text, COALESCE(last_calculated::text, '')`
+
+func scanGoal(row pgx.Row) (models.Goal, error) {
+	var g models.Goal
+	err := row.Scan(&g.ID, &g.UserId, &g.Name, &g.TargetAmount, &g.TargetDate, &g.AccountId, &g.AllocationPercent, &g.CurrentAmount, &g.RequiredMonthly, &g.Status, &g.ProjectedCompletion, &g.CreatedAt, &g.LastCalculated)
+	return g, err
+}
+
+func FetchGoals(userId string, pool *pgxpool.Pool) ([]models.Goal, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.goals WHERE user_id = $1 ORDER BY target_date`, goalColumns)
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []models.Goal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+func FetchGoal(userId string, goalId string, pool *pgxpool.Pool) (models.Goal, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.goals WHERE user_id = $1 AND id = $2`, goalColumns)
+	return scanGoal(pool.QueryRow(context.Background(), query, userId, goalId))
+}
+
+func InsertGoal(g models.Goal, pool *pgxpool.Pool) (string, error) {
+	var id string
+	query := `INSERT INTO public.goals (user_id, name, target_amount, target_date, account_id, allocation_percent) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err := pool.QueryRow(context.Background(), query, g.UserId, g.Name, g.TargetAmount, g.TargetDate, g.AccountId, g.AllocationPercent).Scan(&id)
+	return id, err
+}
+
+func UpdateGoal(g models.Goal, pool *pgxpool.Pool) error {
+	query := `UPDATE public.goals SET name = $1, target_amount = $2, target_date = $3, account_id = $4, allocation_percent = $5 WHERE user_id = $6 AND id = $7`
+	_, err := pool.Exec(context.Background(), query, g.Name, g.TargetAmount, g.TargetDate, g.AccountId, g.AllocationPercent, g.UserId, g.ID)
+	return err
+}
+
+// Stores the output of a goal recalculation
+func UpdateGoalProgress(g models.Goal, pool *pgxpool.Pool) error {
+	query := `UPDATE public.goals SET current_amount = $1, required_monthly = $2, status = $3, projected_completion = NULLIF($4, '')::date, last_calculated = now() WHERE id = $5`
+	_, err := pool.Exec(context.Background(), query, g.CurrentAmount, g.RequiredMonthly, g.Status, g.ProjectedCompletion, g.ID)
+	return err
+}
+
+func DeleteGoal(userId string, goalId string, pool *pgxpool.Pool) error {
+	query := `DELETE FROM public.goals WHERE user_id = $1 AND id = $2`
+	_, err := pool.Exec(context.Background(), query, userId, goalId)
+	return err
+}
+
+// Sums the allocation of every goal on an account except the given one (pass "" for a new goal)
+func FetchAllocatedPercent(accountId string, excludeGoalId string, pool *pgxpool.Pool) (float64, error) {
+	var allocated float64
+	query := `SELECT COALESCE(SUM(allocation_percent), 0)::float8 FROM public.goals WHERE account_id = $1 AND id::text <> $2`
+	err := pool.QueryRow(context.Background(), query, accountId, excludeGoalId).Scan(&allocated)
+	return allocated, err
+}
+
+func UpsertGoalSnapshot(goalId string, snapshot models.GoalSnapshot, pool *pgxpool.Pool) error {
+	query := `INSERT INTO public.goal_snapshots (goal_id, snapshot_date, amount) VALUES ($1, $2, $3)
+		ON CONFLICT (goal_id, snapshot_date) DO UPDATE SET amount = EXCLUDED.amount`
+	_, err := pool.Exec(context.Background(), query, goalId, snapshot.Date, snapshot.Amount)
+	return err
+}
+
+// Returns the goal's snapshots on or after the given date, oldest first
+func FetchGoalSnapshots(goalId string, since string, pool *pgxpool.Pool) ([]models.GoalSnapshot, error) {
+	query := `SELECT snapshot_date::text, amount FROM public.goal_snapshots WHERE goal_id = $1 AND snapshot_date >= $2 ORDER BY snapshot_date`
+	rows, err := pool.Query(context.Background(), query, goalId, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch goal snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []models.GoalSnapshot
+	for rows.Next() {
+		var s models.GoalSnapshot
+		if err := rows.Scan(&s.Date, &s.Amount); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
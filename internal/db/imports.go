@@ -0,0 +1,272 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrImportNotFound  = errors.New("import not found")
+	ErrProfileNotFound = errors.New("import profile not found")
+)
+
+const importColumns = `id::text, user_id::text, account_id, format, filename, status, transaction_count, created_at::text`
+
+func importScanTargets(record *models.ImportRecord) []any {
+	return []any{&record.ID, &record.UserId, &record.AccountId, &record.Format, &record.Filename, &record.Status, &record.TransactionCount, &record.CreatedAt}
+}
+
+// Fetches any of the user's accounts, synced or manual
+func FetchUserAccount(userId string, accountId string, pool *pgxpool.Pool) (models.StoredAccount, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.accounts WHERE user_id = $1 AND id = $2`, accountColumns)
+	acc, err := scanAccount(pool.QueryRow(context.Background(), query, userId, accountId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return acc, ErrAccountNotFound
+	}
+	return acc, err
+}
+
+// Stores a previewed import with its parsed transactions and returns its ID
+func InsertImport(record models.ImportRecord, pool *pgxpool.Pool) (string, error) {
+	rows, err := json.Marshal(record.Transactions)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	query := `INSERT INTO public.imports (user_id, account_id, format, filename, status, rows) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id::text`
+	err = pool.QueryRow(context.Background(), query, record.UserId, record.AccountId, record.Format, record.Filename, record.Status, string(rows)).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert import: %w", err)
+	}
+	return id, nil
+}
+
+// Fetches one of the user's imports along with its parsed transactions
+func FetchImport(userId string, importId string, pool *pgxpool.Pool) (models.ImportRecord, error) {
+	var (
+		record models.ImportRecord
+		rows   string
+	)
+	query := fmt.Sprintf(`SELECT %s, rows::text FROM public.imports WHERE user_id = $1 AND id::text = $2`, importColumns)
+	err := pool.QueryRow(context.Background(), query, userId, importId).Scan(append(importScanTargets(&record), &rows)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return record, ErrImportNotFound
+	} else if err != nil {
+		return record, fmt.Errorf("failed to fetch import: %w", err)
+	}
+	err = json.Unmarshal([]byte(rows), &record.Transactions)
+	return record, err
+}
+
+// The user's import history, newest first. Previews that were never committed are left out
+func FetchImports(userId string, pool *pgxpool.Pool) ([]models.ImportRecord, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.imports WHERE user_id = $1 AND status <> $2 ORDER BY created_at DESC`, importColumns)
+	rows, err := pool.Query(context.Background(), query, userId, models.ImportStatusPreview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch imports: %w", err)
+	}
+	defer rows.Close()
+
+	imports := []models.ImportRecord{}
+	for rows.Next() {
+		var record models.ImportRecord
+		if err := rows.Scan(importScanTargets(&record)...); err != nil {
+			return nil, err
+		}
+		imports = append(imports, record)
+	}
+	return imports, rows.Err()
+}
+
+// Returns which of the IDs InsertNewTransactions and CommitImport would skip: transactions that are already stored
+// and ones that were merged away as duplicates
+func FetchExistingTransactionIds(ids []string, pool *pgxpool.Pool) (map[string]bool, error) {
+	query := `SELECT id FROM public.transactions WHERE id = ANY($1) UNION SELECT id FROM public.merged_transactions WHERE id = ANY($1)`
+	rows, err := pool.Query(context.Background(), query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing transactions: %w", err)
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+	return existing, rows.Err()
+}
+
+// Inserts the transactions of a previewed import that aren't stored yet, marks the import as
+// committed and them as belonging to it, saves their notes and tags, and moves the balance of a
+// manual account by their total. All of it happens in one database transaction, so a failed commit
+// leaves nothing behind and of two concurrent commits of the same import only one gets through.
+// Returns how many transactions were added, or ErrImportNotFound when the import isn't the user's
+// or was already committed
+func CommitImport(userId string, importId string, txns []models.Transaction, balanceDate int64, pool *pgxpool.Pool) (int, error) {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// locks the import row, a concurrent commit waits here and then finds it committed
+	var accountId string
+	query := `UPDATE public.imports SET status = $1, committed_at = now()
+		WHERE user_id = $2 AND id::text = $3 AND status = $4 RETURNING account_id`
+	err = tx.QueryRow(ctx, query, models.ImportStatusCommitted, userId, importId, models.ImportStatusPreview).Scan(&accountId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrImportNotFound
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	transactionIds, err := insertTransactions(ctx, tx, txns)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		added int
+		total string
+	)
+	query = `WITH imported AS (UPDATE public.transactions SET import_id = $1::uuid WHERE id = ANY($2) AND account_id = $3 AND import_id IS NULL RETURNING amount)
+		SELECT COUNT(*), COALESCE(SUM(amount::numeric), 0)::text FROM imported`
+	if err := tx.QueryRow(ctx, query, importId, transactionIds, accountId).Scan(&added, &total); err != nil {
+		return 0, fmt.Errorf("failed to mark imported transactions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE public.imports SET transaction_count = $1 WHERE id::text = $2`, added, importId); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	if err := adjustManualBalance(ctx, tx, accountId, total, balanceDate); err != nil {
+		return 0, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	for _, txn := range txns {
+		if txn.Notes != "" {
+			if _, err := tx.Exec(ctx, `UPDATE public.transactions SET notes = $1 WHERE id = $2 AND import_id = $3::uuid`, txn.Notes, txn.ID, importId); err != nil {
+				return 0, fmt.Errorf("failed to save notes of transaction %s: %w", txn.ID, err)
+			}
+		}
+		if len(txn.Tags) > 0 {
+			if err := updateTransactionTags(ctx, tx, userId, models.TransactionCategoryRequest{ID: txn.ID, AddTags: txn.Tags}); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return added, tx.Commit(ctx)
+}
+
+// Deletes the transactions a committed import added and takes their total back out of a manual
+// account's balance. Transfers they were part of are unlinked on the other side
+func UndoImport(userId string, importId string, balanceDate int64, pool *pgxpool.Pool) (int, error) {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var accountId string
+	query := `UPDATE public.imports SET status = $1, undone_at = now() WHERE user_id = $2 AND id::text = $3 AND status = $4 RETURNING account_id`
+	err = tx.QueryRow(ctx, query, models.ImportStatusUndone, userId, importId, models.ImportStatusCommitted).Scan(&accountId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrImportNotFound
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to undo import: %w", err)
+	}
+
+	query = `UPDATE public.transactions SET transfer_group_id = NULL WHERE (import_id IS NULL OR import_id <> $1::uuid) AND transfer_group_id IN
+		(SELECT transfer_group_id FROM public.transactions WHERE import_id = $1::uuid AND transfer_group_id IS NOT NULL)`
+	if _, err := tx.Exec(ctx, query, importId); err != nil {
+		return 0, fmt.Errorf("failed to unlink transfers: %w", err)
+	}
+
+	var (
+		removed int
+		negated string
+	)
+	query = `WITH removed AS (DELETE FROM public.transactions WHERE import_id = $1::uuid RETURNING amount)
+		SELECT COUNT(*), (-COALESCE(SUM(amount::numeric), 0))::text FROM removed`
+	if err := tx.QueryRow(ctx, query, importId).Scan(&removed, &negated); err != nil {
+		return 0, fmt.Errorf("failed to delete imported transactions: %w", err)
+	}
+	if err := adjustManualBalance(ctx, tx, accountId, negated, balanceDate); err != nil {
+		return 0, fmt.Errorf("failed to update balance: %w", err)
+	}
+	return removed, tx.Commit(ctx)
+}
+
+func scanImportProfile(row pgx.Row) (models.ImportProfile, error) {
+	var (
+		profile models.ImportProfile
+		mapping string
+	)
+	if err := row.Scan(&profile.ID, &profile.UserId, &profile.Name, &mapping); err != nil {
+		return profile, err
+	}
+	err := json.Unmarshal([]byte(mapping), &profile.Mapping)
+	return profile, err
+}
+
+func FetchImportProfiles(userId string, pool *pgxpool.Pool) ([]models.ImportProfile, error) {
+	rows, err := pool.Query(context.Background(), `SELECT id::text, user_id::text, name, mapping::text FROM public.import_profiles WHERE user_id = $1 ORDER BY name`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch import profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []models.ImportProfile{}
+	for rows.Next() {
+		profile, err := scanImportProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+func FetchImportProfile(userId string, profileId string, pool *pgxpool.Pool) (models.ImportProfile, error) {
+	query := `SELECT id::text, user_id::text, name, mapping::text FROM public.import_profiles WHERE user_id = $1 AND id::text = $2`
+	profile, err := scanImportProfile(pool.QueryRow(context.Background(), query, userId, profileId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return profile, ErrProfileNotFound
+	}
+	return profile, err
+}
+
+// Saves the profile, replacing the mapping of an existing profile with the same name, and returns its ID
+func UpsertImportProfile(profile models.ImportProfile, pool *pgxpool.Pool) (string, error) {
+	mapping, err := json.Marshal(profile.Mapping)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	query := `INSERT INTO public.import_profiles (user_id, name, mapping) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, name) DO UPDATE SET mapping = EXCLUDED.mapping RETURNING id::text`
+	if err := pool.QueryRow(context.Background(), query, profile.UserId, profile.Name, string(mapping)).Scan(&id); err != nil {
+		return "", fmt.Errorf("failed to save import profile: %w", err)
+	}
+	return id, nil
+}
+
+func DeleteImportProfile(userId string, profileId string, pool *pgxpool.Pool) error {
+	tag, err := pool.Exec(context.Background(), `DELETE FROM public.import_profiles WHERE user_id = $1 AND id::text = $2`, userId, profileId)
+	if err != nil {
+		return fmt.Errorf("failed to delete import profile: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProfileNotFound
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAccountNotFound = errors.New("account not found")
+
+func FetchManualAccount(userId string, accountId string, pool *pgxpool.Pool) (models.StoredAccount, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.accounts WHERE user_id = $1 AND id = $2 AND manual`, accountColumns)
+	acc, err := scanAccount(pool.QueryRow(context.Background(), query, userId, accountId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return acc, ErrAccountNotFound
+	}
+	return acc, err
+}
+
+func InsertManualAccount(account models.StoredAccount, pool *pgxpool.Pool) error {
+	query := `INSERT INTO public.accounts (id, user_id, name, account_type, currency, balance, available_balance, org_name, balance_date, manual)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, true)`
+	_, err := pool.Exec(context.Background(), query, account.ID, account.UserId, account.Name, account.AccountType, account.Currency, account.Balance, account.Org.Name, account.BalanceDate)
+	return err
+}
+
+func UpdateManualAccount(account models.StoredAccount, pool *pgxpool.Pool) error {
+	query := `UPDATE public.accounts SET name = $1, account_type = $2, currency = $3, balance = $4, available_balance = $4, org_name = $5, balance_date = $6
+		WHERE user_id = $7 AND id = $8 AND manual`
+	_, err := pool.Exec(context.Background(), query, account.Name, account.AccountType, account.Currency, account.Balance, account.Org.Name, account.BalanceDate, account.UserId, account.ID)
+	return err
+}
+
+// Deletes a manual account with all of its transactions. Transfers the transactions were part of
+// are unlinked on the other side
+func DeleteManualAccount(userId string, accountId string, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.accounts WHERE user_id = $1 AND id = $2 AND manual)`, userId, accountId).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAccountNotFound
+	}
+
+	query := `UPDATE public.transactions SET transfer_group_id = NULL WHERE account_id <> $1 AND transfer_group_id IN
+		(SELECT transfer_group_id FROM public.transactions WHERE account_id = $1 AND transfer_group_id IS NOT NULL)`
+	if _, err := tx.Exec(ctx, query, accountId); err != nil {
+		return fmt.Errorf("failed to unlink transfers: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM public.transactions WHERE account_id = $1`, accountId); err != nil {
+		return fmt.Errorf("failed to delete transactions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM public.accounts WHERE id = $1`, accountId); err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Moves the balance of a manual account by the change a transaction made to it
+func adjustManualBalance(ctx context.Context, tx pgx.Tx, accountId string, delta string, balanceDate int64) error {
+	query := `UPDATE public.accounts SET balance = balance + $1::numeric, available_balance = available_balance + $1::numeric, balance_date = $2
+		WHERE id = $3 AND manual`
+	_, err := tx.Exec(ctx, query, delta, balanceDate, accountId)
+	return err
+}
+
+// Adds a transaction to a manual account and moves the account's balance by its amount
+func InsertManualTransaction(txn models.Transaction, balanceDate int64, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO public.transactions (id, account_id, posted, amount, description, payee, memo, transacted_at, category) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	if _, err := tx.Exec(ctx, query, txn.ID, txn.AccountID, txn.Posted, txn.Amount, txn.Description, txn.Payee, txn.Memo, txn.TransactedAt, txn.Category); err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	if err := adjustManualBalance(ctx, tx, txn.AccountID, txn.Amount, balanceDate); err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Replaces a manual transaction and moves the account's balance by the difference in amount. A
+// split whose lines no longer add up to the new amount is removed
+func UpdateManualTransaction(txn models.Transaction, balanceDate int64, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT amount::text FROM public.transactions WHERE id = $1 AND account_id = $2 FOR UPDATE`, txn.ID, txn.AccountID).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTransactionNotFound
+	} else if err != nil {
+		return err
+	}
+
+	query := `UPDATE public.transactions SET posted = $1, amount = $2, description = $3, payee = $4, memo = $5, transacted_at = $6, category = $7, merchant_id = NULL WHERE id = $8`
+	if _, err := tx.Exec(ctx, query, txn.Posted, txn.Amount, txn.Description, txn.Payee, txn.Memo, txn.TransactedAt, txn.Category, txn.ID); err != nil {
+		return fmt.Errorf("failed to update transaction: %w", err)
+	}
+	var (
+		delta   string
+		changed bool
+	)
+	if err := tx.QueryRow(ctx, `SELECT ($1::numeric - $2::numeric)::text, $1::numeric <> $2::numeric`, txn.Amount, previous).Scan(&delta, &changed); err != nil {
+		return err
+	}
+	if changed {
+		if _, err := tx.Exec(ctx, `DELETE FROM public.transaction_splits WHERE transaction_id = $1`, txn.ID); err != nil {
+			return fmt.Errorf("failed to clear transaction splits: %w", err)
+		}
+	}
+	if err := adjustManualBalance(ctx, tx, txn.AccountID, delta, balanceDate); err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Removes a manual transaction and takes its amount back out of the account's balance
+func DeleteManualTransaction(accountId string, transactionId string, balanceDate int64, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var negated string
+	err = tx.QueryRow(ctx, `DELETE FROM public.transactions WHERE id = $1 AND account_id = $2 RETURNING (-amount::numeric)::text`, transactionId, accountId).Scan(&negated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTransactionNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := adjustManualBalance(ctx, tx, accountId, negated, balanceDate); err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	return tx.Commit(ctx)
+}
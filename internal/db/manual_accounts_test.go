@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/google/uuid"
+)
+
+func TestUpdateManualTransactionClearsStaleSplits(t *testing.T) {
+	pool := testPool(t)
+	userId := testUser(t, pool)
+
+	account := models.StoredAccount{ID: "MAN-ACT-" + uuid.NewString(), UserId: uuid.MustParse(userId), Name: "Cash", AccountType: "cash", Currency: "USD", Balance: "0"}
+	if err := InsertManualAccount(account, pool); err != nil {
+		t.Fatalf("Failed to insert manual account: %v", err)
+	}
+	txn := models.Transaction{ID: "MAN-TRN-" + uuid.NewString(), AccountID: account.ID, Posted: 1791000000, TransactedAt: 1791000000, Amount: "-60.00", Payee: "Market", Category: "Groceries"}
+	if err := InsertManualTransaction(txn, 1791000000, pool); err != nil {
+		t.Fatalf("Failed to insert manual transaction: %v", err)
+	}
+	splits := []models.TransactionSplit{{Amount: "-40.00", Category: "Groceries"}, {Amount: "-20.00", Category: "Personal Care"}}
+	if err := ReplaceTransactionSplits(txn.ID, splits, pool); err != nil {
+		t.Fatalf("Failed to split transaction: %v", err)
+	}
+
+	// edits that keep the amount keep the split
+	txn.Memo = "weekly shop"
+	if err := UpdateManualTransaction(txn, 1791000000, pool); err != nil {
+		t.Fatalf("Failed to update manual transaction: %v", err)
+	}
+	stored, err := FetchTransactionSplits([]string{txn.ID}, pool)
+	if err != nil || len(stored[txn.ID]) != 2 {
+		t.Fatalf("Expected the split to be kept, got %v (%v)", stored[txn.ID], err)
+	}
+
+	txn.Amount = "-75.00"
+	if err := UpdateManualTransaction(txn, 1791000000, pool); err != nil {
+		t.Fatalf("Failed to update manual transaction: %v", err)
+	}
+	stored, err = FetchTransactionSplits([]string{txn.ID}, pool)
+	if err != nil || len(stored[txn.ID]) != 0 {
+		t.Errorf("Expected the split to be removed with the amount change, got %v (%v)", stored[txn.ID], err)
+	}
+}
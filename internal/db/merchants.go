@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Finds the user's merchant for a normalized payee key, through an alias or the merchant's own name.
+// Returns an empty ID when there is none
+func FetchMerchantIdByKey(userId string, key string, pool *pgxpool.Pool) (string, string, error) {
+	var id, category string
+	query := `SELECT id::text, COALESCE(category, '') FROM public.merchants WHERE user_id = $1 AND
+			(id = (SELECT merchant_id FROM public.merchant_aliases WHERE user_id = $1 AND alias_key = $2) OR name_key = $2)
+		LIMIT 1`
+	err := pool.QueryRow(context.Background(), query, userId, key).Scan(&id, &category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", nil
+	}
+	return id, category, err
+}
+
+// The category most recently given to one of the merchant's transactions
+func FetchCategoryByMerchant(merchantId string, pool *pgxpool.Pool) (string, error) {
+	var category string
+	query := `SELECT category FROM public.transactions WHERE merchant_id = $1 AND category IS NOT NULL AND category <> ''
+		ORDER BY transacted_at DESC LIMIT 1`
+	err := pool.QueryRow(context.Background(), query, merchantId).Scan(&category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return category, err
+}
+
+// Creates the merchant, or returns the existing one with the same key
+func InsertMerchant(userId string, name string, key string, pool *pgxpool.Pool) (string, error) {
+	var id string
+	query := `INSERT INTO public.merchants (user_id, name, name_key) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, name_key) DO UPDATE SET name_key = EXCLUDED.name_key RETURNING id::text`
+	err := pool.QueryRow(context.Background(), query, userId, name, key).Scan(&id)
+	return id, err
+}
+
+// The user's transactions that haven't been matched to a merchant yet
+func FetchTransactionsWithoutMerchant(userId string, pool *pgxpool.Pool) ([]models.Transaction, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.transactions
+		WHERE merchant_id IS NULL AND account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)`, transactionColumns)
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions without merchant: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, rows.Err()
+}
+
+func SetTransactionsMerchant(transactionIds []string, merchantId string, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(context.Background(), `UPDATE public.transactions SET merchant_id = $1 WHERE id = ANY($2)`, merchantId, transactionIds)
+	return err
+}
+
+func FetchMerchants(userId string, pool *pgxpool.Pool) ([]models.Merchant, error) {
+	query := `SELECT m.id::text, m.user_id::text, m.name, COALESCE(m.category, ''),
+			COALESCE((SELECT array_agg(a.alias ORDER BY a.alias) FROM public.merchant_aliases a WHERE a.merchant_id = m.id), '{}'),
+			(SELECT COUNT(*) FROM public.transactions t WHERE t.merchant_id = m.id)
+		FROM public.merchants m WHERE m.user_id = $1 ORDER BY m.name`
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch merchants: %w", err)
+	}
+	defer rows.Close()
+
+	merchants := []models.Merchant{}
+	for rows.Next() {
+		var m models.Merchant
+		if err := rows.Scan(&m.ID, &m.UserId, &m.Name, &m.Category, &m.Aliases, &m.TransactionCount); err != nil {
+			return nil, err
+		}
+		merchants = append(merchants, m)
+	}
+	return merchants, rows.Err()
+}
+
+// Renames the merchant and sets (or clears, when empty) its category. The category is applied to
+// all of the merchant's transactions
+func UpdateMerchant(m models.Merchant, key string, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `UPDATE public.merchants SET name = $1, name_key = $2, category = NULLIF($3, '') WHERE user_id = $4 AND id = $5`,
+		m.Name, key, m.Category, m.UserId, m.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update merchant: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("merchant %s not found", m.ID)
+	}
+	if m.Category != "" {
+		if _, err := tx.Exec(ctx, `UPDATE public.transactions SET category = $1 WHERE merchant_id = $2`, m.Category, m.ID); err != nil {
+			return fmt.Errorf("failed to recategorize merchant transactions: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Points the alias at the merchant. A merchant that was created for the alias before is merged
+// into this one, moving its transactions and aliases over
+func AddMerchantAlias(userId string, merchantId string, alias string, key string, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.merchants WHERE user_id = $1 AND id = $2)`, userId, merchantId).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("merchant %s not found", merchantId)
+	}
+
+	query := `INSERT INTO public.merchant_aliases (user_id, alias_key, merchant_id, alias) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, alias_key) DO UPDATE SET merchant_id = EXCLUDED.merchant_id, alias = EXCLUDED.alias`
+	if _, err := tx.Exec(ctx, query, userId, key, merchantId, alias); err != nil {
+		return fmt.Errorf("failed to save merchant alias: %w", err)
+	}
+
+	merged := `SELECT id FROM public.merchants WHERE user_id = $1 AND name_key = $2 AND id <> $3`
+	if _, err := tx.Exec(ctx, `UPDATE public.transactions SET merchant_id = $3 WHERE merchant_id IN (`+merged+`)`, userId, key, merchantId); err != nil {
+		return fmt.Errorf("failed to move merchant transactions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE public.merchant_aliases SET merchant_id = $3 WHERE merchant_id IN (`+merged+`)`, userId, key, merchantId); err != nil {
+		return fmt.Errorf("failed to move merchant aliases: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM public.merchants WHERE user_id = $1 AND name_key = $2 AND id <> $3`, userId, key, merchantId); err != nil {
+		return fmt.Errorf("failed to delete merged merchant: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Removes an alias. Transactions already matched through it stay with the merchant
+func DeleteMerchantAlias(userId string, merchantId string, key string, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(context.Background(), `DELETE FROM public.merchant_aliases WHERE user_id = $1 AND merchant_id = $2 AND alias_key = $3`, userId, merchantId, key)
+	return err
+}
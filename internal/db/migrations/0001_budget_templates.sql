@@ -0,0 +1,33 @@
+-- Named, reusable budget amounts and the opt-in for creating each month's budget automatically
+
+CREATE TABLE IF NOT EXISTS public.budget_templates (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    total numeric NOT NULL DEFAULT 0,
+    food numeric NOT NULL DEFAULT 0,
+    groceries numeric NOT NULL DEFAULT 0,
+    transportation numeric NOT NULL DEFAULT 0,
+    entertainment numeric NOT NULL DEFAULT 0,
+    health numeric NOT NULL DEFAULT 0,
+    shopping numeric NOT NULL DEFAULT 0,
+    utilities numeric NOT NULL DEFAULT 0,
+    housing numeric NOT NULL DEFAULT 0,
+    travel numeric NOT NULL DEFAULT 0,
+    education numeric NOT NULL DEFAULT 0,
+    subscriptions numeric NOT NULL DEFAULT 0,
+    gifts numeric NOT NULL DEFAULT 0,
+    insurance numeric NOT NULL DEFAULT 0,
+    personal_care numeric NOT NULL DEFAULT 0,
+    other numeric NOT NULL DEFAULT 0,
+    unknown numeric NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT unique_template_name UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS public.budget_auto_create (
+    user_id uuid PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    enabled boolean NOT NULL DEFAULT false,
+    source text NOT NULL DEFAULT 'previous_month' CHECK (source IN ('previous_month', 'template')),
+    template_id uuid REFERENCES public.budget_templates (id) ON DELETE SET NULL
+);
@@ -0,0 +1,18 @@
+-- Budgets are no longer strictly monthly. Existing rows become monthly budgets covering their year/month.
+
+ALTER TABLE public.budgets
+    ADD COLUMN IF NOT EXISTS period text NOT NULL DEFAULT 'monthly'
+        CHECK (period IN ('monthly', 'weekly', 'biweekly', 'quarterly', 'yearly', 'custom')),
+    ADD COLUMN IF NOT EXISTS start_date date,
+    ADD COLUMN IF NOT EXISTS end_date date;
+
+UPDATE public.budgets
+SET start_date = make_date(year, month, 1),
+    end_date = (make_date(year, month, 1) + interval '1 month')::date
+WHERE start_date IS NULL;
+
+ALTER TABLE public.budgets ALTER COLUMN start_date SET NOT NULL;
+
+-- several budgets can now share a month (e.g. a weekly one next to the monthly one)
+ALTER TABLE public.budgets DROP CONSTRAINT IF EXISTS unique_month_year;
+ALTER TABLE public.budgets ADD CONSTRAINT unique_budget_period UNIQUE (user_id, period, start_date);
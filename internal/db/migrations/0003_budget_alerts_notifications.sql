@@ -0,0 +1,45 @@
+-- Budget overspend alerts and the notifications they produce
+
+CREATE TABLE IF NOT EXISTS public.budget_alert_thresholds (
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    budget_id uuid NOT NULL REFERENCES public.budgets (id) ON DELETE CASCADE,
+    field text NOT NULL,
+    percent numeric NOT NULL CHECK (percent > 0),
+    PRIMARY KEY (budget_id, field, percent)
+);
+
+-- one row per threshold that fired, so each alert goes out once per period window
+CREATE TABLE IF NOT EXISTS public.budget_alert_events (
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    budget_id uuid NOT NULL REFERENCES public.budgets (id) ON DELETE CASCADE,
+    period_start date NOT NULL,
+    field text NOT NULL,
+    percent numeric NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (budget_id, period_start, field, percent)
+);
+
+CREATE TABLE IF NOT EXISTS public.notifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    kind text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    data jsonb NOT NULL DEFAULT '{}',
+    read boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_created_idx ON public.notifications (user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS public.notification_preferences (
+    user_id uuid PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    email_enabled boolean NOT NULL DEFAULT false,
+    email text NOT NULL DEFAULT '',
+    webhook_enabled boolean NOT NULL DEFAULT false,
+    webhook_url text NOT NULL DEFAULT '',
+    webhook_secret text NOT NULL DEFAULT '',
+    quiet_hours_start smallint NOT NULL DEFAULT 0 CHECK (quiet_hours_start BETWEEN 0 AND 23),
+    quiet_hours_end smallint NOT NULL DEFAULT 0 CHECK (quiet_hours_end BETWEEN 0 AND 23),
+    timezone text NOT NULL DEFAULT 'UTC'
+);
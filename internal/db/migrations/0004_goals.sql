@@ -0,0 +1,27 @@
+-- Savings goals tracked against account balances
+
+CREATE TABLE IF NOT EXISTS public.goals (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    target_amount numeric NOT NULL CHECK (target_amount > 0),
+    target_date date NOT NULL,
+    account_id text NOT NULL REFERENCES public.accounts (id) ON DELETE CASCADE,
+    allocation_percent numeric NOT NULL DEFAULT 100 CHECK (allocation_percent > 0 AND allocation_percent <= 100),
+    current_amount numeric NOT NULL DEFAULT 0,
+    required_monthly numeric NOT NULL DEFAULT 0,
+    status text NOT NULL DEFAULT 'not_started',
+    projected_completion date,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    last_calculated timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS goals_user_idx ON public.goals (user_id);
+CREATE INDEX IF NOT EXISTS goals_account_idx ON public.goals (account_id);
+
+CREATE TABLE IF NOT EXISTS public.goal_snapshots (
+    goal_id uuid NOT NULL REFERENCES public.goals (id) ON DELETE CASCADE,
+    snapshot_date date NOT NULL,
+    amount numeric NOT NULL,
+    PRIMARY KEY (goal_id, snapshot_date)
+);
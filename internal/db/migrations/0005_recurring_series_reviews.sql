@@ -0,0 +1,9 @@
+-- Recurring series are detected on the fly from transactions, only the user's review of them is stored
+
+CREATE TABLE IF NOT EXISTS public.recurring_series_reviews (
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    series_id text NOT NULL,
+    review text NOT NULL CHECK (review IN ('confirmed', 'dismissed')),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, series_id)
+);
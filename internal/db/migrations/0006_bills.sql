@@ -0,0 +1,23 @@
+-- Manually entered bills and the per user token behind the .ics calendar feed
+
+CREATE TABLE IF NOT EXISTS public.manual_bills (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    amount numeric NOT NULL CHECK (amount > 0),
+    account_id text REFERENCES public.accounts (id) ON DELETE SET NULL,
+    category text NOT NULL DEFAULT 'Unknown',
+    cadence text NOT NULL DEFAULT 'monthly'
+        CHECK (cadence IN ('once', 'weekly', 'biweekly', 'monthly', 'quarterly', 'annual')),
+    next_due_date date NOT NULL,
+    notes text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS manual_bills_user_idx ON public.manual_bills (user_id);
+
+CREATE TABLE IF NOT EXISTS public.calendar_tokens (
+    user_id uuid PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    token text NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
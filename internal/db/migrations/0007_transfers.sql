@@ -0,0 +1,9 @@
+-- Transfers between a user's own accounts are linked so reports can leave them out
+
+ALTER TABLE public.transactions
+    ADD COLUMN IF NOT EXISTS transfer_group_id uuid,
+    -- set when the user unlinks a transfer so the matcher doesn't pair it up again
+    ADD COLUMN IF NOT EXISTS transfer_locked boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS transactions_transfer_group_idx ON public.transactions (transfer_group_id)
+    WHERE transfer_group_id IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Category lines of a transaction that spans several categories (e.g. one Costco charge for
+-- groceries and household items). The amounts of a transaction's lines add up to its amount
+
+CREATE TABLE IF NOT EXISTS public.transaction_splits (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id text NOT NULL REFERENCES public.transactions (id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    amount numeric NOT NULL,
+    category text NOT NULL,
+    memo text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS transaction_splits_transaction_idx ON public.transaction_splits (transaction_id);
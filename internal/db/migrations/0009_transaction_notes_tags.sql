@@ -0,0 +1,25 @@
+-- Free-form notes, tags and custom key/value fields on transactions
+
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS notes text;
+
+CREATE TABLE IF NOT EXISTS public.tags (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    CONSTRAINT unique_user_tag UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS public.transaction_tags (
+    transaction_id text NOT NULL REFERENCES public.transactions (id) ON DELETE CASCADE,
+    tag_id uuid NOT NULL REFERENCES public.tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (transaction_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS transaction_tags_tag_idx ON public.transaction_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS public.transaction_custom_fields (
+    transaction_id text NOT NULL REFERENCES public.transactions (id) ON DELETE CASCADE,
+    key text NOT NULL,
+    value text NOT NULL,
+    PRIMARY KEY (transaction_id, key)
+);
@@ -0,0 +1,18 @@
+-- Receipts and documents uploaded for transactions. When a transaction is deleted its attachments
+-- lose their transaction_id, and the cleanup job removes their files from the blob store and then
+-- the rows themselves
+
+CREATE TABLE IF NOT EXISTS public.attachments (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id text REFERENCES public.transactions (id) ON DELETE SET NULL,
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    storage_key text NOT NULL,
+    thumbnail_key text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS attachments_transaction_idx ON public.attachments (transaction_id);
+CREATE INDEX IF NOT EXISTS attachments_orphaned_idx ON public.attachments (id) WHERE transaction_id IS NULL;
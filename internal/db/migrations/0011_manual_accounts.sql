@@ -0,0 +1,3 @@
+-- Accounts the user maintains by hand (cash, Venmo, loans, property). Syncs skip them
+
+ALTER TABLE public.accounts ADD COLUMN IF NOT EXISTS manual boolean NOT NULL DEFAULT false;
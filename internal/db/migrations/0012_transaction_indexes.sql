@@ -0,0 +1,7 @@
+-- Indexes behind the filtered and paginated transaction list. Every list query is limited to the
+-- user's accounts, so account_id leads each index
+
+CREATE INDEX IF NOT EXISTS transactions_account_date_idx ON public.transactions (account_id, transacted_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS transactions_account_amount_idx ON public.transactions (account_id, (amount::numeric), id);
+CREATE INDEX IF NOT EXISTS transactions_account_category_idx ON public.transactions (account_id, category);
+CREATE INDEX IF NOT EXISTS transaction_splits_category_idx ON public.transaction_splits (category, transaction_id);
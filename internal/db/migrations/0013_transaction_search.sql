@@ -0,0 +1,12 @@
+-- Typo tolerant transaction search: a full text vector over the text fields and trigram indexes
+-- on payee and description for the word similarity (<%) matches
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple',
+        coalesce(payee, '') || ' ' || coalesce(description, '') || ' ' || coalesce(memo, '') || ' ' || coalesce(notes, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS transactions_search_vector_idx ON public.transactions USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS transactions_payee_trgm_idx ON public.transactions USING gin (payee gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS transactions_description_trgm_idx ON public.transactions USING gin (description gin_trgm_ops);
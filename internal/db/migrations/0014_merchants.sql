@@ -0,0 +1,26 @@
+-- Merchants group the many payee spellings a bank uses for the same business. Transactions keep
+-- their raw payee and point at the merchant it was matched to
+
+CREATE TABLE IF NOT EXISTS public.merchants (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    -- lower cased name used for matching
+    name_key text NOT NULL,
+    -- category every transaction of the merchant gets, overriding the categorizer
+    category text,
+    CONSTRAINT unique_user_merchant UNIQUE (user_id, name_key)
+);
+
+CREATE TABLE IF NOT EXISTS public.merchant_aliases (
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    alias_key text NOT NULL,
+    merchant_id uuid NOT NULL REFERENCES public.merchants (id) ON DELETE CASCADE,
+    alias text NOT NULL,
+    PRIMARY KEY (user_id, alias_key)
+);
+
+ALTER TABLE public.transactions
+    ADD COLUMN IF NOT EXISTS merchant_id uuid REFERENCES public.merchants (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS transactions_merchant_idx ON public.transactions (merchant_id);
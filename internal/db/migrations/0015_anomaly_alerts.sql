@@ -0,0 +1,22 @@
+-- Unusual transactions and spending spikes flagged after each sync
+
+CREATE TABLE IF NOT EXISTS public.anomaly_alerts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    kind text NOT NULL,
+    -- what was flagged: the transaction id, or category and month for spending spikes
+    key text NOT NULL,
+    transaction_id text REFERENCES public.transactions (id) ON DELETE CASCADE,
+    category text,
+    amount numeric NOT NULL DEFAULT 0,
+    reason text NOT NULL,
+    dismissed boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT unique_anomaly UNIQUE (user_id, kind, key)
+);
+
+CREATE INDEX IF NOT EXISTS anomaly_alerts_user_created_idx ON public.anomaly_alerts (user_id, created_at DESC);
+
+-- anomalies always show up under /alerts, this decides whether they are also sent as notifications
+ALTER TABLE public.notification_preferences
+    ADD COLUMN IF NOT EXISTS anomaly_alerts_enabled boolean NOT NULL DEFAULT false;
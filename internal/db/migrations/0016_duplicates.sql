@@ -0,0 +1,18 @@
+-- Suspected duplicate transactions the user looked at
+
+-- pairs the user said aren't duplicates, with the lower id first
+CREATE TABLE IF NOT EXISTS public.duplicate_dismissals (
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    transaction_id text NOT NULL REFERENCES public.transactions (id) ON DELETE CASCADE,
+    other_transaction_id text NOT NULL REFERENCES public.transactions (id) ON DELETE CASCADE,
+    PRIMARY KEY (transaction_id, other_transaction_id),
+    CHECK (transaction_id < other_transaction_id)
+);
+
+-- ids of transactions merged into another one, so a later sync doesn't bring them back
+CREATE TABLE IF NOT EXISTS public.merged_transactions (
+    id text PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    kept_id text NOT NULL REFERENCES public.transactions (id) ON DELETE CASCADE,
+    merged_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,15 @@
+-- Monthly narrative summaries, kept so users can look back at earlier months
+
+CREATE TABLE IF NOT EXISTS public.monthly_digests (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    -- YYYY-MM
+    month text NOT NULL,
+    summary text NOT NULL,
+    -- "llm", or "template" when no language model was available
+    generated_by text NOT NULL,
+    -- the numbers the summary was written from
+    facts jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT unique_user_digest_month UNIQUE (user_id, month)
+);
@@ -0,0 +1,36 @@
+-- Transactions imported from bank export files (CSV, OFX/QFX, QIF)
+
+-- saved CSV column mappings, usually one per bank
+CREATE TABLE IF NOT EXISTS public.import_profiles (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    mapping jsonb NOT NULL,
+    CONSTRAINT unique_user_import_profile UNIQUE (user_id, name)
+);
+
+-- every uploaded file. The parsed rows are held here between the preview and the commit
+CREATE TABLE IF NOT EXISTS public.imports (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    account_id text NOT NULL REFERENCES public.accounts (id) ON DELETE CASCADE,
+    format text NOT NULL,
+    filename text NOT NULL DEFAULT '',
+    -- preview, committed or undone
+    status text NOT NULL DEFAULT 'preview',
+    rows jsonb NOT NULL DEFAULT '[]',
+    -- transactions the commit actually added, rows already in the account are skipped
+    transaction_count integer NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    committed_at timestamptz,
+    undone_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS imports_user_idx ON public.imports (user_id, created_at DESC);
+
+-- which import added a transaction, so the import can be undone
+ALTER TABLE public.transactions
+    ADD COLUMN IF NOT EXISTS import_id uuid REFERENCES public.imports (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS transactions_import_idx ON public.transactions (import_id)
+    WHERE import_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Categories of other budgeting apps (Mint, YNAB, Monarch) the user mapped onto ours for migrating
+
+CREATE TABLE IF NOT EXISTS public.category_mappings (
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    -- mint, ynab or monarch
+    source text NOT NULL,
+    -- lower cased
+    source_category text NOT NULL,
+    category text NOT NULL,
+    PRIMARY KEY (user_id, source, source_category)
+);
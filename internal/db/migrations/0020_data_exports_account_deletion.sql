@@ -0,0 +1,37 @@
+-- Full data exports and account deletion
+
+-- zips of all of a user's data, built in the background and kept in the blob store until they expire
+CREATE TABLE IF NOT EXISTS public.data_exports (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    -- pending, running, ready or failed
+    status text NOT NULL DEFAULT 'pending',
+    storage_key text,
+    size bigint NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    started_at timestamptz,
+    completed_at timestamptz,
+    expires_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_idx ON public.data_exports (user_id, created_at DESC);
+
+-- requested deletions, carried out once scheduled_for has passed unless cancelled first. The row
+-- goes away with the rest of the user's data
+CREATE TABLE IF NOT EXISTS public.account_deletions (
+    user_id uuid PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    requested_at timestamptz NOT NULL DEFAULT now(),
+    scheduled_for timestamptz NOT NULL
+);
+
+-- record of exports and deletions. Deliberately not tied to public.users so it outlives the account
+CREATE TABLE IF NOT EXISTS public.audit_log (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    action text NOT NULL,
+    details jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_idx ON public.audit_log (user_id, created_at);
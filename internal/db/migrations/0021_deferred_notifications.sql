@@ -0,0 +1,13 @@
+-- Email and webhook deliveries held back during the user's quiet hours, sent once they are over.
+-- The notification itself is already in the inbox
+
+CREATE TABLE IF NOT EXISTS public.deferred_notifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    -- push channels to deliver to once quiet hours end: email and/or webhook
+    channels text[] NOT NULL,
+    notification jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS deferred_notifications_user_idx ON public.deferred_notifications (user_id, created_at);
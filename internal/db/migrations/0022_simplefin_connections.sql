@@ -0,0 +1,9 @@
+-- Each user's own SimpleFIN connection. The access URL is claimed from the setup token the user
+-- hands over and holds the credentials for their bank connections only. Users without one sync
+-- through the server's shared SIMPLE_FIN_USERNAME/SIMPLE_FIN_PASSWORD
+
+CREATE TABLE IF NOT EXISTS public.simplefin_connections (
+    user_id uuid PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    access_url text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func InsertNotification(n models.Notification, pool *pgxpool.Pool) (string, error) {
+	data, err := json.Marshal(n.Data)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	query := `INSERT INTO public.notifications (user_id, kind, title, body, data) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err = pool.QueryRow(context.Background(), query, n.UserId, n.Kind, n.Title, n.Body, string(data)).Scan(&id)
+	return id, err
+}
+
+func FetchNotifications(userId string, unreadOnly bool, pool *pgxpool.Pool) ([]models.Notification, error) {
+	query := `SELECT id, user_id, kind, title, body, data::text, read, created_at::text FROM public.notifications
+		WHERE user_id = $1 AND (NOT $2 OR NOT read) ORDER BY created_at DESC LIMIT 200`
+	rows, err := pool.Query(context.Background(), query, userId, unreadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var (
+			n    models.Notification
+			data string
+		)
+		if err := rows.Scan(&n.ID, &n.UserId, &n.Kind, &n.Title, &n.Body, &data, &n.Read, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &n.Data); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// Holds a notification back from the push channels until the user's quiet hours are over
+func DeferNotification(n models.Notification, channels []string, pool *pgxpool.Pool) error {
+	notification, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO public.deferred_notifications (user_id, channels, notification) VALUES ($1, $2, $3)`
+	if _, err := pool.Exec(context.Background(), query, n.UserId, channels, string(notification)); err != nil {
+		return fmt.Errorf("failed to defer notification: %w", err)
+	}
+	return nil
+}
+
+// Users with deliveries waiting for their quiet hours to end
+func FetchDeferredNotificationUsers(pool *pgxpool.Pool) ([]string, error) {
+	rows, err := pool.Query(context.Background(), `SELECT DISTINCT user_id::text FROM public.deferred_notifications`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deferred notifications: %w", err)
+	}
+	defer rows.Close()
+
+	userIds := []string{}
+	for rows.Next() {
+		var userId string
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		userIds = append(userIds, userId)
+	}
+	return userIds, rows.Err()
+}
+
+// The user's held back deliveries, oldest first
+func FetchDeferredNotifications(userId string, pool *pgxpool.Pool) ([]models.DeferredNotification, error) {
+	query := `SELECT id::text, channels, notification::text FROM public.deferred_notifications WHERE user_id = $1 ORDER BY created_at`
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deferred notifications: %w", err)
+	}
+	defer rows.Close()
+
+	deferred := []models.DeferredNotification{}
+	for rows.Next() {
+		var (
+			d            models.DeferredNotification
+			notification string
+		)
+		if err := rows.Scan(&d.ID, &d.Channels, &notification); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(notification), &d.Notification); err != nil {
+			return nil, err
+		}
+		deferred = append(deferred, d)
+	}
+	return deferred, rows.Err()
+}
+
+func DeleteDeferredNotification(id string, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(context.Background(), `DELETE FROM public.deferred_notifications WHERE id::text = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete deferred notification: %w", err)
+	}
+	return nil
+}
+
+func MarkNotificationRead(userId string, notificationId string, pool *pgxpool.Pool) error {
+	query := `UPDATE public.notifications SET read = true WHERE user_id = $1 AND id = $2`
+	_, err := pool.Exec(context.Background(), query, userId, notificationId)
+	return err
+}
+
+func FetchNotificationPreferences(userId string, pool *pgxpool.Pool) (models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{UserId: userId, Timezone: "UTC"}
+	query := `SELECT email_enabled, email, webhook_enabled, webhook_url, webhook_secret, quiet_hours_start, quiet_hours_end, timezone, anomaly_alerts_enabled
+		FROM public.notification_preferences WHERE user_id = $1`
+	err := pool.QueryRow(context.Background(), query, userId).Scan(&prefs.EmailEnabled, &prefs.Email, &prefs.WebhookEnabled, &prefs.WebhookURL, &prefs.WebhookSecret, &prefs.QuietHoursStart, &prefs.QuietHoursEnd, &prefs.Timezone, &prefs.AnomalyAlertsEnabled)
+	if err == pgx.ErrNoRows {
+		// without saved preferences notifications only go to the inbox
+		return prefs, nil
+	}
+	return prefs, err
+}
+
+func UpsertNotificationPreferences(prefs models.NotificationPreferences, pool *pgxpool.Pool) error {
+	query := `INSERT INTO public.notification_preferences (user_id, email_enabled, email, webhook_enabled, webhook_url, webhook_secret, quiet_hours_start, quiet_hours_end, timezone, anomaly_alerts_enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id) DO UPDATE SET email_enabled = EXCLUDED.email_enabled, email = EXCLUDED.email,
+			webhook_enabled = EXCLUDED.webhook_enabled, webhook_url = EXCLUDED.webhook_url, webhook_secret = EXCLUDED.webhook_secret,
+			quiet_hours_start = EXCLUDED.quiet_hours_start, quiet_hours_end = EXCLUDED.quiet_hours_end, timezone = EXCLUDED.timezone,
+			anomaly_alerts_enabled = EXCLUDED.anomaly_alerts_enabled`
+	_, err := pool.Exec(context.Background(), query, prefs.UserId, prefs.EmailEnabled, prefs.Email, prefs.WebhookEnabled, prefs.WebhookURL, prefs.WebhookSecret, prefs.QuietHoursStart, prefs.QuietHoursEnd, prefs.Timezone, prefs.AnomalyAlertsEnabled)
+	return err
+}
+
+////////////////////////// BUDGET ALERTS /////////////////////////////////////
+
+func FetchBudgetAlertThresholds(userId string, budgetId string, pool *pgxpool.Pool) ([]models.BudgetAlertThreshold, error) {
+	query := `SELECT field, percent FROM public.budget_alert_thresholds WHERE user_id = $1 AND budget_id = $2 ORDER BY field, percent`
+	rows, err := pool.Query(context.Background(), query, userId, budgetId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch budget alert thresholds: %w", err)
+	}
+	defer rows.Close()
+
+	thresholds := []models.BudgetAlertThreshold{}
+	for rows.Next() {
+		var t models.BudgetAlertThreshold
+		if err := rows.Scan(&t.Field, &t.Percent); err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, rows.Err()
+}
+
+// Replaces every threshold of a budget with the given ones
+func ReplaceBudgetAlertThresholds(userId string, budgetId string, thresholds []models.BudgetAlertThreshold, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM public.budget_alert_thresholds WHERE user_id = $1 AND budget_id = $2`, userId, budgetId); err != nil {
+		return err
+	}
+	for _, t := range thresholds {
+		query := `INSERT INTO public.budget_alert_thresholds (user_id, budget_id, field, percent) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(ctx, query, userId, budgetId, t.Field, t.Percent); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Records that a threshold fired for a period window. Returns false if it already fired, which is
+// what keeps each alert to once per period per threshold
+func InsertBudgetAlertEvent(userId string, budgetId string, periodStart string, threshold models.BudgetAlertThreshold, pool *pgxpool.Pool) (bool, error) {
+	query := `INSERT INTO public.budget_alert_events (user_id, budget_id, period_start, field, percent) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`
+	result, err := pool.Exec(context.Background(), query, userId, budgetId, periodStart, threshold.Field, threshold.Percent)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
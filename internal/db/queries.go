@@ -14,11 +14,28 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const accountColumns = `id, user_id, name, account_type, currency, balance, available_balance, org_name, balance_date, manual`
+
+func scanAccount(row pgx.Row) (models.StoredAccount, error) {
+	// storing these values as numeric in the database, even though they return from the simplefin as strings
+	// this does a conversion to allow us to store them as strings again after getting back from the db
+	// Maybe update the DB instead?
+	var (
+		balance          float64
+		availableBalance float64
+	)
+	var acc models.StoredAccount
+	err := row.Scan(&acc.ID, &acc.UserId, &acc.Name, &acc.AccountType, &acc.Currency, &balance, &availableBalance, &acc.Org.Name, &acc.BalanceDate, &acc.Manual)
+	acc.Balance = fmt.Sprintf("%.2f", balance)
+	acc.AvailableBalance = fmt.Sprintf("%.2f", availableBalance)
+	return acc, err
+}
+
 func FetchExistingAccounts(userId uuid.UUID, pool *pgxpool.Pool) ([]models.StoredAccount, error) {
 	logger := log.Default()
 	// Load configuration (you can expand this later)
 
-	query := `SELECT * FROM public.accounts WHERE user_id = $1`
+	query := fmt.Sprintf(`SELECT %s FROM public.accounts WHERE user_id = $1`, accountColumns)
 	rows, err := pool.Query(context.Background(), query, userId)
 	if err != nil {
 		log.Fatalf("Failed to get accounts: %s", err)
@@ -29,20 +46,10 @@ func FetchExistingAccounts(userId uuid.UUID, pool *pgxpool.Pool) ([]models.Store
 
 	// get all accounts and map them to the transaction map
 	for rows.Next() {
-		// storing these values as numeric in the database, even though they return from the simplefin as strings
-		// this does a conversion to allow us to store them as strings again after getting back from the db
-		// Maybe update the DB instead?
-		var (
-			balance          float64
-			availableBalance float64
-		)
-		var acc models.StoredAccount
-		err := rows.Scan(&acc.ID, &acc.UserId, &acc.Name, &acc.AccountType, &acc.Currency, &balance, &availableBalance, &acc.Org.Name, &acc.BalanceDate)
+		acc, err := scanAccount(rows)
 		if err != nil {
 			return nil, err
 		}
-		acc.Balance = fmt.Sprintf("%.2f", balance)
-		acc.AvailableBalance = fmt.Sprintf("%.2f", availableBalance)
 		accounts = append(accounts, acc)
 		rowCount++
 	}
@@ -70,9 +77,10 @@ func InsertNewAccounts(account models.StoredAccount, pool *pgxpool.Pool) error {
 }
 
 func UpdateExistingAccounts(account models.UpdatedAccountData, pool *pgxpool.Pool) error {
+	// manual accounts are maintained by the user, a sync must never overwrite their balances
 	query := `UPDATE public.accounts 
           SET balance = $1, available_balance = $2, balance_date = $3
-          WHERE id = $4`
+          WHERE id = $4 AND NOT manual`
 	_, err := pool.Exec(context.Background(), query, account.Balance, account.AvailableBalance, account.BalanceDate, account.ID)
 	if err != nil {
 		log.Printf("Failed to update category for transaction with ID: %s\n", account.ID)
@@ -85,6 +93,19 @@ func UpdateExistingAccounts(account models.UpdatedAccountData, pool *pgxpool.Poo
 
 ///////////////// TRANSACTIONS //////////////////////
 
+const transactionColumns = `id, account_id, amount, description, payee, memo, category, transacted_at, posted, COALESCE(transfer_group_id::text, ''), COALESCE(notes, ''), COALESCE(merchant_id::text, '')`
+
+// Scan destinations matching transactionColumns, for queries that select extra columns after them
+func transactionScanTargets(txn *models.Transaction) []any {
+	return []any{&txn.ID, &txn.AccountID, &txn.Amount, &txn.Description, &txn.Payee, &txn.Memo, &txn.Category, &txn.TransactedAt, &txn.Posted, &txn.TransferGroupId, &txn.Notes, &txn.MerchantId}
+}
+
+func scanTransaction(row pgx.Row) (models.Transaction, error) {
+	var txn models.Transaction
+	err := row.Scan(transactionScanTargets(&txn)...)
+	return txn, err
+}
+
 // Fetches every single transaction in the db. Will want to update this to fetch by userId
 func FetchAllTransactions(userAccounts []models.StoredAccount, pool *pgxpool.Pool) ([]models.Transaction, error) {
 	logger := log.Default()
@@ -109,7 +130,7 @@ func FetchAllTransactions(userAccounts []models.StoredAccount, pool *pgxpool.Poo
 	}
 
 	// Query only transactions that belong to the user's accounts
-	query := fmt.Sprintf(`SELECT * FROM public.transactions WHERE account_id IN (%s)`, strings.Join(placeholders, ","))
+	query := fmt.Sprintf(`SELECT %s FROM public.transactions WHERE account_id IN (%s) ORDER BY transacted_at DESC, id DESC`, transactionColumns, strings.Join(placeholders, ","))
 
 	rows, err := pool.Query(context.Background(), query, args...)
 	if err != nil {
@@ -118,13 +139,15 @@ func FetchAllTransactions(userAccounts []models.StoredAccount, pool *pgxpool.Poo
 
 	transactions := []models.Transaction{}
 	for rows.Next() {
-		var txn models.Transaction
-		err := rows.Scan(&txn.ID, &txn.AccountID, &txn.Amount, &txn.Description, &txn.Payee, &txn.Memo, &txn.Category, &txn.TransactedAt, &txn.Posted)
+		txn, err := scanTransaction(rows)
 		if err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, txn)
 	}
+	if err := attachTransactionDetails(transactions, pool); err != nil {
+		return nil, err
+	}
 
 	logger.Printf("Number of transactions fetched: %d", len(transactions))
 	return transactions, nil
@@ -134,7 +157,7 @@ func FetchExistingTransactions(accountId string, pool *pgxpool.Pool) ([]models.T
 	logger := log.Default()
 	// Load configuration (you can expand this later)
 
-	query := `SELECT * FROM public.transactions WHERE account_id = $1`
+	query := fmt.Sprintf(`SELECT %s FROM public.transactions WHERE account_id = $1`, transactionColumns)
 	rows, err := pool.Query(context.Background(), query, accountId)
 	if err != nil {
 		log.Fatalf("Failed to get transactions: %s", err)
@@ -146,14 +169,16 @@ func FetchExistingTransactions(accountId string, pool *pgxpool.Pool) ([]models.T
 	// get all transactions and map them to the transaction map
 	for rows.Next() {
 		rowCount++
-		var txn models.Transaction
-		err := rows.Scan(&txn.ID, &txn.AccountID, &txn.Amount, &txn.Description, &txn.Payee, &txn.Memo, &txn.Category, &txn.TransactedAt, &txn.Posted)
+		txn, err := scanTransaction(rows)
 		if err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, txn) // Use ID as a map key for easy lookups
 	}
 	log.Printf("Number of transactions fetched: %d", rowCount)
+	if err := attachTransactionDetails(transactions, pool); err != nil {
+		return nil, err
+	}
 
 	return transactions, nil
 
@@ -161,7 +186,8 @@ func FetchExistingTransactions(accountId string, pool *pgxpool.Pool) ([]models.T
 
 func FetchMostRecentTransactionForAllAccounts(pool *pgxpool.Pool) (int64, error) {
 	var lastTransactionDate *int64
-	err := pool.QueryRow(context.Background(), "SELECT MAX(transacted_at) FROM public.transactions").Scan(&lastTransactionDate)
+	err := pool.QueryRow(context.Background(), `SELECT MAX(transacted_at) FROM public.transactions
+		WHERE account_id NOT IN (SELECT id FROM public.accounts WHERE manual)`).Scan(&lastTransactionDate)
 	if err == pgx.ErrNoRows || lastTransactionDate == nil {
 		fmt.Println("No transactions found for this account")
 		return getLast30DaysTimestamp(), nil
@@ -201,43 +227,41 @@ func getLast30DaysTimestamp() int64 {
 }
 
 func InsertNewTransactions(txns []models.Transaction, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := insertTransactions(ctx, tx, txns); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Inserts the transactions that aren't stored yet and returns the IDs of the ones it inserted
+func insertTransactions(ctx context.Context, tx pgx.Tx, txns []models.Transaction) ([]string, error) {
+	var inserted []string
 	for _, txn := range txns {
-		// Check if transaction ID already exists
+		// Check if transaction ID already exists, or was merged into another transaction as a duplicate
 		var exists bool
-		err := pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM public.transactions WHERE id = $1)", txn.ID).Scan(&exists)
+		err := tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM public.transactions WHERE id = $1) OR EXISTS(SELECT 1 FROM public.merged_transactions WHERE id = $1)", txn.ID).Scan(&exists)
 		if err != nil {
-			log.Fatalf("Failed to check existing transaction: %v\n", err)
+			return nil, fmt.Errorf("failed to check existing transaction %s: %w", txn.ID, err)
 		}
 		if exists {
 			continue // Skip this transaction if it already exists
 		}
 
 		query := `INSERT INTO public.transactions (id, account_id, posted, amount, description, payee, memo, transacted_at, category) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-		_, err = pool.Exec(context.Background(), query, txn.ID, txn.AccountID, txn.Posted, txn.Amount, txn.Description, txn.Payee, txn.Memo, txn.TransactedAt, txn.Category)
+		_, err = tx.Exec(ctx, query, txn.ID, txn.AccountID, txn.Posted, txn.Amount, txn.Description, txn.Payee, txn.Memo, txn.TransactedAt, txn.Category)
 		if err != nil {
-			log.Printf("Failed to insert transaction with ID: %s, AccountID: %s\n", txn.ID, txn.AccountID)
-			log.Fatalf("Unable to insert new transactions into database: %v\n", err)
-			return err
+			return nil, fmt.Errorf("failed to insert transaction %s into account %s: %w", txn.ID, txn.AccountID, err)
 		}
+		inserted = append(inserted, txn.ID)
 	}
-	return nil
-}
-
-func UpdateTransactionCategory(txns models.UpdatedTransactions, pool *pgxpool.Pool) error {
-
-	for _, txn := range txns.UpdatedTransactions {
-		query := `UPDATE public.transactions 
-          SET category = $1 
-          WHERE id = $2`
-		_, err := pool.Exec(context.Background(), query, txn.Category, txn.ID)
-		if err != nil {
-			log.Printf("Failed to update category for transaction with ID: %s\n", txn.ID)
-			log.Fatalf("Unable to update transaction in database: %v\n", err)
-			return err
-		}
-
-	}
-	return nil
+	return inserted, nil
 }
 
 func FetchCategoryByPayee(txn models.Transaction, pool *pgxpool.Pool) (string, error) {
@@ -258,12 +282,53 @@ func FetchCategoryByPayee(txn models.Transaction, pool *pgxpool.Pool) (string, e
 	return category, nil
 }
 
+// Sums the spending (negative amounts) of a user's transactions between two unix timestamps, grouped
+// by category. Amounts are returned as positive numbers. The end timestamp is exclusive.
+// Transfers between the user's own accounts are not spending and are left out.
+func FetchSpendingByCategory(userId string, start int64, end int64, pool *pgxpool.Pool) (map[string]float64, error) {
+	// split transactions count once per split line instead of under the parent's category
+	query := `SELECT COALESCE(s.category, t.category, 'Unknown') AS line_category,
+			SUM(-COALESCE(s.amount, t.amount::numeric))::float8
+		FROM public.transactions t
+		JOIN public.accounts a ON a.id = t.account_id
+		LEFT JOIN public.transaction_splits s ON s.transaction_id = t.id
+		WHERE a.user_id = $1 AND t.transacted_at >= $2 AND t.transacted_at < $3
+			AND COALESCE(s.amount, t.amount::numeric) < 0 AND t.transfer_group_id IS NULL
+		GROUP BY line_category`
+	rows, err := pool.Query(context.Background(), query, userId, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch spending by category: %w", err)
+	}
+	defer rows.Close()
+
+	spending := map[string]float64{}
+	for rows.Next() {
+		var (
+			category string
+			total    float64
+		)
+		if err := rows.Scan(&category, &total); err != nil {
+			return nil, err
+		}
+		spending[category] = total
+	}
+	return spending, rows.Err()
+}
+
 ////////////////////////// BUDGET /////////////////////////////////////
 
-func FetchExistingBudget(budgetRequest models.BudgetRequest, pool *pgxpool.Pool) (models.StoredBudget, error) {
+const budgetColumns = `id, user_id, year, month, total, food, groceries, transportation, entertainment, health, shopping, utilities, housing, travel, education, subscriptions, gifts, insurance, personal_care, other, unknown, created_at, last_updated, period, start_date::text, COALESCE(end_date::text, '')`
+
+func scanBudget(row pgx.Row) (models.StoredBudget, error) {
 	var budget models.StoredBudget
-	query := `SELECT * FROM public.budgets WHERE user_id = $1 AND year = $2 AND month = $3`
-	err := pool.QueryRow(context.Background(), query, budgetRequest.UserId, budgetRequest.Year, budgetRequest.Month).Scan(&budget.ID, &budget.UserId, &budget.Year, &budget.Month, &budget.Total, &budget.Food, &budget.Groceries, &budget.Transportation, &budget.Entertainment, &budget.Health, &budget.Shopping, &budget.Utilities, &budget.Housing, &budget.Travel, &budget.Education, &budget.Subscriptions, &budget.Gifts, &budget.Insurance, &budget.PersonalCare, &budget.Other, &budget.Unknown, &budget.CreatedAt, &budget.LastUpdated)
+	err := row.Scan(&budget.ID, &budget.UserId, &budget.Year, &budget.Month, &budget.Total, &budget.Food, &budget.Groceries, &budget.Transportation, &budget.Entertainment, &budget.Health, &budget.Shopping, &budget.Utilities, &budget.Housing, &budget.Travel, &budget.Education, &budget.Subscriptions, &budget.Gifts, &budget.Insurance, &budget.PersonalCare, &budget.Other, &budget.Unknown, &budget.CreatedAt, &budget.LastUpdated, &budget.Period, &budget.StartDate, &budget.EndDate)
+	return budget, err
+}
+
+// Fetches the monthly budget for the requested year and month
+func FetchExistingBudget(budgetRequest models.BudgetRequest, pool *pgxpool.Pool) (models.StoredBudget, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.budgets WHERE user_id = $1 AND year = $2 AND month = $3 AND period = 'monthly'`, budgetColumns)
+	budget, err := scanBudget(pool.QueryRow(context.Background(), query, budgetRequest.UserId, budgetRequest.Year, budgetRequest.Month))
 	if err != nil {
 		return models.StoredBudget{}, err
 	}
@@ -278,15 +343,14 @@ func FetchAllExistingBudgets(userId string, pool *pgxpool.Pool) ([]models.Stored
 	}
 
 	var budgets []models.StoredBudget
-	query := `SELECT * FROM public.budgets WHERE user_id = $1`
+	query := fmt.Sprintf(`SELECT %s FROM public.budgets WHERE user_id = $1`, budgetColumns)
 	rows, err := pool.Query(context.Background(), query, userUUID)
 	if err != nil {
 		return []models.StoredBudget{}, err
 	}
 
 	for rows.Next() {
-		var budget models.StoredBudget
-		err := rows.Scan(&budget.ID, &budget.UserId, &budget.Year, &budget.Month, &budget.Total, &budget.Food, &budget.Groceries, &budget.Transportation, &budget.Entertainment, &budget.Health, &budget.Shopping, &budget.Utilities, &budget.Housing, &budget.Travel, &budget.Education, &budget.Subscriptions, &budget.Gifts, &budget.Insurance, &budget.PersonalCare, &budget.Other, &budget.Unknown, &budget.CreatedAt, &budget.LastUpdated)
+		budget, err := scanBudget(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -303,8 +367,13 @@ func InsertNewBudget(budgetRequest models.BudgetRequest, pool *pgxpool.Pool) err
 		return err
 	}
 
-	query := `INSERT INTO public.budgets (user_id, year, month, total, food, groceries, transportation, entertainment, health, shopping, utilities, housing, travel, education, subscriptions, gifts, insurance, personal_care, other, unknown) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
-	_, err = pool.Exec(context.Background(), query, userUUID, budgetRequest.Year, budgetRequest.Month, budgetRequest.Total, budgetRequest.Food, budgetRequest.Groceries, budgetRequest.Transportation, budgetRequest.Entertainment, budgetRequest.Health, budgetRequest.Shopping, budgetRequest.Utilities, budgetRequest.Housing, budgetRequest.Travel, budgetRequest.Education, budgetRequest.Subscriptions, budgetRequest.Gifts, budgetRequest.Insurance, budgetRequest.PersonalCare, budgetRequest.Other, budgetRequest.Unknown)
+	// Requests that don't specify a period are plain monthly budgets
+	if budgetRequest.Period == "" {
+		budgetRequest.BudgetPeriod = models.MonthlyPeriod(budgetRequest.Year, budgetRequest.Month)
+	}
+
+	query := `INSERT INTO public.budgets (user_id, year, month, total, food, groceries, transportation, entertainment, health, shopping, utilities, housing, travel, education, subscriptions, gifts, insurance, personal_care, other, unknown, period, start_date, end_date) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, NULLIF($23, '')::date)`
+	_, err = pool.Exec(context.Background(), query, userUUID, budgetRequest.Year, budgetRequest.Month, budgetRequest.Total, budgetRequest.Food, budgetRequest.Groceries, budgetRequest.Transportation, budgetRequest.Entertainment, budgetRequest.Health, budgetRequest.Shopping, budgetRequest.Utilities, budgetRequest.Housing, budgetRequest.Travel, budgetRequest.Education, budgetRequest.Subscriptions, budgetRequest.Gifts, budgetRequest.Insurance, budgetRequest.PersonalCare, budgetRequest.Other, budgetRequest.Unknown, budgetRequest.Period, budgetRequest.StartDate, budgetRequest.EndDate)
 	if err != nil {
 		return err
 	}
@@ -320,11 +389,12 @@ func DeleteBudget(budgetId string, pool *pgxpool.Pool) error {
 	return nil
 }
 
+// Expects the period to be filled in, an empty one would clear the budget's period
 func UpdateExistingBudget(budgetId string, updateBudgetRequest models.UpdateBudgetRequest, pool *pgxpool.Pool) error {
 	query := `UPDATE public.budgets 
-          SET year = $1, month = $2, total = $3, food = $4, groceries = $5, transportation = $6, entertainment = $7, health = $8, shopping = $9, utilities = $10, housing = $11, travel = $12, education = $13, subscriptions = $14, gifts = $15, insurance = $16, personal_care = $17, other = $18, unknown = $19
-          WHERE id = $20`
-	_, err := pool.Exec(context.Background(), query, updateBudgetRequest.Year, updateBudgetRequest.Month, updateBudgetRequest.Total, updateBudgetRequest.Food, updateBudgetRequest.Groceries, updateBudgetRequest.Transportation, updateBudgetRequest.Entertainment, updateBudgetRequest.Health, updateBudgetRequest.Shopping, updateBudgetRequest.Utilities, updateBudgetRequest.Housing, updateBudgetRequest.Travel, updateBudgetRequest.Education, updateBudgetRequest.Subscriptions, updateBudgetRequest.Gifts, updateBudgetRequest.Insurance, updateBudgetRequest.PersonalCare, updateBudgetRequest.Other, updateBudgetRequest.Unknown, budgetId)
+          SET year = $1, month = $2, total = $3, food = $4, groceries = $5, transportation = $6, entertainment = $7, health = $8, shopping = $9, utilities = $10, housing = $11, travel = $12, education = $13, subscriptions = $14, gifts = $15, insurance = $16, personal_care = $17, other = $18, unknown = $19, period = $20, start_date = $21, end_date = NULLIF($22, '')::date
+          WHERE id = $23`
+	_, err := pool.Exec(context.Background(), query, updateBudgetRequest.Year, updateBudgetRequest.Month, updateBudgetRequest.Total, updateBudgetRequest.Food, updateBudgetRequest.Groceries, updateBudgetRequest.Transportation, updateBudgetRequest.Entertainment, updateBudgetRequest.Health, updateBudgetRequest.Shopping, updateBudgetRequest.Utilities, updateBudgetRequest.Housing, updateBudgetRequest.Travel, updateBudgetRequest.Education, updateBudgetRequest.Subscriptions, updateBudgetRequest.Gifts, updateBudgetRequest.Insurance, updateBudgetRequest.PersonalCare, updateBudgetRequest.Other, updateBudgetRequest.Unknown, updateBudgetRequest.Period, updateBudgetRequest.StartDate, updateBudgetRequest.EndDate, budgetId)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns how the user reviewed each detected recurring series, keyed by series ID
+func FetchRecurringReviews(userId string, pool *pgxpool.Pool) (map[string]string, error) {
+	query := `SELECT series_id, review FROM public.recurring_series_reviews WHERE user_id = $1`
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recurring reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := map[string]string{}
+	for rows.Next() {
+		var seriesId, review string
+		if err := rows.Scan(&seriesId, &review); err != nil {
+			return nil, err
+		}
+		reviews[seriesId] = review
+	}
+	return reviews, rows.Err()
+}
+
+func UpsertRecurringReview(userId string, seriesId string, review string, pool *pgxpool.Pool) error {
+	query := `INSERT INTO public.recurring_series_reviews (user_id, series_id, review) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, series_id) DO UPDATE SET review = EXCLUDED.review, updated_at = now()`
+	_, err := pool.Exec(context.Background(), query, userId, seriesId, review)
+	return err
+}
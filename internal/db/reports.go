@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Group expressions of the spending report, keyed by the group_by values the API accepts
+var spendingGroups = map[string]string{
+	models.ReportGroupByCategory: `COALESCE(s.category, t.category, 'Unknown')`,
+	models.ReportGroupByMerchant: `COALESCE(m.name, NULLIF(t.payee, ''), 'Unknown')`,
+	models.ReportGroupByAccount:  `a.name`,
+	models.ReportGroupByTag:      `COALESCE(tg.name, 'untagged')`,
+}
+
+// Sums the user's spending between start and end (unix seconds, end exclusive) per interval
+// ("month" or "week", weeks start on Monday) and group. Split transactions count once per split
+// line and transfers don't count at all, like in the budget. Grouped by tag, a transaction with
+// several tags counts under each of them
+func FetchSpendingTotals(userId string, groupBy string, interval string, start int64, end int64, pool *pgxpool.Pool) ([]models.SpendingTotal, error) {
+	group, ok := spendingGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown spending group %q", groupBy)
+	}
+	if interval != models.ReportIntervalMonth && interval != models.ReportIntervalWeek {
+		return nil, fmt.Errorf("unknown spending interval %q", interval)
+	}
+
+	// the tag join repeats every line once per tag, so it is only joined when grouping by tag
+	tagJoin := ""
+	if groupBy == models.ReportGroupByTag {
+		tagJoin = `LEFT JOIN public.transaction_tags tt ON tt.transaction_id = t.id
+		LEFT JOIN public.tags tg ON tg.id = tt.tag_id`
+	}
+
+	query := fmt.Sprintf(`SELECT to_char(date_trunc('%s', to_timestamp(t.transacted_at) AT TIME ZONE 'UTC'), 'YYYY-MM-DD') AS period,
+			%s AS group_key,
+			SUM(-COALESCE(s.amount, t.amount::numeric))::float8,
+			COUNT(DISTINCT t.id)
+		FROM public.transactions t
+		JOIN public.accounts a ON a.id = t.account_id
+		LEFT JOIN public.transaction_splits s ON s.transaction_id = t.id
+		LEFT JOIN public.merchants m ON m.id = t.merchant_id
+		%s
+		WHERE a.user_id = $1 AND t.transacted_at >= $2 AND t.transacted_at < $3
+			AND COALESCE(s.amount, t.amount::numeric) < 0 AND t.transfer_group_id IS NULL
+		GROUP BY period, group_key
+		ORDER BY period, group_key`, interval, group, tagJoin)
+
+	rows, err := pool.Query(context.Background(), query, userId, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch spending totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []models.SpendingTotal{}
+	for rows.Next() {
+		var total models.SpendingTotal
+		if err := rows.Scan(&total.Period, &total.Key, &total.Total, &total.TransactionCount); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// Sums the user's income per source (merchant or payee) and expenses per category for each month
+// between start and end (unix seconds, end exclusive). Transfers are left out and split
+// transactions count per split line. Refunds reduce the expenses of their category
+func FetchCashFlowTotals(userId string, start int64, end int64, pool *pgxpool.Pool) ([]models.CashFlowTotal, error) {
+	query := `SELECT month, is_income, name, SUM(amount)::float8 FROM (
+			SELECT to_char(to_timestamp(t.transacted_at) AT TIME ZONE 'UTC', 'YYYY-MM') AS month,
+				COALESCE(s.category, t.category, '') = $4 AS is_income,
+				CASE WHEN COALESCE(s.category, t.category, '') = $4 THEN COALESCE(m.name, NULLIF(t.payee, ''), 'Unknown')
+					ELSE COALESCE(s.category, t.category, 'Unknown') END AS name,
+				CASE WHEN COALESCE(s.category, t.category, '') = $4 THEN COALESCE(s.amount, t.amount::numeric)
+					ELSE -COALESCE(s.amount, t.amount::numeric) END AS amount
+			FROM public.transactions t
+			JOIN public.accounts a ON a.id = t.account_id
+			LEFT JOIN public.transaction_splits s ON s.transaction_id = t.id
+			LEFT JOIN public.merchants m ON m.id = t.merchant_id
+			WHERE a.user_id = $1 AND t.transacted_at >= $2 AND t.transacted_at < $3 AND t.transfer_group_id IS NULL
+		) lines
+		GROUP BY month, is_income, name
+		ORDER BY month, is_income, name`
+	rows, err := pool.Query(context.Background(), query, userId, start, end, models.IncomeCategory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cash flow totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []models.CashFlowTotal{}
+	for rows.Next() {
+		var total models.CashFlowTotal
+		if err := rows.Scan(&total.Month, &total.IsIncome, &total.Name, &total.Amount); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
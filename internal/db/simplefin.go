@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrSimpleFINNotConnected = errors.New("no SimpleFIN connection")
+
+func FetchSimpleFINAccessURL(userId string, pool *pgxpool.Pool) (string, error) {
+	var accessURL string
+	err := pool.QueryRow(context.Background(), `SELECT access_url FROM public.simplefin_connections WHERE user_id = $1`, userId).Scan(&accessURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrSimpleFINNotConnected
+	} else if err != nil {
+		return "", fmt.Errorf("failed to fetch SimpleFIN connection: %w", err)
+	}
+	return accessURL, nil
+}
+
+// Stores the user's access URL, replacing the one of an earlier connection
+func SaveSimpleFINConnection(userId string, accessURL string, pool *pgxpool.Pool) error {
+	query := `INSERT INTO public.simplefin_connections (user_id, access_url) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET access_url = EXCLUDED.access_url, created_at = now()`
+	if _, err := pool.Exec(context.Background(), query, userId, accessURL); err != nil {
+		return fmt.Errorf("failed to save SimpleFIN connection: %w", err)
+	}
+	return nil
+}
+
+// Forgets the user's access URL, after which the server can't read their bank connections anymore
+func DeleteSimpleFINConnection(userId string, pool *pgxpool.Pool) error {
+	tag, err := pool.Exec(context.Background(), `DELETE FROM public.simplefin_connections WHERE user_id = $1`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete SimpleFIN connection: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSimpleFINNotConnected
+	}
+	return nil
+}
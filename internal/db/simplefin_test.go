@@ -0,0 +1,30 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSimpleFINConnectionRoundTrip(t *testing.T) {
+	pool := testPool(t)
+	userId := testUser(t, pool)
+
+	if _, err := FetchSimpleFINAccessURL(userId, pool); !errors.Is(err, ErrSimpleFINNotConnected) {
+		t.Fatalf("Expected no connection yet, got %v", err)
+	}
+	for _, accessURL := range []string{"https://a:1@bridge.simplefin.org/simplefin", "https://b:2@bridge.simplefin.org/simplefin"} {
+		if err := SaveSimpleFINConnection(userId, accessURL, pool); err != nil {
+			t.Fatalf("Failed to save connection: %v", err)
+		}
+	}
+	if accessURL, err := FetchSimpleFINAccessURL(userId, pool); err != nil || accessURL != "https://b:2@bridge.simplefin.org/simplefin" {
+		t.Errorf("Expected the latest connection to replace the earlier one, got %q (%v)", accessURL, err)
+	}
+
+	if err := DeleteSimpleFINConnection(userId, pool); err != nil {
+		t.Fatalf("Failed to delete connection: %v", err)
+	}
+	if err := DeleteSimpleFINConnection(userId, pool); !errors.Is(err, ErrSimpleFINNotConnected) {
+		t.Errorf("Expected nothing left to delete, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns the split lines of the given transactions, keyed by transaction ID
+func FetchTransactionSplits(transactionIds []string, pool *pgxpool.Pool) (map[string][]models.TransactionSplit, error) {
+	query := `SELECT id::text, transaction_id, amount::text, category, memo FROM public.transaction_splits
+		WHERE transaction_id = ANY($1) ORDER BY transaction_id, position`
+	rows, err := pool.Query(context.Background(), query, transactionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction splits: %w", err)
+	}
+	defer rows.Close()
+
+	splits := map[string][]models.TransactionSplit{}
+	for rows.Next() {
+		var split models.TransactionSplit
+		if err := rows.Scan(&split.ID, &split.TransactionId, &split.Amount, &split.Category, &split.Memo); err != nil {
+			return nil, err
+		}
+		splits[split.TransactionId] = append(splits[split.TransactionId], split)
+	}
+	return splits, rows.Err()
+}
+
+// Replaces all split lines of a transaction. An empty list removes the split
+func ReplaceTransactionSplits(transactionId string, splits []models.TransactionSplit, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM public.transaction_splits WHERE transaction_id = $1`, transactionId); err != nil {
+		return fmt.Errorf("failed to clear transaction splits: %w", err)
+	}
+	for i, split := range splits {
+		query := `INSERT INTO public.transaction_splits (transaction_id, position, amount, category, memo) VALUES ($1, $2, $3::numeric, $4, $5)`
+		if _, err := tx.Exec(ctx, query, transactionId, i, split.Amount, split.Category, split.Memo); err != nil {
+			return fmt.Errorf("failed to insert transaction split: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
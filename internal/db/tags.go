@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// Fills in the splits, tags and custom fields of the transactions
+func attachTransactionDetails(txns []models.Transaction, pool *pgxpool.Pool) error {
+	if len(txns) == 0 {
+		return nil
+	}
+	ids := make([]string, len(txns))
+	for i, txn := range txns {
+		ids[i] = txn.ID
+	}
+	splits, err := FetchTransactionSplits(ids, pool)
+	if err != nil {
+		return err
+	}
+	tags, err := fetchTransactionTags(ids, pool)
+	if err != nil {
+		return err
+	}
+	customFields, err := fetchTransactionCustomFields(ids, pool)
+	if err != nil {
+		return err
+	}
+	for i := range txns {
+		txns[i].Splits = splits[txns[i].ID]
+		txns[i].Tags = tags[txns[i].ID]
+		txns[i].CustomFields = customFields[txns[i].ID]
+	}
+	return nil
+}
+
+func fetchTransactionTags(transactionIds []string, pool *pgxpool.Pool) (map[string][]string, error) {
+	query := `SELECT tt.transaction_id, t.name FROM public.transaction_tags tt
+		JOIN public.tags t ON t.id = tt.tag_id
+		WHERE tt.transaction_id = ANY($1) ORDER BY t.name`
+	rows, err := pool.Query(context.Background(), query, transactionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := map[string][]string{}
+	for rows.Next() {
+		var transactionId, name string
+		if err := rows.Scan(&transactionId, &name); err != nil {
+			return nil, err
+		}
+		tags[transactionId] = append(tags[transactionId], name)
+	}
+	return tags, rows.Err()
+}
+
+func fetchTransactionCustomFields(transactionIds []string, pool *pgxpool.Pool) (map[string]map[string]string, error) {
+	query := `SELECT transaction_id, key, value FROM public.transaction_custom_fields WHERE transaction_id = ANY($1)`
+	rows, err := pool.Query(context.Background(), query, transactionIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := map[string]map[string]string{}
+	for rows.Next() {
+		var transactionId, key, value string
+		if err := rows.Scan(&transactionId, &key, &value); err != nil {
+			return nil, err
+		}
+		if fields[transactionId] == nil {
+			fields[transactionId] = map[string]string{}
+		}
+		fields[transactionId][key] = value
+	}
+	return fields, rows.Err()
+}
+
+// Applies a batch of transaction changes in one database transaction, so either all of them are
+// saved or none are. Transactions that don't belong to the user fail the whole batch
+func UpdateTransactions(userId string, updates []models.TransactionCategoryRequest, pool *pgxpool.Pool) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, update := range updates {
+		var owned bool
+		query := `SELECT EXISTS(SELECT 1 FROM public.transactions
+			WHERE id = $1 AND account_id IN (SELECT id FROM public.accounts WHERE user_id = $2))`
+		if err := tx.QueryRow(ctx, query, update.ID, userId).Scan(&owned); err != nil {
+			return fmt.Errorf("failed to check transaction %s: %w", update.ID, err)
+		}
+		if !owned {
+			return fmt.Errorf("%w: %s", ErrTransactionNotFound, update.ID)
+		}
+
+		if update.Category != "" {
+			if _, err := tx.Exec(ctx, `UPDATE public.transactions SET category = $1 WHERE id = $2`, update.Category, update.ID); err != nil {
+				return fmt.Errorf("failed to update category of transaction %s: %w", update.ID, err)
+			}
+		}
+		if update.Notes != nil {
+			if _, err := tx.Exec(ctx, `UPDATE public.transactions SET notes = NULLIF($1, '') WHERE id = $2`, *update.Notes, update.ID); err != nil {
+				return fmt.Errorf("failed to update notes of transaction %s: %w", update.ID, err)
+			}
+		}
+		if err := updateTransactionTags(ctx, tx, userId, update); err != nil {
+			return err
+		}
+		for key, value := range update.CustomFields {
+			if value == nil {
+				_, err = tx.Exec(ctx, `DELETE FROM public.transaction_custom_fields WHERE transaction_id = $1 AND key = $2`, update.ID, key)
+			} else {
+				_, err = tx.Exec(ctx, `INSERT INTO public.transaction_custom_fields (transaction_id, key, value) VALUES ($1, $2, $3)
+					ON CONFLICT (transaction_id, key) DO UPDATE SET value = EXCLUDED.value`, update.ID, key, *value)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update custom field %s of transaction %s: %w", key, update.ID, err)
+			}
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func updateTransactionTags(ctx context.Context, tx pgx.Tx, userId string, update models.TransactionCategoryRequest) error {
+	addTags := update.AddTags
+	if update.Tags != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM public.transaction_tags WHERE transaction_id = $1`, update.ID); err != nil {
+			return fmt.Errorf("failed to clear tags of transaction %s: %w", update.ID, err)
+		}
+		addTags = append(append([]string(nil), *update.Tags...), addTags...)
+	}
+
+	for _, name := range addTags {
+		var tagId string
+		query := `INSERT INTO public.tags (user_id, name) VALUES ($1, $2)
+			ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name RETURNING id::text`
+		if err := tx.QueryRow(ctx, query, userId, name).Scan(&tagId); err != nil {
+			return fmt.Errorf("failed to save tag %s: %w", name, err)
+		}
+		query = `INSERT INTO public.transaction_tags (transaction_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(ctx, query, update.ID, tagId); err != nil {
+			return fmt.Errorf("failed to tag transaction %s: %w", update.ID, err)
+		}
+	}
+
+	if len(update.RemoveTags) > 0 {
+		query := `DELETE FROM public.transaction_tags WHERE transaction_id = $1
+			AND tag_id IN (SELECT id FROM public.tags WHERE user_id = $2 AND name = ANY($3))`
+		if _, err := tx.Exec(ctx, query, update.ID, userId, update.RemoveTags); err != nil {
+			return fmt.Errorf("failed to remove tags of transaction %s: %w", update.ID, err)
+		}
+	}
+	return nil
+}
+
+// Returns every tag of the user with how many transactions carry it and how much was spent on
+// them between the timestamps. Transfers don't count as spending
+func FetchTagSummaries(userId string, start int64, end int64, pool *pgxpool.Pool) ([]models.TagSummary, error) {
+	query := `SELECT tg.name, COUNT(t.id),
+			COALESCE(SUM(CASE WHEN t.amount::numeric < 0 AND t.transfer_group_id IS NULL THEN -t.amount::numeric END), 0)::float8
+		FROM public.tags tg
+		LEFT JOIN public.transaction_tags tt ON tt.tag_id = tg.id
+		LEFT JOIN public.transactions t ON t.id = tt.transaction_id AND t.transacted_at >= $2 AND t.transacted_at < $3
+		WHERE tg.user_id = $1
+		GROUP BY tg.name ORDER BY tg.name`
+	rows, err := pool.Query(context.Background(), query, userId, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []models.TagSummary{}
+	for rows.Next() {
+		var tag models.TagSummary
+		if err := rows.Scan(&tag.Name, &tag.TransactionCount, &tag.Spent); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
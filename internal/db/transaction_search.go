@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Minimum pg_trgm word similarity for a fuzzy search match. The extension's default of 0.6 misses
+// single typos in short payees ("starbcks" scores 0.58 against "starbucks")
+const WordSimilarityThreshold = 0.5
+
+// Sort columns and directions of the transaction list, all with the ID as tiebreaker so the
+// order is stable for keyset pagination. Relevance sorts by the search rank instead
+var transactionSorts = map[string]struct {
+	column    string
+	cast      string
+	direction string
+}{
+	models.TransactionSortDateDesc:   {"t.transacted_at", "bigint", "DESC"},
+	models.TransactionSortDateAsc:    {"t.transacted_at", "bigint", "ASC"},
+	models.TransactionSortAmountAsc:  {"t.amount::numeric", "numeric", "ASC"},
+	models.TransactionSortAmountDesc: {"t.amount::numeric", "numeric", "DESC"},
+	models.TransactionSortRelevance:  {"", "float8", "DESC"},
+}
+
+// Builds the WHERE conditions of a transaction query and the expression ranking how well each
+// transaction matches the search: 1 for a full text match on all words, plus the best trigram
+// word similarity of the search to the payee or description, which is what catches typos
+func transactionFilters(userId string, q models.TransactionQuery) ([]string, []any, string) {
+	args := []any{userId}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"t.account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)"}
+	if q.From != 0 {
+		conditions = append(conditions, "t.transacted_at >= "+arg(q.From))
+	}
+	if q.To != 0 {
+		conditions = append(conditions, "t.transacted_at < "+arg(q.To))
+	}
+	if len(q.AccountIds) > 0 {
+		conditions = append(conditions, "t.account_id = ANY("+arg(q.AccountIds)+")")
+	}
+	if len(q.Categories) > 0 {
+		// split transactions match on any of their lines
+		categories := arg(q.Categories)
+		conditions = append(conditions, fmt.Sprintf(`(t.category = ANY(%[1]s) OR EXISTS (SELECT 1 FROM public.transaction_splits s
+			WHERE s.transaction_id = t.id AND s.category = ANY(%[1]s)))`, categories))
+	}
+	if len(q.Tags) > 0 {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM public.transaction_tags tt JOIN public.tags tg ON tg.id = tt.tag_id
+			WHERE tt.transaction_id = t.id AND tg.name = ANY(`+arg(q.Tags)+`))`)
+	}
+	if q.MinAmount != nil {
+		conditions = append(conditions, "t.amount::numeric >= "+arg(*q.MinAmount))
+	}
+	if q.MaxAmount != nil {
+		conditions = append(conditions, "t.amount::numeric <= "+arg(*q.MaxAmount))
+	}
+	rank := "0::float8"
+	if q.Search != "" {
+		search := arg(q.Search)
+		conditions = append(conditions, fmt.Sprintf("(t.search_vector @@ plainto_tsquery('simple', %[1]s) OR %[1]s <%% t.payee OR %[1]s <%% t.description)", search))
+		rank = fmt.Sprintf(`((CASE WHEN t.search_vector @@ plainto_tsquery('simple', %[1]s) THEN 1 ELSE 0 END)
+			+ GREATEST(word_similarity(%[1]s, t.payee), word_similarity(%[1]s, t.description)))::float8`, search)
+	}
+	return conditions, args, rank
+}
+
+// Returns one page of the user's transactions matching the query plus the total number of matches.
+// One extra transaction past the limit is returned when there is a next page
+func SearchTransactions(userId string, q models.TransactionQuery, pool *pgxpool.Pool) ([]models.RankedTransaction, int, error) {
+	conditions, args, rank := transactionFilters(userId, q)
+
+	// the <% operator reads its threshold from the session, SET LOCAL keeps it to this transaction
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL pg_trgm.word_similarity_threshold = %g", WordSimilarityThreshold)); err != nil {
+		return nil, 0, fmt.Errorf("failed to set similarity threshold: %w", err)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM public.transactions t WHERE ` + strings.Join(conditions, " AND ")
+	if err := tx.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	sort, ok := transactionSorts[q.Sort]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	if sort.column == "" {
+		sort.column = rank
+	}
+	if q.After != nil {
+		comparison := "<"
+		if sort.direction == "ASC" {
+			comparison = ">"
+		}
+		args = append(args, q.After.Value, q.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, t.id) %s ($%d::%s, $%d)", sort.column, comparison, len(args)-1, sort.cast, len(args)))
+	}
+	args = append(args, q.Limit+1)
+
+	query := fmt.Sprintf(`SELECT %s, %s FROM public.transactions t WHERE %s ORDER BY %s %s, t.id %s LIMIT $%d`,
+		transactionColumns, rank, strings.Join(conditions, " AND "), sort.column, sort.direction, sort.direction, len(args))
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	ranks := []float64{}
+	for rows.Next() {
+		var txn models.Transaction
+		var rank float64
+		if err := rows.Scan(append(transactionScanTargets(&txn), &rank)...); err != nil {
+			return nil, 0, err
+		}
+		transactions = append(transactions, txn)
+		ranks = append(ranks, rank)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := attachTransactionDetails(transactions, pool); err != nil {
+		return nil, 0, err
+	}
+
+	ranked := make([]models.RankedTransaction, len(transactions))
+	for i, txn := range transactions {
+		ranked[i] = models.RankedTransaction{Transaction: txn, Rank: ranks[i]}
+	}
+	return ranked, total, nil
+}
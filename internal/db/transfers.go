@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns the user's transactions since the timestamp that aren't part of a transfer yet and that
+// the user hasn't unlinked from one (those are left for the user to link by hand)
+func FetchTransferCandidates(userId string, since int64, pool *pgxpool.Pool) ([]models.Transaction, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.transactions
+		WHERE account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)
+			AND transacted_at >= $2 AND transfer_group_id IS NULL AND NOT transfer_locked`, transactionColumns)
+	rows, err := pool.Query(context.Background(), query, userId, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transfer candidates: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, rows.Err()
+}
+
+// Fetches transactions by ID, limited to the ones belonging to the user's accounts
+func FetchUserTransactionsByIds(userId string, ids []string, pool *pgxpool.Pool) ([]models.Transaction, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.transactions
+		WHERE id = ANY($2) AND account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)`, transactionColumns)
+	rows, err := pool.Query(context.Background(), query, userId, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, rows.Err()
+}
+
+// Links the transactions into a new transfer group and returns its ID
+func LinkTransfer(transactionIds []string, pool *pgxpool.Pool) (string, error) {
+	var groupId string
+	query := `WITH g AS (SELECT gen_random_uuid() AS id)
+		UPDATE public.transactions SET transfer_group_id = g.id, transfer_locked = false FROM g
+		WHERE public.transactions.id = ANY($1) RETURNING g.id::text`
+	err := pool.QueryRow(context.Background(), query, transactionIds).Scan(&groupId)
+	return groupId, err
+}
+
+// Breaks up a transfer group. Its transactions are locked so the matcher doesn't pair them again
+func UnlinkTransfer(userId string, groupId string, pool *pgxpool.Pool) error {
+	query := `UPDATE public.transactions SET transfer_group_id = NULL, transfer_locked = true
+		WHERE transfer_group_id = $2 AND account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)`
+	_, err := pool.Exec(context.Background(), query, userId, groupId)
+	return err
+}
+
+func FetchTransferGroups(userId string, pool *pgxpool.Pool) ([]models.TransferGroup, error) {
+	query := fmt.Sprintf(`SELECT %s FROM public.transactions
+		WHERE transfer_group_id IS NOT NULL AND account_id IN (SELECT id FROM public.accounts WHERE user_id = $1)
+		ORDER BY transacted_at DESC`, transactionColumns)
+	rows, err := pool.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transfers: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.TransferGroup{}
+	index := map[string]int{}
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[txn.TransferGroupId]
+		if !ok {
+			i = len(groups)
+			index[txn.TransferGroupId] = i
+			groups = append(groups, models.TransferGroup{ID: txn.TransferGroupId})
+		}
+		groups[i].Transactions = append(groups[i].Transactions, txn)
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// One row per transaction, or per line of a split transaction so the amounts still add up and
+// each row has a single category
+type csvWriter struct {
+	w    *csv.Writer
+	opts Options
+}
+
+func newCSVWriter(w io.Writer, opts Options) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	header := []string{"Date", "Account", "Payee", "Description", "Memo", "Amount", "Currency", "Category", "Tags", "Notes", "Transaction ID", "Split"}
+	return &csvWriter{w: writer, opts: opts}, writer.Write(header)
+}
+
+func (c *csvWriter) Write(txn models.Transaction) error {
+	account := c.opts.account(txn.AccountID)
+	row := func(amount string, category string, memo string, split string) []string {
+		return []string{transactionDate(txn).Format(time.DateOnly), account.Name, txn.Payee, txn.Description, memo, amount, account.Currency,
+			category, strings.Join(txn.Tags, ","), txn.Notes, txn.ID, split}
+	}
+	if len(txn.Splits) == 0 {
+		return c.w.Write(row(txn.Amount, txn.Category, txn.Memo, ""))
+	}
+	for i, split := range txn.Splits {
+		memo := split.Memo
+		if memo == "" {
+			memo = txn.Memo
+		}
+		if err := c.w.Write(row(split.Amount, split.Category, memo, fmt.Sprintf("%d/%d", i+1, len(txn.Splits)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,124 @@
+// Package exporter writes transactions in formats other tools read (CSV, OFX, JSON, Ledger and
+// Beancount), one transaction at a time so exports of any size can be streamed
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// An account transactions are exported from
+type Account struct {
+	ID          string
+	Name        string
+	Org         string
+	AccountType string
+	Currency    string
+	// Owed rather than owned, e.g. a credit card or loan
+	Liability   bool
+	Balance     string
+	BalanceDate int64
+}
+
+type Options struct {
+	Accounts []Account
+	// Range of the export, To is exclusive. Zero when unbounded
+	From time.Time
+	To   time.Time
+	Now  time.Time
+}
+
+func (o Options) account(accountId string) Account {
+	for _, account := range o.Accounts {
+		if account.ID == accountId {
+			if account.Currency == "" {
+				account.Currency = "USD"
+			}
+			return account
+		}
+	}
+	return Account{ID: accountId, Name: accountId, Currency: "USD"}
+}
+
+type Writer interface {
+	Write(txn models.Transaction) error
+	// Writes whatever follows the last transaction
+	Close() error
+}
+
+// Whether transactions have to come grouped by account rather than in date order
+func GroupsByAccount(format string) bool {
+	return format == models.ExportFormatOFX
+}
+
+func ContentType(format string) string {
+	switch format {
+	case models.ExportFormatCSV:
+		return "text/csv"
+	case models.ExportFormatOFX:
+		return "application/x-ofx"
+	case models.ExportFormatJSON:
+		return "application/json"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func NewWriter(format string, w io.Writer, opts Options) (Writer, error) {
+	switch format {
+	case models.ExportFormatCSV:
+		return newCSVWriter(w, opts)
+	case models.ExportFormatOFX:
+		return newOFXWriter(w, opts)
+	case models.ExportFormatJSON:
+		return newJSONWriter(w)
+	case models.ExportFormatLedger:
+		return newLedgerWriter(w, opts, ledgerDialect)
+	case models.ExportFormatBeancount:
+		return newLedgerWriter(w, opts, beancountDialect)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+func transactionDate(txn models.Transaction) time.Time {
+	if txn.TransactedAt != 0 {
+		return time.Unix(txn.TransactedAt, 0).UTC()
+	}
+	return time.Unix(txn.Posted, 0).UTC()
+}
+
+// Writes a JSON array one element at a time
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONWriter(w io.Writer) (*jsonWriter, error) {
+	_, err := io.WriteString(w, "[")
+	return &jsonWriter{w: w}, err
+}
+
+func (j *jsonWriter) Write(txn models.Transaction) error {
+	data, err := json.Marshal(txn)
+	if err != nil {
+		return err
+	}
+	separator := "\n"
+	if j.count > 0 {
+		separator = ",\n"
+	}
+	j.count++
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
@@ -0,0 +1,150 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/importer"
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+var testOptions = Options{
+	Accounts: []Account{
+		{ID: "chk", Name: "Everyday Checking", Org: "Chase", AccountType: "checking", Balance: "1500.00", BalanceDate: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC).Unix()},
+		{ID: "card", Name: "Sapphire Card", Org: "Chase", Liability: true, Balance: "-96.50", BalanceDate: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC).Unix()},
+	},
+	Now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+}
+
+func testTransactions() []models.Transaction {
+	on := func(day string) int64 {
+		d, _ := time.Parse(time.DateOnly, day)
+		return d.Unix()
+	}
+	return []models.Transaction{
+		{ID: "TRN-1", AccountID: "card", Amount: "-6.50", Payee: "Blue Bottle", Description: "SQ *BLUE BOTTLE", Category: "Food & Dining", TransactedAt: on("2026-10-01"), Tags: []string{"work-trip"}, Notes: "client meeting"},
+		{ID: "TRN-2", AccountID: "chk", Amount: "2100.00", Payee: "Acme Payroll", Category: "Income", TransactedAt: on("2026-10-02")},
+		{ID: "TRN-3", AccountID: "card", Amount: "-90.00", Payee: "Costco", Category: "Groceries", TransactedAt: on("2026-10-03"), Splits: []models.TransactionSplit{
+			{Amount: "-60.00", Category: "Groceries"}, {Amount: "-30.00", Category: "Shopping", Memo: "towels"},
+		}},
+	}
+}
+
+func export(t *testing.T, format string, txns []models.Transaction) string {
+	var out bytes.Buffer
+	writer, err := NewWriter(format, &out, testOptions)
+	if err != nil {
+		t.Fatalf("Failed to create %s writer: %v", format, err)
+	}
+	for _, txn := range txns {
+		if err := writer.Write(txn); err != nil {
+			t.Fatalf("Failed to write %s: %v", format, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close %s writer: %v", format, err)
+	}
+	return out.String()
+}
+
+func TestExportCSV(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(export(t, models.ExportFormatCSV, testTransactions()))).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read csv export: %v", err)
+	}
+	// header, two plain transactions and the two lines of the split one
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 rows, got %v", records)
+	}
+	if coffee := records[1]; coffee[1] != "Sapphire Card" || coffee[5] != "-6.50" || coffee[8] != "work-trip" || coffee[9] != "client meeting" {
+		t.Errorf("Unexpected coffee row: %v", coffee)
+	}
+	if towels := records[4]; towels[4] != "towels" || towels[5] != "-30.00" || towels[7] != "Shopping" || towels[11] != "2/2" {
+		t.Errorf("Unexpected split row: %v", towels)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	var txns []models.Transaction
+	if err := json.Unmarshal([]byte(export(t, models.ExportFormatJSON, testTransactions())), &txns); err != nil {
+		t.Fatalf("Failed to read json export: %v", err)
+	}
+	if len(txns) != 3 || len(txns[2].Splits) != 2 || txns[0].Tags[0] != "work-trip" {
+		t.Errorf("Unexpected json export: %+v", txns)
+	}
+	if out := export(t, models.ExportFormatJSON, nil); strings.TrimSpace(out) != "[\n]" {
+		t.Errorf("Expected an empty array, got %q", out)
+	}
+}
+
+func TestExportOFX(t *testing.T) {
+	txns := testTransactions()
+	// grouped by account, the way the export query orders them for ofx
+	grouped := []models.Transaction{txns[0], txns[2], txns[1]}
+	out := export(t, models.ExportFormatOFX, grouped)
+	if strings.Count(out, "<STMTRS>") != 2 || !strings.Contains(out, "<ACCTTYPE>CREDITLINE</ACCTTYPE>") {
+		t.Errorf("Expected a statement per account, got %s", out)
+	}
+
+	// our own importer reads it back
+	result, err := importer.ParseOFX([]byte(out))
+	if err != nil || len(result.Transactions) != 3 || len(result.Errors) != 0 {
+		t.Fatalf("Failed to read the export back: %+v (%v)", result, err)
+	}
+	if coffee := result.Transactions[0]; coffee.ID != "TRN-1" || coffee.Amount != "-6.50" || coffee.Payee != "Blue Bottle" || coffee.TransactedAt != txns[0].TransactedAt {
+		t.Errorf("Unexpected transaction read back: %+v", coffee)
+	}
+}
+
+// Postings are padded into columns, the tests only care that the account and amount are apart
+var columnPadding = regexp.MustCompile(` {2,}`)
+
+func TestExportLedger(t *testing.T) {
+	out := columnPadding.ReplaceAllString(export(t, models.ExportFormatLedger, testTransactions()), "  ")
+	for _, expected := range []string{
+		"2026/10/01 * Blue Bottle\n  ; id: TRN-1\n  ; SQ *BLUE BOTTLE\n  ; :work-trip:\n  ; client meeting\n",
+		"  Liabilities:Chase:Sapphire Card  -6.50 USD\n  Expenses:Food & Dining  6.50 USD\n",
+		"  Income:General  -2100.00 USD\n",
+		"  Expenses:Shopping  30.00 USD\n",
+		"2026/10/18 * Balance reconciliation\n  Assets:Chase:Everyday Checking  = 1500.00 USD\n  Equity:Opening Balances\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected the ledger export to contain %q, got\n%s", expected, out)
+		}
+	}
+}
+
+func TestExportBeancount(t *testing.T) {
+	out := columnPadding.ReplaceAllString(export(t, models.ExportFormatBeancount, testTransactions()), "  ")
+	for _, expected := range []string{
+		"1970-01-01 open Equity:Opening-Balances\n",
+		"2026-10-01 open Liabilities:Chase:Sapphire-Card\n2026-10-01 pad Liabilities:Chase:Sapphire-Card Equity:Opening-Balances\n",
+		"2026-10-01 open Expenses:Food-Dining\n",
+		`2026-10-01 * "Blue Bottle" "SQ *BLUE BOTTLE" #work-trip` + "\n  id: \"TRN-1\"\n  notes: \"client meeting\"\n",
+		"  Liabilities:Chase:Sapphire-Card  -90.00 USD\n  Expenses:Groceries  60.00 USD\n  Expenses:Shopping  30.00 USD\n",
+		"2026-10-19 balance Liabilities:Chase:Sapphire-Card  -96.50 USD\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected the beancount export to contain %q, got\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, "open Expenses:Groceries") != 1 {
+		t.Errorf("Expected each account to be opened once, got\n%s", out)
+	}
+
+	// an export that stops before the balance date can't end on the balance
+	options := testOptions
+	options.To = time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	writer, _ := NewWriter(models.ExportFormatBeancount, &buf, options)
+	writer.Write(testTransactions()[0])
+	writer.Close()
+	if strings.Contains(buf.String(), " pad ") || strings.Contains(buf.String(), " balance ") {
+		t.Errorf("Expected no balance assertion for a partial export, got\n%s", buf.String())
+	}
+}
@@ -0,0 +1,251 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// Ledger and Beancount are close enough to share a writer; the dialect covers where they differ
+type dialect int
+
+const (
+	ledgerDialect dialect = iota
+	beancountDialect
+)
+
+// Chart of accounts both formats post to. The user's accounts sit under Assets or Liabilities by
+// institution, categories under Expenses, and both legs of a transfer between the user's own
+// accounts go through the transfers clearing account so they net out
+const (
+	incomeCategory   = "Income"
+	openingBalances  = "Equity:Opening Balances"
+	transfersAccount = "Equity:Transfers"
+)
+
+// Double-entry output: each transaction posts its amount to the account it's in and the opposite
+// to its category (one posting per split line). When the export runs up to an account's balance
+// date, the account ends with its balance, so the books reconcile against the bank's: Ledger gets
+// an opening balance adjustment through a balance assignment, Beancount a pad and balance assertion
+type ledgerWriter struct {
+	w       io.Writer
+	opts    Options
+	dialect dialect
+	// accounts opened so far (Beancount needs an open directive before use), and the date of the
+	// latest transaction of each of the user's accounts
+	opened   map[string]bool
+	lastDate map[string]time.Time
+	order    []string
+}
+
+func newLedgerWriter(w io.Writer, opts Options, d dialect) (*ledgerWriter, error) {
+	l := &ledgerWriter{w: w, opts: opts, dialect: d, opened: map[string]bool{}, lastDate: map[string]time.Time{}}
+	var err error
+	if d == beancountDialect {
+		_, err = fmt.Fprintf(w, "; Transactions exported %s\n\n1970-01-01 open %s\n1970-01-01 open %s\n\n",
+			opts.Now.Format(time.DateOnly), l.accountName(openingBalances), l.accountName(transfersAccount))
+	} else {
+		_, err = fmt.Fprintf(w, "; Transactions exported %s\n\n", opts.Now.Format(time.DateOnly))
+	}
+	return l, err
+}
+
+// Beancount account components start with a capital letter or digit and hold only letters,
+// digits and dashes. Ledger takes nearly anything, but a colon starts a sub account and two
+// spaces end the name
+func (l *ledgerWriter) component(name string) string {
+	if l.dialect == ledgerDialect {
+		name = strings.NewReplacer(":", "-", ";", "-").Replace(name)
+		return strings.Join(strings.Fields(name), " ")
+	}
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	if len(words) == 0 {
+		return "Unknown"
+	}
+	return strings.Join(words, "-")
+}
+
+// Beancount tags take letters, digits and - _ / .
+func beancountTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_/.", r) {
+			return r
+		}
+		return '-'
+	}, tag)
+}
+
+func (l *ledgerWriter) accountName(path string) string {
+	components := strings.Split(path, ":")
+	for i, component := range components {
+		components[i] = l.component(component)
+	}
+	return strings.Join(components, ":")
+}
+
+func (l *ledgerWriter) userAccount(account Account) string {
+	components := []string{"Assets"}
+	if account.Liability {
+		components[0] = "Liabilities"
+	}
+	if account.Org != "" {
+		components = append(components, l.component(account.Org))
+	}
+	return strings.Join(append(components, l.component(account.Name)), ":")
+}
+
+func (l *ledgerWriter) categoryAccount(txn models.Transaction, category string) string {
+	switch {
+	case txn.TransferGroupId != "":
+		return l.accountName(transfersAccount)
+	case category == incomeCategory:
+		return l.accountName("Income:General")
+	case category == "":
+		category = "Unknown"
+	}
+	return "Expenses:" + l.component(category)
+}
+
+// Whether the export covers the account up to its balance date, so it can end on its balance
+func (l *ledgerWriter) reconciles(account Account) bool {
+	return account.Balance != "" && account.BalanceDate != 0 && (l.opts.To.IsZero() || l.opts.To.Unix() > account.BalanceDate)
+}
+
+func (l *ledgerWriter) quote(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if l.dialect == ledgerDialect {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func negate(amount string) string {
+	amount = strings.TrimSpace(amount)
+	if strings.HasPrefix(amount, "-") {
+		return amount[1:]
+	}
+	return "-" + amount
+}
+
+func (l *ledgerWriter) open(name string, date time.Time, account *Account) error {
+	if l.dialect != beancountDialect || l.opened[name] {
+		return nil
+	}
+	l.opened[name] = true
+	if _, err := fmt.Fprintf(l.w, "%s open %s\n", date.Format(time.DateOnly), name); err != nil {
+		return err
+	}
+	if account != nil && l.reconciles(*account) {
+		// fills the gap between zero and the balance before the first exported transaction
+		_, err := fmt.Fprintf(l.w, "%s pad %s %s\n", date.Format(time.DateOnly), name, l.accountName(openingBalances))
+		return err
+	}
+	return nil
+}
+
+func (l *ledgerWriter) posting(account string, amount string, currency string) string {
+	indent := "    "
+	if l.dialect == beancountDialect {
+		indent = "  "
+	}
+	if amount == "" {
+		return indent + account + "\n"
+	}
+	return fmt.Sprintf("%s%-50s  %12s %s\n", indent, account, amount, currency)
+}
+
+func (l *ledgerWriter) Write(txn models.Transaction) error {
+	account := l.opts.account(txn.AccountID)
+	date := transactionDate(txn)
+	name := l.userAccount(account)
+	if _, ok := l.lastDate[txn.AccountID]; !ok {
+		l.order = append(l.order, txn.AccountID)
+	}
+	l.lastDate[txn.AccountID] = date
+	if err := l.open(name, date, &account); err != nil {
+		return err
+	}
+
+	type posting struct{ account, amount string }
+	postings := []posting{{name, txn.Amount}}
+	if len(txn.Splits) == 0 {
+		postings = append(postings, posting{l.categoryAccount(txn, txn.Category), negate(txn.Amount)})
+	}
+	for _, split := range txn.Splits {
+		postings = append(postings, posting{l.categoryAccount(txn, split.Category), negate(split.Amount)})
+	}
+	for _, p := range postings[1:] {
+		if err := l.open(p.account, date, nil); err != nil {
+			return err
+		}
+	}
+
+	payee := txn.Payee
+	if payee == "" {
+		payee = txn.Description
+	}
+	var b strings.Builder
+	if l.dialect == beancountDialect {
+		fmt.Fprintf(&b, "%s * %s %s", date.Format(time.DateOnly), l.quote(payee), l.quote(txn.Description))
+		for _, tag := range txn.Tags {
+			b.WriteString(" #" + beancountTag(tag))
+		}
+		fmt.Fprintf(&b, "\n  id: %s\n", l.quote(txn.ID))
+		if txn.Notes != "" {
+			fmt.Fprintf(&b, "  notes: %s\n", l.quote(txn.Notes))
+		}
+	} else {
+		fmt.Fprintf(&b, "%s * %s\n    ; id: %s\n", date.Format("2006/01/02"), l.quote(payee), txn.ID)
+		if txn.Description != "" && txn.Description != payee {
+			fmt.Fprintf(&b, "    ; %s\n", l.quote(txn.Description))
+		}
+		if len(txn.Tags) > 0 {
+			fmt.Fprintf(&b, "    ; :%s:\n", strings.Join(txn.Tags, ":"))
+		}
+		if txn.Notes != "" {
+			fmt.Fprintf(&b, "    ; %s\n", l.quote(txn.Notes))
+		}
+	}
+	for _, p := range postings {
+		b.WriteString(l.posting(p.account, p.amount, account.Currency))
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(l.w, b.String())
+	return err
+}
+
+// Ends each account on its balance as of the balance date
+func (l *ledgerWriter) Close() error {
+	for _, accountId := range l.order {
+		account := l.opts.account(accountId)
+		if !l.reconciles(account) {
+			continue
+		}
+		date := time.Unix(account.BalanceDate, 0).UTC()
+		if last := l.lastDate[accountId]; last.After(date) {
+			date = last
+		}
+		name := l.userAccount(account)
+		var err error
+		if l.dialect == beancountDialect {
+			// assertions hold at the start of the day, so the day after takes that day's transactions in
+			_, err = fmt.Fprintf(l.w, "%s balance %-50s  %s %s\n", date.AddDate(0, 0, 1).Format(time.DateOnly), name, account.Balance, account.Currency)
+		} else {
+			_, err = fmt.Fprintf(l.w, "%s * Balance reconciliation\n    %-50s  = %s %s\n    %s\n\n",
+				date.Format("2006/01/02"), name, account.Balance, account.Currency, openingBalances)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+`
+
+// OFX 2 with one bank statement per account. Transactions have to arrive grouped by account, a
+// statement is closed when the next account's transactions start
+type ofxWriter struct {
+	w       io.Writer
+	opts    Options
+	current string
+	open    bool
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format("20060102150405")
+}
+
+// Escapes a value for an OFX element, cut to the element's maximum length
+func ofxText(value string, limit int) string {
+	runes := []rune(strings.Join(strings.Fields(value), " "))
+	if len(runes) > limit {
+		runes = runes[:limit]
+	}
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(string(runes)))
+	return b.String()
+}
+
+func newOFXWriter(w io.Writer, opts Options) (*ofxWriter, error) {
+	_, err := fmt.Fprintf(w, ofxHeader, ofxDate(opts.Now))
+	return &ofxWriter{w: w, opts: opts}, err
+}
+
+func (o *ofxWriter) startStatement(account Account, first time.Time) error {
+	accountType := "CHECKING"
+	switch {
+	case account.Liability:
+		accountType = "CREDITLINE"
+	case strings.Contains(strings.ToLower(account.AccountType), "saving"):
+		accountType = "SAVINGS"
+	}
+	start := o.opts.From
+	if start.IsZero() {
+		start = first
+	}
+	_, err := fmt.Fprintf(o.w, `<STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxText(account.Currency, 3), ofxText(account.Org, 9), ofxText(account.ID, 22), accountType, ofxDate(start), ofxDate(o.end()))
+	return err
+}
+
+func (o *ofxWriter) end() time.Time {
+	if !o.opts.To.IsZero() && o.opts.To.Before(o.opts.Now) {
+		return o.opts.To
+	}
+	return o.opts.Now
+}
+
+func (o *ofxWriter) endStatement() error {
+	account := o.opts.account(o.current)
+	balance := account.Balance
+	if balance == "" {
+		balance = "0"
+	}
+	_, err := fmt.Fprintf(o.w, "</BANKTRANLIST>\n<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n</STMTRS></STMTTRNRS>\n",
+		ofxText(balance, 32), ofxDate(time.Unix(account.BalanceDate, 0)))
+	return err
+}
+
+func (o *ofxWriter) Write(txn models.Transaction) error {
+	if !o.open || txn.AccountID != o.current {
+		if o.open {
+			if err := o.endStatement(); err != nil {
+				return err
+			}
+		}
+		o.current, o.open = txn.AccountID, true
+		if err := o.startStatement(o.opts.account(txn.AccountID), transactionDate(txn)); err != nil {
+			return err
+		}
+	}
+
+	kind := "CREDIT"
+	if strings.HasPrefix(strings.TrimSpace(txn.Amount), "-") {
+		kind = "DEBIT"
+	}
+	name := txn.Payee
+	if name == "" {
+		name = txn.Description
+	}
+	memo := txn.Memo
+	if memo == "" {
+		memo = txn.Category
+	}
+	_, err := fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID><NAME>%s</NAME><MEMO>%s</MEMO></STMTTRN>\n",
+		kind, ofxDate(transactionDate(txn)), ofxText(txn.Amount, 32), ofxText(txn.ID, 255), ofxText(name, 32), ofxText(memo, 255))
+	return err
+}
+
+func (o *ofxWriter) Close() error {
+	if o.open {
+		if err := o.endStatement(); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(o.w, "</BANKMSGSRSV1>\n</OFX>\n")
+	return err
+}
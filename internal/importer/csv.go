@@ -0,0 +1,200 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// Date layouts tried when the mapping doesn't give one. Month first, like US banks export
+var csvDateFormats = []string{"2006-01-02", "01/02/2006", "1/2/2006", "01/02/06", "1/2/06", "2006/01/02", "01-02-2006", "Jan 2, 2006", "2 Jan 2006"}
+
+// Header names banks commonly use for each field, lower cased
+var csvColumnNames = map[string][]string{
+	"date":        {"date", "transaction date", "trans date", "posted date", "posting date", "post date"},
+	"amount":      {"amount", "transaction amount", "amount (usd)"},
+	"debit":       {"debit", "withdrawal", "withdrawals", "debit amount", "money out"},
+	"credit":      {"credit", "deposit", "deposits", "credit amount", "money in"},
+	"payee":       {"payee", "merchant", "name", "description", "transaction description"},
+	"description": {"description", "details", "original description", "transaction description"},
+	"memo":        {"memo", "notes", "note", "reference"},
+	"id":          {"transaction id", "id", "reference number", "fitid"},
+}
+
+// Guesses the mapping from the header, for the user to confirm or correct in the mapping step
+func SuggestCSVMapping(columns []string) models.CSVMapping {
+	find := func(field string, skip ...string) string {
+		for _, name := range csvColumnNames[field] {
+			for _, column := range columns {
+				if strings.EqualFold(strings.TrimSpace(column), name) && !containsFold(skip, column) {
+					return column
+				}
+			}
+		}
+		return ""
+	}
+	mapping := models.CSVMapping{
+		Date:   find("date"),
+		Amount: find("amount"),
+		Payee:  find("payee"),
+		Memo:   find("memo"),
+		ID:     find("id"),
+	}
+	if mapping.Amount == "" {
+		mapping.Debit, mapping.Credit = find("debit"), find("credit")
+	}
+	mapping.Description = find("description", mapping.Payee)
+	return mapping
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if item != "" && strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCSVDate(value string, format string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if format != "" {
+		return time.Parse(format, value)
+	}
+	for _, layout := range csvDateFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", value)
+}
+
+// Reads exports that have a byte order mark or rows of different lengths, both common from banks
+func newCSVReader(data []byte) *csv.Reader {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+// Header row of a CSV export, for the column mapping step
+func CSVColumns(data []byte) ([]string, error) {
+	columns, err := newCSVReader(data).Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	return columns, nil
+}
+
+// Reads a CSV export with a header row, using the mapping to find the columns. Rows that can't be
+// read are reported by their line number in the file
+func ParseCSV(data []byte, mapping models.CSVMapping) (Result, error) {
+	records, err := newCSVReader(data).ReadAll()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return Result{}, fmt.Errorf("the file is empty")
+	}
+
+	result := Result{Columns: records[0], Transactions: []models.Transaction{}, Errors: []models.ImportRowError{}}
+	index := map[string]int{}
+	for i, column := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	column := func(name string) (int, bool) {
+		if name == "" {
+			return 0, false
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(name))]
+		return i, ok
+	}
+
+	dateColumn, ok := column(mapping.Date)
+	if !ok {
+		return result, fmt.Errorf("the date column %q isn't in the file", mapping.Date)
+	}
+	amountColumn, hasAmount := column(mapping.Amount)
+	_, hasDebit := column(mapping.Debit)
+	_, hasCredit := column(mapping.Credit)
+	if !hasAmount && !hasDebit && !hasCredit {
+		return result, fmt.Errorf("the mapping needs an amount column, or debit and credit columns")
+	}
+
+	for i, record := range records[1:] {
+		line := i + 2
+		field := func(name string) string {
+			if c, ok := column(name); ok && c < len(record) {
+				return strings.TrimSpace(record[c])
+			}
+			return ""
+		}
+		// blank lines and trailing totals rows without a date
+		if dateColumn >= len(record) || strings.TrimSpace(record[dateColumn]) == "" {
+			continue
+		}
+
+		date, err := parseCSVDate(record[dateColumn], mapping.DateFormat)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: line, Message: err.Error()})
+			continue
+		}
+
+		var amount float64
+		if hasAmount {
+			if amountColumn >= len(record) {
+				result.Errors = append(result.Errors, models.ImportRowError{Row: line, Message: "missing amount"})
+				continue
+			}
+			if amount, err = parseAmount(record[amountColumn]); err != nil {
+				result.Errors = append(result.Errors, models.ImportRowError{Row: line, Message: err.Error()})
+				continue
+			}
+		} else {
+			var debit, credit float64
+			if value := field(mapping.Debit); hasDebit && value != "" {
+				if debit, err = parseAmount(value); err != nil {
+					result.Errors = append(result.Errors, models.ImportRowError{Row: line, Message: err.Error()})
+					continue
+				}
+			}
+			if value := field(mapping.Credit); hasCredit && value != "" {
+				if credit, err = parseAmount(value); err != nil {
+					result.Errors = append(result.Errors, models.ImportRowError{Row: line, Message: err.Error()})
+					continue
+				}
+			}
+			// some banks put a minus on debits, some don't
+			if debit < 0 {
+				debit = -debit
+			}
+			amount = credit - debit
+		}
+		if mapping.NegateAmounts {
+			amount = -amount
+		}
+
+		payee := field(mapping.Payee)
+		description := field(mapping.Description)
+		if payee == "" {
+			payee = description
+		}
+		if description == "" {
+			description = payee
+		}
+		result.Transactions = append(result.Transactions, models.Transaction{
+			ID:           field(mapping.ID),
+			Posted:       date.Unix(),
+			TransactedAt: date.Unix(),
+			Amount:       formatAmount(amount),
+			Payee:        payee,
+			Description:  description,
+			Memo:         field(mapping.Memo),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+// Package importer parses bank export files (CSV, OFX/QFX and QIF) into transactions
+package importer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// What a parser got out of a file. Rows that couldn't be read are reported in Errors and left out
+// of Transactions
+type Result struct {
+	Transactions []models.Transaction
+	Errors       []models.ImportRowError
+	// CSV header
+	Columns []string
+}
+
+// Works out the format from the file name, or the content when the extension doesn't tell
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return models.ImportFormatCSV
+	case ".ofx", ".qfx":
+		return models.ImportFormatOFX
+	case ".qif":
+		return models.ImportFormatQIF
+	}
+	head := bytes.ToUpper(bytes.TrimSpace(data[:min(len(data), 512)]))
+	switch {
+	case bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>")):
+		return models.ImportFormatOFX
+	case bytes.HasPrefix(head, []byte("!TYPE")) || bytes.HasPrefix(head, []byte("!ACCOUNT")):
+		return models.ImportFormatQIF
+	}
+	return models.ImportFormatCSV
+}
+
+func Parse(format string, data []byte, mapping models.CSVMapping) (Result, error) {
+	switch format {
+	case models.ImportFormatCSV:
+		return ParseCSV(data, mapping)
+	case models.ImportFormatOFX:
+		return ParseOFX(data)
+	case models.ImportFormatQIF:
+		return ParseQIF(data)
+	}
+	return Result{}, fmt.Errorf("unsupported import format %q", format)
+}
+
+// Reads an amount the way banks write them: "$1,234.56", "-12.00", "(12.00)" or "12.00-"
+func parseAmount(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative, value = true, value[1:len(value)-1]
+	}
+	if strings.HasSuffix(value, "-") {
+		negative, value = true, strings.TrimSuffix(value, "-")
+	}
+	value = strings.NewReplacer("$", "", ",", "", " ", "", "+", "").Replace(value)
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// Gives every transaction an ID that stays the same when the same file is imported again, so the
+// usual dedup on ID catches re-imports. Transactions the bank gave an id (in ID) keep a hash of it;
+// the others are identified by their content and how many identical rows came before them in the
+// file, so two identical coffees on one day both import
+func AssignIds(accountId string, txns []models.Transaction) {
+	seen := map[string]int{}
+	for i, txn := range txns {
+		source := txn.ID
+		if source == "" {
+			content := fmt.Sprintf("%d|%s|%s|%s", txn.TransactedAt, txn.Amount, txn.Payee, txn.Description)
+			source = fmt.Sprintf("%s|%d", content, seen[content])
+			seen[content]++
+		} else {
+			source = "id|" + source
+		}
+		sum := sha256.Sum256([]byte(accountId + "|" + source))
+		txns[i].ID = "IMP-" + hex.EncodeToString(sum[:12])
+		txns[i].AccountID = accountId
+	}
+}
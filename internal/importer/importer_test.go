@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+func day(value string) int64 {
+	d, _ := time.Parse(time.DateOnly, value)
+	return d.Unix()
+}
+
+func TestParseCSV(t *testing.T) {
+	data := []byte("\xef\xbb\xbfPosting Date,Description,Debit,Credit,Reference\n" +
+		"10/01/2026,COFFEE SHOP,4.50,,A1\n" +
+		"10/02/2026,\"ACME PAYROLL, INC\",,\"2,100.00\",A2\n" +
+		"10/03/2026,REFUND,(5.00),,A3\n" +
+		"not a date,BROKEN,1.00,,A4\n" +
+		",,,,\n")
+	columns := []string{"Posting Date", "Description", "Debit", "Credit", "Reference"}
+
+	mapping := SuggestCSVMapping(columns)
+	if mapping.Date != "Posting Date" || mapping.Debit != "Debit" || mapping.Credit != "Credit" || mapping.Payee != "Description" || mapping.Description != "" || mapping.Memo != "Reference" {
+		t.Fatalf("Unexpected suggested mapping: %+v", mapping)
+	}
+
+	result, err := ParseCSV(data, mapping)
+	if err != nil {
+		t.Fatalf("Failed to parse csv: %v", err)
+	}
+	if len(result.Transactions) != 3 {
+		t.Fatalf("Expected 3 transactions, got %+v", result.Transactions)
+	}
+	coffee, payroll, refund := result.Transactions[0], result.Transactions[1], result.Transactions[2]
+	if coffee.Amount != "-4.50" || coffee.Payee != "COFFEE SHOP" || coffee.TransactedAt != day("2026-10-01") {
+		t.Errorf("Unexpected coffee transaction: %+v", coffee)
+	}
+	if payroll.Amount != "2100.00" || payroll.Payee != "ACME PAYROLL, INC" {
+		t.Errorf("Unexpected payroll transaction: %+v", payroll)
+	}
+	// a debit in parentheses is still money out
+	if refund.Amount != "-5.00" {
+		t.Errorf("Expected the debit to be -5.00, got %s", refund.Amount)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 5 {
+		t.Errorf("Expected an error on row 5, got %+v", result.Errors)
+	}
+
+	signed := []byte("Date,Amount,Merchant\n2026-10-05,12.00,Grocer\n")
+	result, err = ParseCSV(signed, models.CSVMapping{Date: "date", Amount: "amount", Payee: "merchant", NegateAmounts: true})
+	if err != nil || len(result.Transactions) != 1 || result.Transactions[0].Amount != "-12.00" {
+		t.Errorf("Expected the negated amount -12.00, got %+v (%v)", result.Transactions, err)
+	}
+
+	if _, err := ParseCSV(signed, models.CSVMapping{Date: "Posted", Amount: "Amount"}); err == nil {
+		t.Errorf("Expected an error for a date column that isn't in the file")
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	sgml := []byte(`OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20261001120000[-5:EST]<TRNAMT>-4.50<FITID>9001<NAME>COFFEE &amp; CO<MEMO>POS
+</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20261002<TRNAMT>2100.00<FITID>9002<NAME>ACME PAYROLL
+</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>bad<TRNAMT>-1.00<FITID>9003<NAME>BROKEN
+</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`)
+
+	if format := DetectFormat("statement.txt", sgml); format != models.ImportFormatOFX {
+		t.Errorf("Expected the content to be detected as ofx, got %s", format)
+	}
+	result, err := ParseOFX(sgml)
+	if err != nil {
+		t.Fatalf("Failed to parse ofx: %v", err)
+	}
+	if len(result.Transactions) != 2 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("Expected 2 transactions and an error on row 3, got %+v %+v", result.Transactions, result.Errors)
+	}
+	coffee := result.Transactions[0]
+	if coffee.ID != "9001" || coffee.Amount != "-4.50" || coffee.Payee != "COFFEE & CO" || coffee.Memo != "POS" {
+		t.Errorf("Unexpected coffee transaction: %+v", coffee)
+	}
+	if coffee.TransactedAt != time.Date(2026, 10, 1, 17, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("Expected the time zone offset to be applied, got %d", coffee.TransactedAt)
+	}
+
+	xml := []byte(`<?xml version="1.0"?><OFX><STMTTRN><DTPOSTED>20261003</DTPOSTED><TRNAMT>-9.99</TRNAMT><FITID>X1</FITID><NAME>STREAMING</NAME></STMTTRN></OFX>`)
+	result, err = ParseOFX(xml)
+	if err != nil || len(result.Transactions) != 1 || result.Transactions[0].Amount != "-9.99" || result.Transactions[0].TransactedAt != day("2026-10-03") {
+		t.Errorf("Unexpected xml result: %+v (%v)", result.Transactions, err)
+	}
+}
+
+func TestParseQIF(t *testing.T) {
+	data := []byte("!Type:Bank\r\nD10/1'26\r\nT-4.50\r\nPCOFFEE SHOP\r\nMmorning\r\n^\r\nD10/02/2026\r\nU2,100.00\r\nPACME PAYROLL\r\n^\r\nDsometime\r\nT-1.00\r\n^\r\n")
+
+	result, err := ParseQIF(data)
+	if err != nil {
+		t.Fatalf("Failed to parse qif: %v", err)
+	}
+	if len(result.Transactions) != 2 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("Expected 2 transactions and an error on row 3, got %+v %+v", result.Transactions, result.Errors)
+	}
+	coffee, payroll := result.Transactions[0], result.Transactions[1]
+	if coffee.TransactedAt != day("2026-10-01") || coffee.Amount != "-4.50" || coffee.Memo != "morning" {
+		t.Errorf("Unexpected coffee transaction: %+v", coffee)
+	}
+	if payroll.TransactedAt != day("2026-10-02") || payroll.Amount != "2100.00" {
+		t.Errorf("Unexpected payroll transaction: %+v", payroll)
+	}
+}
+
+func TestAssignIds(t *testing.T) {
+	txns := []models.Transaction{
+		{TransactedAt: day("2026-10-01"), Amount: "-4.50", Payee: "COFFEE"},
+		{TransactedAt: day("2026-10-01"), Amount: "-4.50", Payee: "COFFEE"},
+		{ID: "9001", TransactedAt: day("2026-10-02"), Amount: "-9.00", Payee: "LUNCH"},
+	}
+	again := append([]models.Transaction{}, txns...)
+	AssignIds("acc", txns)
+	AssignIds("acc", again)
+
+	if txns[0].ID == txns[1].ID {
+		t.Errorf("Expected identical rows to get different ids")
+	}
+	for i := range txns {
+		if txns[i].ID != again[i].ID || txns[i].AccountID != "acc" {
+			t.Errorf("Expected the same id on re-import, got %s and %s", txns[i].ID, again[i].ID)
+		}
+	}
+}
+
+func TestParseMigration(t *testing.T) {
+	mint := []byte(`"Date","Description","Original Description","Amount","Transaction Type","Category","Account Name","Labels","Notes"
+"10/01/2026","Blue Bottle","SQ *BLUE BOTTLE","6.50","debit","Coffee Shops","Sapphire Card","work trip","client meeting"
+"10/02/2026","Acme","ACME PAYROLL","2,100.00","credit","Paycheck","Checking","",""
+"bad","Broken","","1.00","debit","","Checking","",""
+`)
+	txns, rowErrors, err := ParseMigration(models.MigrationSourceMint, mint)
+	if err != nil {
+		t.Fatalf("Failed to parse mint export: %v", err)
+	}
+	if len(txns) != 2 || len(rowErrors) != 1 || rowErrors[0].Row != 4 {
+		t.Fatalf("Expected 2 transactions and an error on row 4, got %+v %+v", txns, rowErrors)
+	}
+	coffee := txns[0]
+	if coffee.Amount != "-6.50" || coffee.Payee != "Blue Bottle" || coffee.Description != "SQ *BLUE BOTTLE" || coffee.AccountName != "Sapphire Card" ||
+		coffee.SourceCategory != "Coffee Shops" || coffee.Notes != "client meeting" || len(coffee.Tags) != 2 {
+		t.Errorf("Unexpected mint transaction: %+v", coffee)
+	}
+	if txns[1].Amount != "2100.00" {
+		t.Errorf("Expected the credit to be positive, got %s", txns[1].Amount)
+	}
+
+	ynab := []byte("\"Account\",\"Flag\",\"Date\",\"Payee\",\"Category Group/Category\",\"Category Group\",\"Category\",\"Memo\",\"Outflow\",\"Inflow\",\"Cleared\"\n" +
+		"\"Checking\",\"Red\",\"10/03/2026\",\"Landlord\",\"Bills: Rent/Mortgage\",\"Bills\",\"Rent/Mortgage\",\"October\",\"$1,500.00\",\"$0.00\",\"Cleared\"\n")
+	txns, _, err = ParseMigration(models.MigrationSourceYNAB, ynab)
+	if err != nil || len(txns) != 1 {
+		t.Fatalf("Failed to parse ynab export: %+v (%v)", txns, err)
+	}
+	if rent := txns[0]; rent.Amount != "-1500.00" || rent.SourceCategory != "Rent/Mortgage" || rent.Notes != "October" || len(rent.Tags) != 1 || rent.Tags[0] != "Red" {
+		t.Errorf("Unexpected ynab transaction: %+v", rent)
+	}
+
+	monarch := []byte("Date,Merchant,Category,Account,Original Statement,Notes,Amount,Tags\n2026-10-04,Whole Foods,Groceries,Checking,WHOLEFDS #123,,-84.12,\"Household, Weekly\"\n")
+	txns, _, err = ParseMigration(models.MigrationSourceMonarch, monarch)
+	if err != nil || len(txns) != 1 {
+		t.Fatalf("Failed to parse monarch export: %+v (%v)", txns, err)
+	}
+	if groceries := txns[0]; groceries.Amount != "-84.12" || groceries.TransactedAt != day("2026-10-04") || len(groceries.Tags) != 2 || groceries.Tags[1] != "Weekly" {
+		t.Errorf("Unexpected monarch transaction: %+v", groceries)
+	}
+
+	if _, _, err := ParseMigration(models.MigrationSourceMint, monarch); err == nil {
+		t.Errorf("Expected a monarch export to be rejected as a mint one")
+	}
+}
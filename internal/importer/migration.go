@@ -0,0 +1,178 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// A transaction from another budgeting app's export, with the account and category names it had
+// there. Those are matched to the user's accounts and our categories before it is imported
+type MigratedTransaction struct {
+	models.Transaction
+	AccountName    string
+	SourceCategory string
+}
+
+// Columns of an export, looked up by header name regardless of case
+type csvTable struct {
+	index   map[string]int
+	records [][]string
+}
+
+func readCSVTable(data []byte) (csvTable, error) {
+	records, err := newCSVReader(data).ReadAll()
+	if err != nil {
+		return csvTable{}, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return csvTable{}, fmt.Errorf("the file is empty")
+	}
+	table := csvTable{index: map[string]int{}, records: records[1:]}
+	for i, column := range records[0] {
+		table.index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	return table, nil
+}
+
+// Fails with the first of the columns the export must have that's missing
+func (t csvTable) require(columns ...string) error {
+	for _, column := range columns {
+		if _, ok := t.index[strings.ToLower(column)]; !ok {
+			return fmt.Errorf("the %q column is missing, is this the right export?", column)
+		}
+	}
+	return nil
+}
+
+func (t csvTable) get(record []string, column string) string {
+	if i, ok := t.index[strings.ToLower(column)]; ok && i < len(record) {
+		return strings.TrimSpace(record[i])
+	}
+	return ""
+}
+
+// Splits a tag column. Comma separated when there's a comma, whitespace separated otherwise
+func splitTags(value string) []string {
+	var tags []string
+	parts := strings.Fields(value)
+	if strings.Contains(value, ",") {
+		parts = strings.Split(value, ",")
+	}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			tags = append(tags, part)
+		}
+	}
+	return tags
+}
+
+// Reads the transactions export of Mint, YNAB or Monarch. Rows are numbered by their line in the
+// file for error reporting
+func ParseMigration(source string, data []byte) ([]MigratedTransaction, []models.ImportRowError, error) {
+	table, err := readCSVTable(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var parse func(record []string) (MigratedTransaction, error)
+	switch source {
+	case models.MigrationSourceMint:
+		parse, err = table.parseMintRow, table.require("Date", "Amount", "Transaction Type", "Account Name")
+	case models.MigrationSourceYNAB:
+		parse, err = table.parseYNABRow, table.require("Account", "Date", "Payee", "Outflow", "Inflow")
+	case models.MigrationSourceMonarch:
+		parse, err = table.parseMonarchRow, table.require("Date", "Merchant", "Account", "Amount")
+	default:
+		return nil, nil, fmt.Errorf("unsupported migration source %q", source)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txns := []MigratedTransaction{}
+	rowErrors := []models.ImportRowError{}
+	for i, record := range table.records {
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		txn, err := parse(record)
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: i + 2, Message: err.Error()})
+			continue
+		}
+		txns = append(txns, txn)
+	}
+	return txns, rowErrors, nil
+}
+
+func migratedTransaction(date string, amount float64, payee string, description string) (MigratedTransaction, error) {
+	d, err := parseCSVDate(date, "")
+	if err != nil {
+		return MigratedTransaction{}, err
+	}
+	if description == "" {
+		description = payee
+	}
+	return MigratedTransaction{Transaction: models.Transaction{
+		Posted:       d.Unix(),
+		TransactedAt: d.Unix(),
+		Amount:       formatAmount(amount),
+		Payee:        payee,
+		Description:  description,
+	}}, nil
+}
+
+// Mint writes every amount as positive, with the sign in the transaction type
+func (t csvTable) parseMintRow(record []string) (MigratedTransaction, error) {
+	amount, err := parseAmount(t.get(record, "Amount"))
+	if err != nil {
+		return MigratedTransaction{}, err
+	}
+	if strings.EqualFold(t.get(record, "Transaction Type"), "debit") {
+		amount = -amount
+	}
+	txn, err := migratedTransaction(t.get(record, "Date"), amount, t.get(record, "Description"), t.get(record, "Original Description"))
+	txn.AccountName = t.get(record, "Account Name")
+	txn.SourceCategory = t.get(record, "Category")
+	txn.Notes = t.get(record, "Notes")
+	txn.Tags = splitTags(t.get(record, "Labels"))
+	return txn, err
+}
+
+// YNAB has separate outflow and inflow columns. Flags are the closest YNAB has to tags
+func (t csvTable) parseYNABRow(record []string) (MigratedTransaction, error) {
+	var outflow, inflow float64
+	var err error
+	if value := t.get(record, "Outflow"); value != "" {
+		if outflow, err = parseAmount(value); err != nil {
+			return MigratedTransaction{}, err
+		}
+	}
+	if value := t.get(record, "Inflow"); value != "" {
+		if inflow, err = parseAmount(value); err != nil {
+			return MigratedTransaction{}, err
+		}
+	}
+	txn, err := migratedTransaction(t.get(record, "Date"), inflow-outflow, t.get(record, "Payee"), "")
+	txn.AccountName = t.get(record, "Account")
+	txn.SourceCategory = t.get(record, "Category")
+	txn.Notes = t.get(record, "Memo")
+	if flag := t.get(record, "Flag"); flag != "" {
+		txn.Tags = []string{flag}
+	}
+	return txn, err
+}
+
+func (t csvTable) parseMonarchRow(record []string) (MigratedTransaction, error) {
+	amount, err := parseAmount(t.get(record, "Amount"))
+	if err != nil {
+		return MigratedTransaction{}, err
+	}
+	txn, err := migratedTransaction(t.get(record, "Date"), amount, t.get(record, "Merchant"), t.get(record, "Original Statement"))
+	txn.AccountName = t.get(record, "Account")
+	txn.SourceCategory = t.get(record, "Category")
+	txn.Notes = t.get(record, "Notes")
+	txn.Tags = splitTags(t.get(record, "Tags"))
+	return txn, err
+}
@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// Matches every tag with the text that follows it. OFX 1.x is SGML and leaves the closing tags of
+// values out, OFX 2.x is XML and has them; reading the text after each opening tag handles both
+var ofxTag = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)>([^<]*)`)
+
+// OFX dates are YYYYMMDD with an optional HHMMSS, milliseconds and a [offset:TZ] suffix
+func parseOFXDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	offset := 0
+	if i := strings.Index(value, "["); i >= 0 {
+		zone := strings.TrimSuffix(value[i+1:], "]")
+		value = value[:i]
+		if j := strings.Index(zone, ":"); j >= 0 {
+			zone = zone[:j]
+		}
+		var hours float64
+		if _, err := fmt.Sscanf(zone, "%g", &hours); err == nil {
+			offset = int(hours * 3600)
+		}
+	}
+	if i := strings.Index(value, "."); i >= 0 {
+		value = value[:i]
+	}
+	var layout string
+	switch len(value) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	t, err := time.ParseInLocation(layout, value, time.FixedZone("", offset))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return t.UTC(), nil
+}
+
+// Reads the statement transactions (STMTTRN) out of an OFX or QFX file. Transactions are numbered
+// in the order they appear for error reporting
+func ParseOFX(data []byte) (Result, error) {
+	result := Result{Transactions: []models.Transaction{}, Errors: []models.ImportRowError{}}
+	if !strings.Contains(strings.ToUpper(string(data)), "<OFX>") {
+		return result, fmt.Errorf("not an OFX file")
+	}
+
+	var (
+		fields map[string]string
+		row    int
+	)
+	finish := func() {
+		defer func() { fields = nil }()
+		row++
+		date := fields["DTPOSTED"]
+		if fields["DTUSER"] != "" {
+			date = fields["DTUSER"]
+		}
+		transactedAt, err := parseOFXDate(date)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: row, Message: err.Error()})
+			return
+		}
+		posted, err := parseOFXDate(fields["DTPOSTED"])
+		if err != nil {
+			posted = transactedAt
+		}
+		amount, err := parseAmount(fields["TRNAMT"])
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: row, Message: err.Error()})
+			return
+		}
+		payee := fields["NAME"]
+		if payee == "" {
+			payee = fields["PAYEE"]
+		}
+		description := payee
+		if payee == "" {
+			payee, description = fields["MEMO"], fields["MEMO"]
+		}
+		result.Transactions = append(result.Transactions, models.Transaction{
+			ID:           fields["FITID"],
+			Posted:       posted.Unix(),
+			TransactedAt: transactedAt.Unix(),
+			Amount:       formatAmount(amount),
+			Payee:        payee,
+			Description:  description,
+			Memo:         fields["MEMO"],
+		})
+	}
+
+	for _, match := range ofxTag.FindAllStringSubmatch(string(data), -1) {
+		closing, tag, text := match[1] == "/", strings.ToUpper(match[2]), strings.TrimSpace(match[3])
+		switch {
+		case tag == "STMTTRN" && !closing:
+			fields = map[string]string{}
+		case tag == "STMTTRN" && closing:
+			if fields != nil {
+				finish()
+			}
+		case fields != nil && !closing && text != "":
+			fields[tag] = unescapeSGML(text)
+		}
+	}
+	return result, nil
+}
+
+func unescapeSGML(text string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&apos;", "'", "&quot;", `"`).Replace(text)
+}
@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+// QIF dates are month first and come as 1/2/2006, 01/02/06 or Quicken's 1/ 2'06
+var qifDateFormats = []string{"1/2/2006", "1/2/06", "2006-01-02"}
+
+func parseQIFDate(value string) (time.Time, error) {
+	value = strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(value), " ", ""), "'", "/")
+	for _, layout := range qifDateFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			// Quicken writes years after 1999 as '00 and up
+			if t.Year() < 1970 {
+				t = t.AddDate(100, 0, 0)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// Reads the transactions of a QIF file. Each record is a set of lines keyed by their first
+// character and ends with a "^" line. Records are numbered in the order they appear
+func ParseQIF(data []byte) (Result, error) {
+	result := Result{Transactions: []models.Transaction{}, Errors: []models.ImportRowError{}}
+
+	var (
+		fields = map[string]string{}
+		row    int
+		inList bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			// account and category lists describe the file, they aren't transactions
+			header := strings.ToLower(line)
+			inList = strings.HasPrefix(header, "!account") || strings.HasPrefix(header, "!type:cat") || strings.HasPrefix(header, "!type:class") || strings.HasPrefix(header, "!type:memorized")
+			continue
+		}
+		if line[0] != '^' {
+			// the first of several split lines (S, $) is enough, the total is on T
+			if _, ok := fields[line[:1]]; !ok {
+				fields[line[:1]] = strings.TrimSpace(line[1:])
+			}
+			continue
+		}
+
+		record := fields
+		fields = map[string]string{}
+		if inList {
+			continue
+		}
+		row++
+		date, err := parseQIFDate(record["D"])
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: row, Message: err.Error()})
+			continue
+		}
+		value := record["T"]
+		if value == "" {
+			value = record["U"]
+		}
+		amount, err := parseAmount(value)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: row, Message: err.Error()})
+			continue
+		}
+		payee := record["P"]
+		if payee == "" {
+			payee = record["M"]
+		}
+		result.Transactions = append(result.Transactions, models.Transaction{
+			Posted:       date.Unix(),
+			TransactedAt: date.Unix(),
+			Amount:       formatAmount(amount),
+			Payee:        payee,
+			Description:  payee,
+			Memo:         record["M"],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read qif: %w", err)
+	}
+	return result, nil
+}
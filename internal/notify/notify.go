@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/internal/db"
+	"github.com/BBaCode/pocketwise-server/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// A delivery channel for notifications (email, webhook, in-app inbox...)
+type Notifier interface {
+	Notify(ctx context.Context, prefs models.NotificationPreferences, n models.Notification) error
+}
+
+// Routes a notification to the inbox and to every push channel the user enabled. During the user's
+// quiet hours the push channels are queued and delivered by SendDeferred once they are over
+type Dispatcher struct {
+	Inbox   Notifier
+	Email   Notifier
+	Webhook Notifier
+	pool    *pgxpool.Pool
+}
+
+// Builds a dispatcher from the environment. Email is only available when SMTP_HOST is set
+func NewDispatcher(pool *pgxpool.Pool) *Dispatcher {
+	d := &Dispatcher{
+		Inbox:   &InboxNotifier{pool: pool},
+		Webhook: NewWebhookNotifier(),
+		pool:    pool,
+	}
+	if smtpNotifier := NewSMTPNotifierFromEnv(); smtpNotifier != nil {
+		d.Email = smtpNotifier
+	}
+	return d
+}
+
+// The push channels the user enabled and this server can deliver to
+func (d *Dispatcher) pushChannels(prefs models.NotificationPreferences) []string {
+	var channels []string
+	if prefs.EmailEnabled && prefs.Email != "" && d.Email != nil {
+		channels = append(channels, models.NotificationChannelEmail)
+	}
+	if prefs.WebhookEnabled && prefs.WebhookURL != "" && d.Webhook != nil {
+		channels = append(channels, models.NotificationChannelWebhook)
+	}
+	return channels
+}
+
+// Delivers to the channels that are still enabled. Push channels are best effort, the
+// notification is already in the inbox
+func (d *Dispatcher) push(ctx context.Context, prefs models.NotificationPreferences, n models.Notification, channels []string) {
+	enabled := d.pushChannels(prefs)
+	for _, channel := range channels {
+		if !slices.Contains(enabled, channel) {
+			continue
+		}
+		switch channel {
+		case models.NotificationChannelEmail:
+			if err := d.Email.Notify(ctx, prefs, n); err != nil {
+				log.Printf("Failed to email notification to user %s: %v\n", n.UserId, err)
+			}
+		case models.NotificationChannelWebhook:
+			if err := d.Webhook.Notify(ctx, prefs, n); err != nil {
+				log.Printf("Failed to deliver notification webhook for user %s: %v\n", n.UserId, err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) Send(ctx context.Context, n models.Notification) error {
+	prefs, err := db.FetchNotificationPreferences(n.UserId, d.pool)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Inbox.Notify(ctx, prefs, n); err != nil {
+		return err
+	}
+
+	channels := d.pushChannels(prefs)
+	if len(channels) == 0 {
+		return nil
+	}
+	if InQuietHours(prefs, time.Now()) {
+		return db.DeferNotification(n, channels, d.pool)
+	}
+	d.push(ctx, prefs, n, channels)
+	return nil
+}
+
+// Delivers what was held back for users whose quiet hours are over. Channels the user turned off
+// in the meantime are skipped
+func (d *Dispatcher) SendDeferred(ctx context.Context, now time.Time) {
+	userIds, err := db.FetchDeferredNotificationUsers(d.pool)
+	if err != nil {
+		log.Printf("Failed to fetch deferred notifications: %v\n", err)
+		return
+	}
+	for _, userId := range userIds {
+		prefs, err := db.FetchNotificationPreferences(userId, d.pool)
+		if err != nil {
+			log.Printf("Failed to fetch notification preferences of user %s: %v\n", userId, err)
+			continue
+		}
+		if InQuietHours(prefs, now) {
+			continue
+		}
+		deferred, err := db.FetchDeferredNotifications(userId, d.pool)
+		if err != nil {
+			log.Printf("Failed to fetch deferred notifications of user %s: %v\n", userId, err)
+			continue
+		}
+		for _, held := range deferred {
+			// removed first so a delivery is never repeated, pushes are best effort either way
+			if err := db.DeleteDeferredNotification(held.ID, d.pool); err != nil {
+				log.Printf("Failed to delete deferred notification %s: %v\n", held.ID, err)
+				continue
+			}
+			d.push(ctx, prefs, held.Notification, held.Channels)
+		}
+	}
+}
+
+// Reports whether now falls in the user's quiet hours. Ranges that wrap past midnight (22 - 7) are
+// supported and equal start and end hours mean there are no quiet hours.
+func InQuietHours(prefs models.NotificationPreferences, now time.Time) bool {
+	if prefs.QuietHoursStart == prefs.QuietHoursEnd {
+		return false
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	hour := now.In(loc).Hour()
+	if prefs.QuietHoursStart < prefs.QuietHoursEnd {
+		return hour >= prefs.QuietHoursStart && hour < prefs.QuietHoursEnd
+	}
+	return hour >= prefs.QuietHoursStart || hour < prefs.QuietHoursEnd
+}
+
+// Stores notifications in the notifications table, which backs GET /notifications
+type InboxNotifier struct {
+	pool *pgxpool.Pool
+}
+
+func (i *InboxNotifier) Notify(ctx context.Context, prefs models.NotificationPreferences, n models.Notification) error {
+	_, err := db.InsertNotification(n, i.pool)
+	return err
+}
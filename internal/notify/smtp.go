@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+type SMTPNotifier struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// Returns nil when SMTP_HOST isn't configured
+func NewSMTPNotifierFromEnv() *SMTPNotifier {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return &SMTPNotifier{
+		Addr: host + ":" + port,
+		From: os.Getenv("SMTP_FROM"),
+		Auth: auth,
+	}
+}
+
+func (s *SMTPNotifier) Notify(ctx context.Context, prefs models.NotificationPreferences, n models.Notification) error {
+	// strip newlines so a title can't inject extra headers
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(n.Title)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", s.From, prefs.Email, subject, n.Body)
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{prefs.Email}, []byte(msg))
+}
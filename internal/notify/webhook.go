@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/BBaCode/pocketwise-server/models"
+)
+
+var ErrWebhookAddressNotAllowed = errors.New("webhook must point at a public address")
+
+// Ranges webhooks may not reach on top of what net.IP reports as loopback, private, link-local or
+// unspecified: shared address space, IETF protocol assignments, benchmarking, reserved and NAT64
+var blockedNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// Whether the server may send webhooks to the address. Keeps users from pointing the server at
+// itself, the private network or the cloud metadata endpoint
+func publicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseWebhookURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.New("webhook URL is invalid")
+	}
+	if u.Scheme != "https" || u.Hostname() == "" {
+		return nil, errors.New("webhook URL must be an https URL")
+	}
+	if u.User != nil {
+		return nil, errors.New("webhook URL can't contain credentials")
+	}
+	return u, nil
+}
+
+// Checks a webhook URL before it is saved: it has to be https and every address its host resolves
+// to has to be public. Deliveries check the address they connect to again, since DNS can change
+func ValidateWebhookURL(ctx context.Context, raw string) error {
+	u, err := parseWebhookURL(raw)
+	if err != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("webhook host %s could not be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+	return nil
+}
+
+// Refuses connections to addresses that aren't public. Runs after DNS resolution, on the address
+// actually being dialed
+func dialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicIP(net.ParseIP(host)) {
+		return ErrWebhookAddressNotAllowed
+	}
+	return nil
+}
+
+// POSTs the notification as JSON to the user's webhook URL. When the user set a secret the body is
+// signed with HMAC-SHA256 and sent in the X-Pocketwise-Signature header so receivers can verify it
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+// The client only connects to public addresses, skips any proxy (which would be the address
+// checked instead) and doesn't follow redirects, which count as a failed delivery
+func NewWebhookNotifier() *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: dialControl}
+	transport := &http.Transport{
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+	return &WebhookNotifier{Client: &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (wh *WebhookNotifier) Notify(ctx context.Context, prefs models.NotificationPreferences, n models.Notification) error {
+	if _, err := parseWebhookURL(prefs.WebhookURL); err != nil {
+		return err
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, prefs.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if prefs.WebhookSecret != "" {
+		req.Header.Set("X-Pocketwise-Signature", Sign(prefs.WebhookSecret, body))
+	}
+
+	resp, err := wh.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,131 @@
+package simplefin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSetupToken = errors.New("invalid SimpleFIN setup token")
+	ErrHostNotAllowed    = errors.New("SimpleFIN URL points at a host that isn't allowed")
+)
+
+// Where the bank connections made with the server's shared credentials live
+const sharedBridgeHost = "beta-bridge.simplefin.org"
+
+// Talks to SimpleFIN bridges (https://www.simplefin.org/protocol.html). A user connects their banks
+// by handing over a setup token, which is claimed once for an access URL. The access URL carries
+// the credentials for that user's accounts only, so deleting it takes the server's access away
+// without touching anyone else's connections
+type Client struct {
+	HTTP *http.Client
+	// Hosts setup tokens and access URLs may point at. Keeps users from sending the server's
+	// requests somewhere else
+	Hosts []string
+}
+
+// Allows the SimpleFIN bridges in SIMPLE_FIN_HOSTS (comma separated), by default the public ones
+func NewClientFromEnv() *Client {
+	hosts := []string{"bridge.simplefin.org", sharedBridgeHost}
+	if env := os.Getenv("SIMPLE_FIN_HOSTS"); env != "" {
+		hosts = strings.Split(env, ",")
+	}
+	return &Client{HTTP: &http.Client{Timeout: 30 * time.Second}, Hosts: hosts}
+}
+
+func (c *Client) parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != "https" {
+		return nil, ErrHostNotAllowed
+	}
+	for _, host := range c.Hosts {
+		if strings.EqualFold(u.Host, strings.TrimSpace(host)) {
+			return u, nil
+		}
+	}
+	return nil, ErrHostNotAllowed
+}
+
+// Exchanges a setup token for an access URL. A setup token can only be claimed once
+func (c *Client) Claim(ctx context.Context, setupToken string) (string, error) {
+	claimURL, err := base64.StdEncoding.DecodeString(strings.TrimSpace(setupToken))
+	if err != nil {
+		return "", ErrInvalidSetupToken
+	}
+	u, err := c.parseURL(string(claimURL))
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to claim setup token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to claim setup token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// claimed before, or made up
+		return "", fmt.Errorf("%w: %s", ErrInvalidSetupToken, resp.Status)
+	}
+
+	accessURL := strings.TrimSpace(string(body))
+	access, err := c.parseURL(accessURL)
+	if err != nil {
+		return "", err
+	}
+	if access.User == nil {
+		return "", fmt.Errorf("SimpleFIN returned an access URL without credentials")
+	}
+	return accessURL, nil
+}
+
+// Builds the GET /accounts request for an access URL, with its credentials moved into the
+// Authorization header
+func (c *Client) AccountsRequest(accessURL string, query url.Values) (*http.Request, error) {
+	u, err := c.parseURL(accessURL)
+	if err != nil {
+		return nil, err
+	}
+	user := u.User
+	u.User = nil
+	u.Path = strings.TrimRight(u.Path, "/") + "/accounts"
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	return req, nil
+}
+
+// The access URL of the server's own SimpleFIN credentials (SIMPLE_FIN_USERNAME and
+// SIMPLE_FIN_PASSWORD), used for users who haven't connected their own. Every such user shares
+// it, so it can't be revoked for one of them
+func SharedAccessURL() string {
+	u := url.URL{
+		Scheme: "https",
+		User:   url.UserPassword(os.Getenv("SIMPLE_FIN_USERNAME"), os.Getenv("SIMPLE_FIN_PASSWORD")),
+		Host:   sharedBridgeHost,
+		Path:   "/simplefin",
+	}
+	return u.String()
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Where uploaded files (receipts, statements) are kept. Keys are slash separated paths
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Stores the size bytes read from r without holding them in memory, for blobs too big for Put.
+	// r may be read more than once, stores that have to hash the content seek back to the start
+	PutStream(ctx context.Context, key string, r io.ReadSeeker, size int64, contentType string) error
+	// Returns ErrNotFound when nothing is stored under the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Deleting a key that doesn't exist is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Picks the store from BLOB_STORE: "s3" for an S3 compatible bucket, otherwise the local filesystem
+// under ATTACHMENTS_DIR (default ./attachments)
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	if os.Getenv("BLOB_STORE") == "s3" {
+		store := &S3Store{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		}
+		if store.Endpoint == "" || store.Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required when BLOB_STORE=s3")
+		}
+		if store.Region == "" {
+			store.Region = "us-east-1"
+		}
+		return store, nil
+	}
+
+	dir := os.Getenv("ATTACHMENTS_DIR")
+	if dir == "" {
+		dir = "./attachments"
+	}
+	return &LocalStore{Dir: dir}, nil
+}
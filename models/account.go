@@ -12,11 +12,38 @@ type Transaction struct {
 	Memo         string `json:"memo"`
 	TransactedAt int64  `json:"transacted_at"`
 	Category     string `json:"category"`
+	// Set when the transaction is one side of a transfer between the user's own accounts
+	TransferGroupId string `json:"transfer_group_id,omitempty"`
+	// Category lines when the transaction is split, empty otherwise
+	Splits []TransactionSplit `json:"splits,omitempty"`
+	Notes  string             `json:"notes,omitempty"`
+	// Set once the payee has been matched to one of the user's merchants
+	MerchantId   string            `json:"merchant_id,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
 }
 
+// Both sides of a transfer between two of the user's accounts
+type TransferGroup struct {
+	ID           string        `json:"id"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+type LinkTransferRequest struct {
+	TransactionIds []string `json:"transaction_ids"`
+}
+
+// A change to one transaction. Only the fields that are set get updated
 type TransactionCategoryRequest struct {
-	ID       string `json:"id"`
-	Category string `json:"category"`
+	ID       string  `json:"id"`
+	Category string  `json:"category"`
+	Notes    *string `json:"notes,omitempty"`
+	// Replaces all of the transaction's tags when set
+	Tags       *[]string `json:"tags,omitempty"`
+	AddTags    []string  `json:"add_tags,omitempty"`
+	RemoveTags []string  `json:"remove_tags,omitempty"`
+	// A null value removes the field
+	CustomFields map[string]*string `json:"custom_fields,omitempty"`
 }
 
 type UpdatedTransactions struct {
@@ -55,6 +82,33 @@ type StoredAccount struct {
 	AvailableBalance string    `json:"available-balance"`
 	Org              Org       `json:"org"`
 	BalanceDate      int64     `json:"balance-date"`
+	// Entered and kept up to date by the user rather than synced from SimpleFIN
+	Manual bool `json:"manual"`
+}
+
+// Body for creating or updating a manual account. Loans and other liabilities are entered as the
+// positive amount owed
+type ManualAccountRequest struct {
+	Name        string `json:"name"`
+	AccountType string `json:"account_type"`
+	Currency    string `json:"currency"`
+	Balance     string `json:"balance"`
+	OrgName     string `json:"org_name"`
+}
+
+type NetWorthAccount struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	AccountType string  `json:"account_type"`
+	Manual      bool    `json:"manual"`
+	Balance     float64 `json:"balance"`
+}
+
+type NetWorth struct {
+	Assets      float64           `json:"assets"`
+	Liabilities float64           `json:"liabilities"`
+	NetWorth    float64           `json:"net_worth"`
+	Accounts    []NetWorthAccount `json:"accounts"`
 }
 
 type UpdatedAccountData struct {
@@ -67,3 +121,8 @@ type UpdatedAccountData struct {
 type Org struct {
 	Name string `json:"name"`
 }
+
+// Body for connecting the user's own SimpleFIN bridge. The setup token comes from the bridge
+type SimpleFINConnectRequest struct {
+	SetupToken string `json:"setup_token"`
+}
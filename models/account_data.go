@@ -0,0 +1,43 @@
+package models
+
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusRunning = "running"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+
+	AuditDataExportRequested      = "data_export_requested"
+	AuditAccountDeletionRequested = "account_deletion_requested"
+	AuditAccountDeletionCancelled = "account_deletion_cancelled"
+	AuditAccountDeleted           = "account_deleted"
+)
+
+// A zip of everything stored for the user, built in the background
+type DataExport struct {
+	ID          string `json:"id"`
+	UserId      string `json:"user_id"`
+	Status      string `json:"status"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	// The zip is removed from the blob store after this
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	StorageKey string `json:"-"`
+}
+
+// Describes the contents of a data export. Written to the zip as manifest.json
+type DataExportManifest struct {
+	UserId      string `json:"user_id"`
+	GeneratedAt string `json:"generated_at"`
+	// Rows written per table, each table is <table>.json in the zip
+	Tables map[string]int `json:"tables"`
+	// Attachment files, under attachments/ in the zip
+	Attachments int `json:"attachments"`
+}
+
+type AccountDeletion struct {
+	UserId       string `json:"user_id"`
+	RequestedAt  string `json:"requested_at"`
+	ScheduledFor string `json:"scheduled_for"`
+}
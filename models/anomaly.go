@@ -0,0 +1,25 @@
+package models
+
+const (
+	AnomalyUnusualAmount = "unusual_amount"
+	AnomalyDuplicate     = "duplicate_charge"
+	AnomalyNewMerchant   = "new_merchant"
+	AnomalyForeign       = "foreign_currency"
+	AnomalyCategorySpike = "category_spike"
+)
+
+// Something unusual about a transaction, or about a category's spending for a month. Key
+// identifies what was flagged (the transaction id, or category and month for spikes) so the same
+// thing is only flagged once per kind
+type Anomaly struct {
+	ID            string  `json:"id"`
+	UserId        string  `json:"user_id"`
+	Kind          string  `json:"kind"`
+	Key           string  `json:"key"`
+	TransactionId string  `json:"transaction_id,omitempty"`
+	Category      string  `json:"category,omitempty"`
+	Amount        float64 `json:"amount"`
+	Reason        string  `json:"reason"`
+	Dismissed     bool    `json:"dismissed"`
+	CreatedAt     string  `json:"created_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+const (
+	AskMetricSpending     = "spending"
+	AskMetricIncome       = "income"
+	AskMetricCount        = "count"
+	AskMetricTransactions = "transactions"
+)
+
+type AskRequest struct {
+	Question string `json:"question"`
+}
+
+// What the language model turns a question into. Every step is one lookup over the user's
+// transactions; comparisons are several steps with the same metric
+type QueryPlan struct {
+	Steps []QueryStep `json:"steps"`
+}
+
+type QueryStep struct {
+	// Shown with the step's result, e.g. "March"
+	Label  string `json:"label"`
+	Metric string `json:"metric"`
+	// YYYY-MM-DD, both inclusive
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Optional breakdown of spending or income: category, merchant or tag
+	GroupBy    string   `json:"group_by,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	// Matched against payees and descriptions like the transaction search
+	Merchant string `json:"merchant,omitempty"`
+	// Most transactions or groups to return
+	Limit int `json:"limit,omitempty"`
+}
+
+type QueryStepResult struct {
+	Label        string          `json:"label"`
+	Metric       string          `json:"metric"`
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Total        float64         `json:"total"`
+	Count        int             `json:"count"`
+	Groups       []SpendingGroup `json:"groups,omitempty"`
+	Transactions []Transaction   `json:"transactions,omitempty"`
+}
+
+type AskResponse struct {
+	Question string            `json:"question"`
+	Answer   string            `json:"answer"`
+	Plan     QueryPlan         `json:"plan"`
+	Results  []QueryStepResult `json:"results"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// A receipt or document uploaded for a transaction. The file itself lives in the blob store
+type Attachment struct {
+	ID            string `json:"id"`
+	TransactionId string `json:"transaction_id"`
+	UserId        string `json:"user_id"`
+	Filename      string `json:"filename"`
+	ContentType   string `json:"content_type"`
+	Size          int64  `json:"size"`
+	HasThumbnail  bool   `json:"has_thumbnail"`
+	CreatedAt     string `json:"created_at"`
+	StorageKey    string `json:"-"`
+	ThumbnailKey  string `json:"-"`
+}
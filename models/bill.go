@@ -0,0 +1,48 @@
+package models
+
+// A bill the user entered by hand, e.g. rent paid by check. Cadence is one of once, weekly,
+// biweekly, monthly, quarterly or annual. Amount is the positive amount owed.
+type ManualBill struct {
+	ID          string  `json:"id"`
+	UserId      string  `json:"user_id"`
+	Name        string  `json:"name"`
+	Amount      float64 `json:"amount"`
+	AccountId   string  `json:"account_id"`
+	Category    string  `json:"category"`
+	Cadence     string  `json:"cadence"`
+	NextDueDate string  `json:"next_due_date"`
+	Notes       string  `json:"notes"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+// A single expected bill payment. Source is "detected" for recurring series found in the
+// transaction history and "manual" for bills the user entered. Amount is positive.
+type UpcomingBill struct {
+	Source    string  `json:"source"`
+	SourceId  string  `json:"source_id"`
+	Name      string  `json:"name"`
+	Amount    float64 `json:"amount"`
+	DueDate   string  `json:"due_date"`
+	AccountId string  `json:"account_id"`
+	Category  string  `json:"category"`
+}
+
+// What an account's balance will look like after the upcoming bills charged to it are paid
+type ProjectedAccountBalance struct {
+	AccountId         string  `json:"account_id"`
+	Name              string  `json:"name"`
+	CurrentBalance    float64 `json:"current_balance"`
+	ProjectedBalance  float64 `json:"projected_balance"`
+	LowestBalance     float64 `json:"lowest_balance"`
+	LowestBalanceDate string  `json:"lowest_balance_date"`
+	OverdraftWarning  bool    `json:"overdraft_warning"`
+}
+
+type UpcomingBillsResponse struct {
+	Bills    []UpcomingBill            `json:"bills"`
+	Accounts []ProjectedAccountBalance `json:"accounts"`
+}
+
+type CalendarFeedResponse struct {
+	URL string `json:"url"`
+}
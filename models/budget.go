@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type StoredBudget struct {
 	ID             string  `json:"id"`
 	UserId         string  `json:"user_id"`
@@ -24,6 +26,7 @@ type StoredBudget struct {
 	PersonalCare   float64 `json:"personal_care"`
 	Other          float64 `json:"other"`
 	Unknown        float64 `json:"unknown"`
+	BudgetPeriod
 }
 
 // This is for new budgets, not for updating existing budgets
@@ -48,6 +51,7 @@ type BudgetRequest struct {
 	PersonalCare   float64 `json:"personal_care"`
 	Other          float64 `json:"other"`
 	Unknown        float64 `json:"unknown"`
+	BudgetPeriod
 }
 
 type UpdateBudgetRequest struct {
@@ -71,8 +75,227 @@ type UpdateBudgetRequest struct {
 	PersonalCare   float64 `json:"personal_care"`
 	Other          float64 `json:"other"`
 	Unknown        float64 `json:"unknown"`
+	BudgetPeriod
+}
+
+const (
+	BudgetPeriodMonthly   = "monthly"
+	BudgetPeriodWeekly    = "weekly"
+	BudgetPeriodBiweekly  = "biweekly"
+	BudgetPeriodQuarterly = "quarterly"
+	BudgetPeriodYearly    = "yearly"
+	BudgetPeriodCustom    = "custom"
+)
+
+// Describes the time span a budget covers. Monthly and custom budgets cover exactly
+// [StartDate, EndDate). Weekly, biweekly, quarterly and yearly budgets repeat from StartDate
+// (e.g. a pay date) until EndDate, or indefinitely when EndDate is empty.
+// Dates are formatted as YYYY-MM-DD.
+type BudgetPeriod struct {
+	Period    string `json:"period"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// The period covering a single calendar month
+func MonthlyPeriod(year int, month int) BudgetPeriod {
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return BudgetPeriod{
+		Period:    BudgetPeriodMonthly,
+		StartDate: start.Format(time.DateOnly),
+		EndDate:   start.AddDate(0, 1, 0).Format(time.DateOnly),
+	}
+}
+
+// A budget together with the concrete window of its period that contains a given date
+type ActiveBudget struct {
+	Budget      StoredBudget `json:"budget"`
+	PeriodStart string       `json:"period_start"`
+	PeriodEnd   string       `json:"period_end"`
+}
+
+type BudgetLineProgress struct {
+	Field       string  `json:"field"`
+	Budgeted    float64 `json:"budgeted"`
+	Prorated    float64 `json:"prorated"`
+	Spent       float64 `json:"spent"`
+	Remaining   float64 `json:"remaining"`
+	PercentUsed float64 `json:"percent_used"`
+}
+
+// Spending against a budget for one window of its period. Prorated amounts are the share of the
+// budget that is "available" so far, based on how much of the window has elapsed
+type BudgetProgress struct {
+	BudgetId        string               `json:"budget_id"`
+	Period          string               `json:"period"`
+	PeriodStart     string               `json:"period_start"`
+	PeriodEnd       string               `json:"period_end"`
+	ElapsedFraction float64              `json:"elapsed_fraction"`
+	Lines           []BudgetLineProgress `json:"lines"`
+	Total           BudgetLineProgress   `json:"total"`
 }
 
 type MessageResponse struct {
 	Message string `json:"message"`
 }
+
+// Maps the categories returned by CategorizeTransaction to the budget column they count against.
+// "Income" is intentionally left out since it is never budgeted as spending.
+var CategoryBudgetFields = map[string]string{
+	"Food & Dining":     "food",
+	"Groceries":         "groceries",
+	"Transportation":    "transportation",
+	"Entertainment":     "entertainment",
+	"Health & Wellness": "health",
+	"Shopping":          "shopping",
+	"Utilities":         "utilities",
+	"Rent":              "housing",
+	"Travel":            "travel",
+	"Education":         "education",
+	"Subscriptions":     "subscriptions",
+	"Gifts & Donations": "gifts",
+	"Insurance":         "insurance",
+	"Personal Care":     "personal_care",
+	"Unknown":           "unknown",
+}
+
+// Returns the budget column a transaction category counts against, falling back to "other"
+// for anything the categorizer did not produce (e.g. a category a user typed in by hand)
+func BudgetFieldForCategory(category string) string {
+	if field, ok := CategoryBudgetFields[category]; ok {
+		return field
+	}
+	return "other"
+}
+
+// The per category amounts of a budget keyed by their json/column name
+type BudgetLines map[string]float64
+
+func (b StoredBudget) Lines() BudgetLines {
+	return BudgetLines{
+		"food": b.Food, "groceries": b.Groceries, "transportation": b.Transportation,
+		"entertainment": b.Entertainment, "health": b.Health, "shopping": b.Shopping,
+		"utilities": b.Utilities, "housing": b.Housing, "travel": b.Travel,
+		"education": b.Education, "subscriptions": b.Subscriptions, "gifts": b.Gifts,
+		"insurance": b.Insurance, "personal_care": b.PersonalCare, "other": b.Other,
+		"unknown": b.Unknown,
+	}
+}
+
+func (t BudgetTemplate) Lines() BudgetLines {
+	return BudgetLines{
+		"food": t.Food, "groceries": t.Groceries, "transportation": t.Transportation,
+		"entertainment": t.Entertainment, "health": t.Health, "shopping": t.Shopping,
+		"utilities": t.Utilities, "housing": t.Housing, "travel": t.Travel,
+		"education": t.Education, "subscriptions": t.Subscriptions, "gifts": t.Gifts,
+		"insurance": t.Insurance, "personal_care": t.PersonalCare, "other": t.Other,
+		"unknown": t.Unknown,
+	}
+}
+
+// Builds a new budget request for the given month out of a set of budget lines
+func (l BudgetLines) ToBudgetRequest(userId string, year int, month int) BudgetRequest {
+	req := BudgetRequest{
+		UserId:         userId,
+		Year:           year,
+		Month:          month,
+		BudgetPeriod:   MonthlyPeriod(year, month),
+		Food:           l["food"],
+		Groceries:      l["groceries"],
+		Transportation: l["transportation"],
+		Entertainment:  l["entertainment"],
+		Health:         l["health"],
+		Shopping:       l["shopping"],
+		Utilities:      l["utilities"],
+		Housing:        l["housing"],
+		Travel:         l["travel"],
+		Education:      l["education"],
+		Subscriptions:  l["subscriptions"],
+		Gifts:          l["gifts"],
+		Insurance:      l["insurance"],
+		PersonalCare:   l["personal_care"],
+		Other:          l["other"],
+		Unknown:        l["unknown"],
+	}
+	req.Total = l.Total()
+	return req
+}
+
+func (l BudgetLines) ToBudgetTemplate(userId string, name string) BudgetTemplate {
+	return BudgetTemplate{
+		UserId:         userId,
+		Name:           name,
+		Total:          l.Total(),
+		Food:           l["food"],
+		Groceries:      l["groceries"],
+		Transportation: l["transportation"],
+		Entertainment:  l["entertainment"],
+		Health:         l["health"],
+		Shopping:       l["shopping"],
+		Utilities:      l["utilities"],
+		Housing:        l["housing"],
+		Travel:         l["travel"],
+		Education:      l["education"],
+		Subscriptions:  l["subscriptions"],
+		Gifts:          l["gifts"],
+		Insurance:      l["insurance"],
+		PersonalCare:   l["personal_care"],
+		Other:          l["other"],
+		Unknown:        l["unknown"],
+	}
+}
+
+func (l BudgetLines) Total() float64 {
+	var total float64
+	for _, amount := range l {
+		total += amount
+	}
+	return total
+}
+
+// A named, reusable set of budget amounts that can be applied to any month
+type BudgetTemplate struct {
+	ID             string  `json:"id"`
+	UserId         string  `json:"user_id"`
+	Name           string  `json:"name"`
+	Total          float64 `json:"total"`
+	CreatedAt      string  `json:"created_at"`
+	Food           float64 `json:"food"`
+	Groceries      float64 `json:"groceries"`
+	Transportation float64 `json:"transportation"`
+	Entertainment  float64 `json:"entertainment"`
+	Health         float64 `json:"health"`
+	Shopping       float64 `json:"shopping"`
+	Utilities      float64 `json:"utilities"`
+	Housing        float64 `json:"housing"`
+	Travel         float64 `json:"travel"`
+	Education      float64 `json:"education"`
+	Subscriptions  float64 `json:"subscriptions"`
+	Gifts          float64 `json:"gifts"`
+	Insurance      float64 `json:"insurance"`
+	PersonalCare   float64 `json:"personal_care"`
+	Other          float64 `json:"other"`
+	Unknown        float64 `json:"unknown"`
+}
+
+// Used to create a template. If BudgetId is set the amounts are taken from that budget
+// instead of the request body
+type BudgetTemplateRequest struct {
+	BudgetTemplate
+	BudgetId string `json:"budget_id"`
+}
+
+// The month to copy a budget from. Left empty it defaults to the month before the target
+type BudgetCopyRequest struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+}
+
+// Opt-in settings for creating each month's budget automatically at the start of the month.
+// Source is either "previous_month" or "template" (in which case TemplateId is required)
+type BudgetAutoCreateSettings struct {
+	UserId     string `json:"user_id"`
+	Enabled    bool   `json:"enabled"`
+	Source     string `json:"source"`
+	TemplateId string `json:"template_id"`
+}
@@ -0,0 +1,60 @@
+package models
+
+// The category the categorizer gives income
+const IncomeCategory = "Income"
+
+// A month's money in or out for one income source or expense category, as aggregated by the
+// database. Month is formatted YYYY-MM and expenses are positive
+type CashFlowTotal struct {
+	Month    string
+	IsIncome bool
+	Name     string
+	Amount   float64
+}
+
+type CashFlowLine struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+type CashFlowMonth struct {
+	Month      string  `json:"month"`
+	Income     float64 `json:"income"`
+	Expenses   float64 `json:"expenses"`
+	NetSavings float64 `json:"net_savings"`
+	// Net savings as a percentage of income, left out for months without income
+	SavingsRate   *float64       `json:"savings_rate,omitempty"`
+	IncomeSources []CashFlowLine `json:"income_sources"`
+	Categories    []CashFlowLine `json:"categories"`
+}
+
+type SankeyNode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	// "source", "income", "category", "savings" or "shortfall"
+	Kind string `json:"kind"`
+}
+
+type SankeyLink struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Value  float64 `json:"value"`
+}
+
+// Income sources flow into a single income node, which flows out to the expense categories and
+// whatever was saved. A year that spent more than it earned gets a shortfall node feeding the
+// income node instead
+type Sankey struct {
+	Nodes []SankeyNode `json:"nodes"`
+	Links []SankeyLink `json:"links"`
+}
+
+type CashFlowStatement struct {
+	Year        int             `json:"year"`
+	Income      float64         `json:"income"`
+	Expenses    float64         `json:"expenses"`
+	NetSavings  float64         `json:"net_savings"`
+	SavingsRate *float64        `json:"savings_rate,omitempty"`
+	Months      []CashFlowMonth `json:"months"`
+	Sankey      Sankey          `json:"sankey"`
+}
@@ -0,0 +1,49 @@
+package models
+
+const (
+	DigestGeneratedByLLM      = "llm"
+	DigestGeneratedByTemplate = "template"
+)
+
+// How a budget did over the digest's month
+type DigestBudget struct {
+	BudgetId    string  `json:"budget_id"`
+	Period      string  `json:"period"`
+	Budgeted    float64 `json:"budgeted"`
+	Spent       float64 `json:"spent"`
+	PercentUsed float64 `json:"percent_used"`
+	// Lines that went over their budgeted amount
+	OverBudget []BudgetLineProgress `json:"over_budget"`
+}
+
+type DigestSubscription struct {
+	Payee   string  `json:"payee"`
+	Amount  float64 `json:"amount"`
+	Cadence string  `json:"cadence"`
+}
+
+// The numbers a monthly digest is written from. All of them come from the report queries, the
+// language model only turns them into prose
+type MonthlyDigestFacts struct {
+	// YYYY-MM
+	Month            string               `json:"month"`
+	Income           float64              `json:"income"`
+	Expenses         float64              `json:"expenses"`
+	NetSavings       float64              `json:"net_savings"`
+	SavingsRate      *float64             `json:"savings_rate,omitempty"`
+	TopCategories    []CashFlowLine       `json:"top_categories"`
+	Budgets          []DigestBudget       `json:"budgets"`
+	BiggestChanges   []SpendingMover      `json:"biggest_changes"`
+	NewSubscriptions []DigestSubscription `json:"new_subscriptions"`
+	UnusualCharges   []Anomaly            `json:"unusual_charges"`
+}
+
+type MonthlyDigest struct {
+	ID          string             `json:"id"`
+	UserId      string             `json:"user_id"`
+	Month       string             `json:"month"`
+	Summary     string             `json:"summary"`
+	GeneratedBy string             `json:"generated_by"`
+	Facts       MonthlyDigestFacts `json:"facts"`
+	CreatedAt   string             `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Two transactions that look like the same charge, e.g. after the bank re-issued its IDs or when
+// one card is synced through two connections. Original is the earlier of the two
+type DuplicatePair struct {
+	Original  Transaction `json:"original"`
+	Duplicate Transaction `json:"duplicate"`
+	// How alike the two payees are, from 0 to 1
+	Similarity float64 `json:"similarity"`
+	Reason     string  `json:"reason"`
+}
+
+// Merges RemoveId into KeepId. The kept transaction takes over the other's tags, custom fields,
+// attachments and, where it has none of its own, category, notes and splits
+type MergeDuplicateRequest struct {
+	KeepId   string `json:"keep_id"`
+	RemoveId string `json:"remove_id"`
+}
+
+type DismissDuplicateRequest struct {
+	TransactionIds [2]string `json:"transaction_ids"`
+}
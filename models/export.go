@@ -0,0 +1,9 @@
+package models
+
+const (
+	ExportFormatCSV       = "csv"
+	ExportFormatOFX       = "ofx"
+	ExportFormatJSON      = "json"
+	ExportFormatLedger    = "ledger"
+	ExportFormatBeancount = "beancount"
+)
@@ -0,0 +1,34 @@
+package models
+
+// A forecast balance for one day. Low and High bound the range the balance is expected to stay
+// in given how much day to day discretionary spending usually varies.
+type ForecastPoint struct {
+	Date    string  `json:"date"`
+	Balance float64 `json:"balance"`
+	Low     float64 `json:"low"`
+	High    float64 `json:"high"`
+}
+
+type ForecastLowPoint struct {
+	Date    string  `json:"date"`
+	Balance float64 `json:"balance"`
+	Low     float64 `json:"low"`
+}
+
+type AccountForecast struct {
+	AccountId       string           `json:"account_id"`
+	Name            string           `json:"name"`
+	StartingBalance float64          `json:"starting_balance"`
+	Points          []ForecastPoint  `json:"points"`
+	LowPoint        ForecastLowPoint `json:"low_point"`
+	Warning         string           `json:"warning,omitempty"`
+}
+
+type ForecastResponse struct {
+	From     string            `json:"from"`
+	Days     int               `json:"days"`
+	Accounts []AccountForecast `json:"accounts"`
+	Total    AccountForecast   `json:"total"`
+	// average discretionary spend per day by budget field, across all accounts
+	DailyDiscretionary map[string]float64 `json:"daily_discretionary"`
+}
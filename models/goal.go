@@ -0,0 +1,42 @@
+package models
+
+const (
+	GoalStatusAchieved   = "achieved"
+	GoalStatusOnTrack    = "on_track"
+	GoalStatusBehind     = "behind"
+	GoalStatusNotStarted = "not_started"
+)
+
+// A savings goal backed by an account. AllocationPercent is the share of the account's balance
+// that counts towards the goal, which lets several goals split one savings account. 100 links the
+// whole account. CurrentAmount, RequiredMonthly, Status and ProjectedCompletion are recalculated
+// after every sync.
+type Goal struct {
+	ID                  string  `json:"id"`
+	UserId              string  `json:"user_id"`
+	Name                string  `json:"name"`
+	TargetAmount        float64 `json:"target_amount"`
+	TargetDate          string  `json:"target_date"`
+	AccountId           string  `json:"account_id"`
+	AllocationPercent   float64 `json:"allocation_percent"`
+	CurrentAmount       float64 `json:"current_amount"`
+	RequiredMonthly     float64 `json:"required_monthly"`
+	Status              string  `json:"status"`
+	ProjectedCompletion string  `json:"projected_completion"`
+	CreatedAt           string  `json:"created_at"`
+	LastCalculated      string  `json:"last_calculated"`
+}
+
+type GoalRequest struct {
+	Name              string  `json:"name"`
+	TargetAmount      float64 `json:"target_amount"`
+	TargetDate        string  `json:"target_date"`
+	AccountId         string  `json:"account_id"`
+	AllocationPercent float64 `json:"allocation_percent"`
+}
+
+// The amount saved towards a goal on a given day, used to project when it will be reached
+type GoalSnapshot struct {
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+}
@@ -0,0 +1,70 @@
+package models
+
+const (
+	ImportFormatCSV = "csv"
+	ImportFormatOFX = "ofx"
+	ImportFormatQIF = "qif"
+
+	ImportStatusPreview   = "preview"
+	ImportStatusCommitted = "committed"
+	ImportStatusUndone    = "undone"
+)
+
+// Which CSV columns (by header name) hold which transaction field. Amount is either a single
+// signed column or separate Debit and Credit columns
+type CSVMapping struct {
+	Date        string `json:"date"`
+	Amount      string `json:"amount,omitempty"`
+	Debit       string `json:"debit,omitempty"`
+	Credit      string `json:"credit,omitempty"`
+	Payee       string `json:"payee"`
+	Description string `json:"description,omitempty"`
+	Memo        string `json:"memo,omitempty"`
+	// The bank's own transaction id, if the export has one
+	ID string `json:"id,omitempty"`
+	// Go layout of the dates, e.g. "01/02/2006". Common layouts are tried when empty
+	DateFormat string `json:"date_format,omitempty"`
+	// Set for banks that export spending as positive amounts
+	NegateAmounts bool `json:"negate_amounts,omitempty"`
+}
+
+// A saved CSV mapping, usually one per bank
+type ImportProfile struct {
+	ID      string     `json:"id"`
+	UserId  string     `json:"user_id"`
+	Name    string     `json:"name"`
+	Mapping CSVMapping `json:"mapping"`
+}
+
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// One uploaded file. Its parsed transactions are held until the import is committed
+type ImportRecord struct {
+	ID               string        `json:"id"`
+	UserId           string        `json:"user_id"`
+	AccountId        string        `json:"account_id"`
+	Format           string        `json:"format"`
+	Filename         string        `json:"filename"`
+	Status           string        `json:"status"`
+	TransactionCount int           `json:"transaction_count"`
+	CreatedAt        string        `json:"created_at"`
+	Transactions     []Transaction `json:"-"`
+}
+
+type ImportPreviewRow struct {
+	Transaction
+	// Already imported or synced before, committing skips it
+	Duplicate bool `json:"duplicate"`
+}
+
+type ImportPreview struct {
+	Import ImportRecord `json:"import"`
+	// Header of a CSV file and the mapping used (or suggested) for it, for the column mapping step
+	Columns      []string           `json:"columns,omitempty"`
+	Mapping      *CSVMapping        `json:"mapping,omitempty"`
+	Transactions []ImportPreviewRow `json:"transactions"`
+	Errors       []ImportRowError   `json:"errors"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// A merchant the user's transactions are grouped under. Payees that normalize to one of the
+// aliases (or to the name) belong to the merchant
+type Merchant struct {
+	ID               string   `json:"id"`
+	UserId           string   `json:"user_id"`
+	Name             string   `json:"name"`
+	Category         string   `json:"category"`
+	Aliases          []string `json:"aliases"`
+	TransactionCount int      `json:"transaction_count"`
+}
+
+type MerchantAliasRequest struct {
+	Alias string `json:"alias"`
+}
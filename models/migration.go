@@ -0,0 +1,38 @@
+package models
+
+// Budgeting apps users can migrate from. Each migration run is recorded as one import per account
+// with the source as its format, so it shows up in the import history and can be undone
+const (
+	MigrationSourceMint    = "mint"
+	MigrationSourceYNAB    = "ynab"
+	MigrationSourceMonarch = "monarch"
+)
+
+// Which of our categories a category from the other app becomes. Custom is set for mappings the
+// user saved, the rest are the defaults
+type CategoryMapping struct {
+	SourceCategory string `json:"source_category"`
+	Category       string `json:"category"`
+	Custom         bool   `json:"custom"`
+}
+
+type MigrationAccount struct {
+	Name      string `json:"name"`
+	AccountId string `json:"account_id"`
+	// Set when no account had the name and a manual one was created for it
+	Created bool `json:"created"`
+	// Empty when every transaction was already migrated
+	ImportId         string `json:"import_id,omitempty"`
+	TransactionCount int    `json:"transaction_count"`
+	// Transactions left out because an earlier run already added them
+	Skipped int `json:"skipped"`
+}
+
+type MigrationResult struct {
+	Source   string             `json:"source"`
+	Accounts []MigrationAccount `json:"accounts"`
+	// Categories from the file with no mapping. Their transactions went through the usual
+	// categorizer; mapping them only affects later runs
+	UnmappedCategories []string         `json:"unmapped_categories"`
+	Errors             []ImportRowError `json:"errors"`
+}
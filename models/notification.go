@@ -0,0 +1,53 @@
+package models
+
+// A message for a user. Every notification lands in the in-app inbox and is additionally pushed
+// through whichever channels the user enabled in their NotificationPreferences
+type Notification struct {
+	ID        string            `json:"id"`
+	UserId    string            `json:"user_id"`
+	Kind      string            `json:"kind"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Data      map[string]string `json:"data"`
+	Read      bool              `json:"read"`
+	CreatedAt string            `json:"created_at"`
+}
+
+const (
+	NotificationChannelEmail   = "email"
+	NotificationChannelWebhook = "webhook"
+)
+
+// A notification whose push channels were held back by quiet hours
+type DeferredNotification struct {
+	ID           string
+	Channels     []string
+	Notification Notification
+}
+
+// Per user delivery settings. Quiet hours are whole hours in the user's timezone, e.g. 22 - 7.
+// While they are in effect notifications only go to the inbox, email and webhook deliveries wait
+// until they end.
+type NotificationPreferences struct {
+	UserId          string `json:"user_id"`
+	EmailEnabled    bool   `json:"email_enabled"`
+	Email           string `json:"email"`
+	WebhookEnabled  bool   `json:"webhook_enabled"`
+	WebhookURL      string `json:"webhook_url"`
+	WebhookSecret   string `json:"webhook_secret,omitempty"`
+	QuietHoursStart int    `json:"quiet_hours_start"`
+	QuietHoursEnd   int    `json:"quiet_hours_end"`
+	Timezone        string `json:"timezone"`
+	// Also send flagged anomalies as notifications, not just list them under /alerts
+	AnomalyAlertsEnabled bool `json:"anomaly_alerts_enabled"`
+}
+
+// Fires an alert once spending on a budget line (or "total") reaches Percent of its budgeted amount
+type BudgetAlertThreshold struct {
+	Field   string  `json:"field"`
+	Percent float64 `json:"percent"`
+}
+
+type BudgetAlertThresholdsRequest struct {
+	Thresholds []BudgetAlertThreshold `json:"thresholds"`
+}
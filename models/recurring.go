@@ -0,0 +1,38 @@
+package models
+
+const (
+	RecurringStatusActive    = "active"
+	RecurringStatusCancelled = "cancelled"
+
+	RecurringReviewDetected  = "detected"
+	RecurringReviewConfirmed = "confirmed"
+	RecurringReviewDismissed = "dismissed"
+)
+
+// A payee that charges (or pays) the user on a regular cadence. Amounts are signed the same way
+// as transactions, so subscriptions are negative and paychecks positive.
+type RecurringSeries struct {
+	ID                 string       `json:"id"`
+	Payee              string       `json:"payee"`
+	NormalizedPayee    string       `json:"normalized_payee"`
+	AccountId          string       `json:"account_id"`
+	Category           string       `json:"category"`
+	Cadence            string       `json:"cadence"`
+	IntervalDays       int          `json:"interval_days"`
+	Occurrences        int          `json:"occurrences"`
+	AverageAmount      float64      `json:"average_amount"`
+	LastAmount         float64      `json:"last_amount"`
+	LastDate           string       `json:"last_date"`
+	NextExpectedDate   string       `json:"next_expected_date"`
+	NextExpectedAmount float64      `json:"next_expected_amount"`
+	IsIncome           bool         `json:"is_income"`
+	PriceIncrease      *PriceChange `json:"price_increase,omitempty"`
+	Status             string       `json:"status"`
+	Review             string       `json:"review"`
+}
+
+type PriceChange struct {
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	Date     string  `json:"date"`
+}
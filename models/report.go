@@ -0,0 +1,58 @@
+package models
+
+const (
+	ReportGroupByCategory = "category"
+	ReportGroupByMerchant = "merchant"
+	ReportGroupByAccount  = "account"
+	ReportGroupByTag      = "tag"
+
+	ReportIntervalMonth = "month"
+	ReportIntervalWeek  = "week"
+)
+
+// Spending of one group in one period, as aggregated by the database. Period is the first day of
+// the period (YYYY-MM-DD)
+type SpendingTotal struct {
+	Period           string
+	Key              string
+	Total            float64
+	TransactionCount int
+}
+
+type SpendingGroup struct {
+	Key              string  `json:"key"`
+	Total            float64 `json:"total"`
+	TransactionCount int     `json:"transaction_count"`
+	// Difference to the same group in the period before
+	Change float64 `json:"change"`
+	// Change as a percentage of the period before, left out when the group had no spending then
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+type SpendingPeriod struct {
+	Start         string          `json:"start"`
+	End           string          `json:"end"`
+	Total         float64         `json:"total"`
+	Change        float64         `json:"change"`
+	ChangePercent *float64        `json:"change_percent,omitempty"`
+	Groups        []SpendingGroup `json:"groups"`
+}
+
+// A group whose spending changed the most between the last two periods of a report
+type SpendingMover struct {
+	Key           string   `json:"key"`
+	Previous      float64  `json:"previous"`
+	Current       float64  `json:"current"`
+	Change        float64  `json:"change"`
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+type SpendingReport struct {
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	GroupBy   string           `json:"group_by"`
+	Interval  string           `json:"interval"`
+	Total     float64          `json:"total"`
+	Periods   []SpendingPeriod `json:"periods"`
+	TopMovers []SpendingMover  `json:"top_movers"`
+}
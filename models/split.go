@@ -0,0 +1,15 @@
+package models
+
+// One line of a transaction split across several categories. The lines of a split transaction
+// always add up to the transaction's amount
+type TransactionSplit struct {
+	ID            string `json:"id"`
+	TransactionId string `json:"transaction_id"`
+	Amount        string `json:"amount"`
+	Category      string `json:"category"`
+	Memo          string `json:"memo"`
+}
+
+type TransactionSplitsRequest struct {
+	Splits []TransactionSplit `json:"splits"`
+}
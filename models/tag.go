@@ -0,0 +1,8 @@
+package models
+
+// A tag with how much it was used over a date range
+type TagSummary struct {
+	Name             string  `json:"name"`
+	TransactionCount int     `json:"transaction_count"`
+	Spent            float64 `json:"spent"`
+}
@@ -0,0 +1,54 @@
+package models
+
+const (
+	TransactionSortDateDesc   = "date_desc"
+	TransactionSortDateAsc    = "date_asc"
+	TransactionSortAmountAsc  = "amount_asc"
+	TransactionSortAmountDesc = "amount_desc"
+	// Best search matches first. The default when searching
+	TransactionSortRelevance = "relevance"
+)
+
+// Position of the last transaction on a page. Value is the sort column (transacted_at, amount or
+// search rank) of that transaction, and the ID breaks ties between equal values
+type TransactionCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
+
+// Filters for the transaction list. Zero values mean no filter
+type TransactionQuery struct {
+	From       int64
+	To         int64
+	AccountIds []string
+	Categories []string
+	Tags       []string
+	MinAmount  *float64
+	MaxAmount  *float64
+	Search     string
+	Sort       string
+	Limit      int
+	After      *TransactionCursor
+}
+
+// A transaction with how well it matched the search, 0 when there was no search
+type RankedTransaction struct {
+	Transaction
+	Rank float64
+}
+
+// Byte ranges of a field's text that matched the search
+type SearchHighlight struct {
+	Field   string   `json:"field"`
+	Text    string   `json:"text"`
+	Matches [][2]int `json:"matches"`
+}
+
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+	TotalCount   int           `json:"total_count"`
+	// Matched fragments per transaction ID, only when searching
+	Highlights map[string][]SearchHighlight `json:"highlights,omitempty"`
+}